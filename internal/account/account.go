@@ -0,0 +1,9 @@
+package account
+
+import "time"
+
+type Account struct {
+	ID        int32     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
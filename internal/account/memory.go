@@ -0,0 +1,120 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+)
+
+type memRepository struct {
+	db *memdb.DB
+}
+
+// NewMemoryRepository returns an AccountRepository that keeps accounts in db.
+// It behaves like the Postgres repository, down to the errors it returns.
+func NewMemoryRepository(db *memdb.DB) AccountRepository {
+	return &memRepository{db: db}
+}
+
+func toAccount(row memdb.Account) *Account {
+	return &Account{ID: row.ID, Name: row.Name, CreatedAt: row.CreatedAt}
+}
+
+func (r *memRepository) CreateAccount(ctx context.Context, args CreateAccountParams) (*Account, error) {
+	var a *Account
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Accounts {
+			if row.Name == args.Name {
+				return fmt.Errorf("scanning row into account struct: %w", memdb.ErrUnique)
+			}
+		}
+
+		row := memdb.Account{
+			ID:        t.NextID("account"),
+			Name:      args.Name,
+			CreatedAt: time.Now(),
+		}
+		t.Accounts[row.ID] = row
+
+		a = toAccount(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (r *memRepository) getAccount(ctx context.Context, fn func(row memdb.Account) bool) (*Account, error) {
+	var a *Account
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Accounts {
+			if fn(row) {
+				a = toAccount(row)
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (r *memRepository) GetAccountByID(ctx context.Context, id int32) (*Account, error) {
+	return r.getAccount(ctx, func(row memdb.Account) bool { return row.ID == id })
+}
+
+func (r *memRepository) GetAccountByName(ctx context.Context, name string) (*Account, error) {
+	return r.getAccount(ctx, func(row memdb.Account) bool { return row.Name == name })
+}
+
+func (r *memRepository) ListAccountPlayerIDs(ctx context.Context, id int32) ([]int32, error) {
+	ids := []int32{}
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, p := range t.Players {
+			if p.AccountID != nil && *p.AccountID == id && p.DeletedAt == nil {
+				ids = append(ids, p.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(ids)
+
+	return ids, nil
+}
+
+func (r *memRepository) SetPlayerAccount(ctx context.Context, args SetPlayerAccountParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		if args.AccountID != nil {
+			if _, ok := t.Accounts[*args.AccountID]; !ok {
+				return fmt.Errorf("updating player account: %w", memdb.ErrForeignKey)
+			}
+		}
+
+		p, ok := t.Players[args.PlayerID]
+		if !ok {
+			return nil
+		}
+
+		p.AccountID = args.AccountID
+		p.Version++
+		p.UpdatedAt = time.Now()
+		t.Players[p.ID] = p
+
+		return nil
+	})
+}
@@ -0,0 +1,149 @@
+package account
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+	"github.com/jackc/pgx/v5"
+)
+
+type AccountRepository interface {
+	CreateAccount(ctx context.Context, args CreateAccountParams) (*Account, error)
+	GetAccountByID(ctx context.Context, id int32) (*Account, error)
+	GetAccountByName(ctx context.Context, name string) (*Account, error)
+	ListAccountPlayerIDs(ctx context.Context, id int32) ([]int32, error)
+	SetPlayerAccount(ctx context.Context, args SetPlayerAccountParams) error
+}
+
+type pgRepository struct {
+	db *database.DB
+}
+
+func NewPostgresRepository(db *database.DB) AccountRepository {
+	return &pgRepository{db: db}
+}
+
+const createAccount = `
+INSERT INTO account (name, created_at)
+VALUES ($1, now())
+RETURNING id, name, created_at
+`
+
+type CreateAccountParams struct {
+	Name string `json:"name"`
+}
+
+func (r *pgRepository) CreateAccount(ctx context.Context, args CreateAccountParams) (*Account, error) {
+	ctx = querylabel.With(ctx, "account", "CreateAccount")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var a Account
+
+	err = tx.QueryRow(ctx, createAccount, args.Name).Scan(&a.ID, &a.Name, &a.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("scanning row into account struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return &a, nil
+}
+
+const getAccountByID = `
+SELECT id, name, created_at FROM account WHERE id = $1
+`
+
+func (r *pgRepository) GetAccountByID(ctx context.Context, id int32) (*Account, error) {
+	ctx = querylabel.With(ctx, "account", "GetAccountByID")
+
+	var a Account
+
+	err := r.db.QueryRow(ctx, getAccountByID, id).Scan(&a.ID, &a.Name, &a.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into account struct: %w", err)
+	}
+
+	return &a, nil
+}
+
+const getAccountByName = `
+SELECT id, name, created_at FROM account WHERE name = $1
+`
+
+func (r *pgRepository) GetAccountByName(ctx context.Context, name string) (*Account, error) {
+	ctx = querylabel.With(ctx, "account", "GetAccountByName")
+
+	var a Account
+
+	err := r.db.QueryRow(ctx, getAccountByName, name).Scan(&a.ID, &a.Name, &a.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into account struct: %w", err)
+	}
+
+	return &a, nil
+}
+
+const listAccountPlayerIDs = `
+SELECT id FROM player WHERE account_id = $1 AND deleted_at IS NULL ORDER BY id
+`
+
+func (r *pgRepository) ListAccountPlayerIDs(ctx context.Context, id int32) ([]int32, error) {
+	ctx = querylabel.With(ctx, "account", "ListAccountPlayerIDs")
+
+	rows, err := r.db.Query(ctx, listAccountPlayerIDs, id)
+	if err != nil {
+		return nil, fmt.Errorf("querying for account players: %w", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+	if err != nil {
+		return nil, fmt.Errorf("scanning rows into player ids: %w", err)
+	}
+
+	return ids, nil
+}
+
+const setPlayerAccount = `
+UPDATE player SET account_id = $2, version = version + 1, updated_at = now() WHERE id = $1
+`
+
+type SetPlayerAccountParams struct {
+	PlayerID  int32  `json:"player_id"`
+	AccountID *int32 `json:"account_id"`
+}
+
+func (r *pgRepository) SetPlayerAccount(ctx context.Context, args SetPlayerAccountParams) error {
+	ctx = querylabel.With(ctx, "account", "SetPlayerAccount")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, setPlayerAccount, args.PlayerID, args.AccountID)
+	if err != nil {
+		return fmt.Errorf("updating player account: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package account
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+)
+
+// PlayerLookup is the part of the player service accounts depend on.
+type PlayerLookup interface {
+	GetPlayerByID(ctx context.Context, id int32) (*player.Player, error)
+}
+
+type AccountService struct {
+	repo    AccountRepository
+	players PlayerLookup
+}
+
+func NewAccountService(repo AccountRepository, players PlayerLookup) *AccountService {
+	return &AccountService{repo: repo, players: players}
+}
+
+var ErrNameInUse = apperr.Conflict("account name already in use")
+
+type AccountDetails struct {
+	*Account
+	PlayerIDs []int32 `json:"player_ids"`
+}
+
+func (s *AccountService) CreateAccount(ctx context.Context, name string) (*Account, error) {
+	a, err := s.repo.GetAccountByName(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("creating account: %w", err)
+	}
+	if a != nil {
+		return nil, ErrNameInUse
+	}
+
+	a, err = s.repo.CreateAccount(ctx, CreateAccountParams{Name: name})
+	if err != nil {
+		return nil, fmt.Errorf("creating new account: %w", err)
+	}
+
+	return a, nil
+}
+
+func (s *AccountService) GetAccountByID(ctx context.Context, id int32) (*AccountDetails, error) {
+	a, err := s.repo.GetAccountByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting account with id %v: %w", id, err)
+	}
+	if a == nil {
+		return nil, apperr.NotFound("account", "id", id)
+	}
+
+	ids, err := s.repo.ListAccountPlayerIDs(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting players of account with id %v: %w", id, err)
+	}
+	if ids == nil {
+		ids = []int32{}
+	}
+
+	return &AccountDetails{Account: a, PlayerIDs: ids}, nil
+}
+
+// SetPlayerAccount groups a player under an account, or removes them from
+// their account when accountID is nil.
+func (s *AccountService) SetPlayerAccount(ctx context.Context, playerID int32, accountID *int32) error {
+	if _, err := s.players.GetPlayerByID(ctx, playerID); err != nil {
+		return err
+	}
+
+	if accountID != nil {
+		if _, err := s.GetAccountByID(ctx, *accountID); err != nil {
+			return err
+		}
+	}
+
+	err := s.repo.SetPlayerAccount(ctx, SetPlayerAccountParams{PlayerID: playerID, AccountID: accountID})
+	if err != nil {
+		return fmt.Errorf("setting account of player with id %v: %w", playerID, err)
+	}
+
+	return nil
+}
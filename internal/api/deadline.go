@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Deadline gives every request a deadline, so that a request that takes too
+// long, or whose client goes away, stops waiting on the database. Routes
+// registered with WithTimeout get their own deadline, other routes get the
+// default one.
+type Deadline struct {
+	mux      *http.ServeMux
+	fallback time.Duration
+}
+
+// NewDeadline returns a Deadline for the routes registered on mux.
+func NewDeadline(mux *http.ServeMux, fallback time.Duration) *Deadline {
+	return &Deadline{mux: mux, fallback: fallback}
+}
+
+func (d *Deadline) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := d.fallback
+
+		h, _ := d.mux.Handler(r)
+		if t, ok := h.(timeoutHandler); ok {
+			timeout = t.timeout
+			// The route may run past the server's write timeout, which
+			// would cut off its response.
+			http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + time.Second))
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type timeoutHandler struct {
+	http.HandlerFunc
+	timeout time.Duration
+}
+
+// WithTimeout registers h with a deadline other than the default of the
+// Deadline middleware, for routes that are expected to take long.
+func WithTimeout(timeout time.Duration, h http.HandlerFunc) http.Handler {
+	return timeoutHandler{HandlerFunc: h, timeout: timeout}
+}
@@ -0,0 +1,101 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/api"
+	"github.com/hossokawa/go-nethttp-example/internal/repotest"
+	"github.com/hossokawa/go-nethttp-example/internal/sqlite"
+)
+
+// TestDeadlineCancelsQuery checks that a request running past its deadline
+// has its query cancelled, gets a 503, and gives its connection back to the
+// pool.
+func TestDeadlineCancelsQuery(t *testing.T) {
+	if !sqlite.Available {
+		t.Skip("built without the sqlite tag")
+	}
+
+	db, err := sqlite.Open(t.Context(), sqlite.Memory)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		var n int64
+		if err := db.QueryRow(r.Context(), repotest.SlowSQLiteQuery).Scan(&n); err != nil {
+			api.WriteError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := httptest.NewServer(api.NewDeadline(mux, 50*time.Millisecond).Wrap(mux))
+	t.Cleanup(server.Close)
+
+	start := time.Now()
+	res, err := http.Get(server.URL + "/slow")
+	if err != nil {
+		t.Fatalf("GET /slow: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GET /slow = %d, want %d", res.StatusCode, http.StatusServiceUnavailable)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("GET /slow took %v to time out", elapsed)
+	}
+	if inUse := db.Pool().Stats().InUse; inUse != 0 {
+		t.Errorf("%d connections still in use", inUse)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	if err := db.Ping(ctx); err != nil {
+		t.Errorf("Ping after the timed out request: %v", err)
+	}
+}
+
+// TestWithTimeout checks that routes registered with WithTimeout get their
+// own deadline and other routes the default one.
+func TestWithTimeout(t *testing.T) {
+	deadline := func(w http.ResponseWriter, r *http.Request) {
+		d, ok := r.Context().Deadline()
+		if !ok {
+			t.Error("request has no deadline")
+		}
+		w.Header().Set("X-Time-Left", time.Until(d).String())
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /deadline", deadline)
+	mux.Handle("GET /long/deadline", api.WithTimeout(time.Minute, deadline))
+
+	server := httptest.NewServer(api.NewDeadline(mux, time.Second).Wrap(mux))
+	t.Cleanup(server.Close)
+
+	for path, want := range map[string]time.Duration{
+		"/deadline":      time.Second,
+		"/long/deadline": time.Minute,
+	} {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		res.Body.Close()
+
+		left, err := time.ParseDuration(res.Header.Get("X-Time-Left"))
+		if err != nil {
+			t.Fatalf("GET %s: no deadline reported", path)
+		}
+		if left > want || left < want/2 {
+			t.Errorf("GET %s had %v left, want about %v", path, left, want)
+		}
+	}
+}
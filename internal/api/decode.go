@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/validate"
+)
+
+// MaxBodyBytes bounds the size of every JSON request body.
+const MaxBodyBytes = 1 << 20
+
+var (
+	ErrBodyTooLarge = apperr.New(apperr.KindTooLarge, "request body is too large")
+	ErrEmptyBody    = apperr.Invalid("request body must not be empty")
+	ErrMalformed    = apperr.Invalid("request body is not valid JSON")
+	ErrTrailingData = apperr.Invalid("request body must contain a single JSON value")
+)
+
+// ReadBody reads the whole request body, up to MaxBodyBytes.
+func ReadBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, ErrBodyTooLarge
+		}
+		return nil, apperr.Invalid("error reading request body")
+	}
+
+	return body, nil
+}
+
+// DecodeJSON decodes the request body into dst and validates the result
+// against its `validate` tags. Unknown fields are rejected, and every
+// violation is reported in a single error.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	defer r.Body.Close()
+
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeErr(err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return ErrTrailingData
+	}
+
+	return validate.Struct(dst).Err("request body")
+}
+
+func decodeErr(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return ErrBodyTooLarge
+	case errors.Is(err, io.EOF):
+		return ErrEmptyBody
+	case errors.As(err, &typeErr) && typeErr.Field == "":
+		return apperr.Invalid("request body must be a JSON object")
+	case errors.As(err, &typeErr):
+		var fields apperr.Fields
+		fields.Add(typeErr.Field, "must be of type "+typeErr.Type.String())
+		return fields.Err("request body")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		var fields apperr.Fields
+		fields.Add(field, "unknown field")
+		return fields.Err("request body")
+	default:
+		return ErrMalformed
+	}
+}
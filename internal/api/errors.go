@@ -1,33 +1,85 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"log"
 	"net/http"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
 )
 
-type APIError struct {
-	Status  int    `json:"status"`
-	Message string `json:"message"`
+// Problem is an RFC 9457 problem details object.
+type Problem struct {
+	Type   string              `json:"type"`
+	Title  string              `json:"title"`
+	Status int                 `json:"status"`
+	Detail string              `json:"detail,omitempty"`
+	Errors []apperr.FieldError `json:"errors,omitempty"`
 }
 
-func WriteJSONError(w http.ResponseWriter, status int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(APIError{
-		Status:  status,
-		Message: msg,
-	})
+var kindStatus = map[apperr.Kind]int{
+	apperr.KindInvalid:            http.StatusBadRequest,
+	apperr.KindValidation:         http.StatusUnprocessableEntity,
+	apperr.KindNotFound:           http.StatusNotFound,
+	apperr.KindConflict:           http.StatusConflict,
+	apperr.KindInsufficientFunds:  http.StatusConflict,
+	apperr.KindPreconditionFailed: http.StatusPreconditionFailed,
+	apperr.KindPaymentRequired:    http.StatusPaymentRequired,
+	apperr.KindTooLarge:           http.StatusRequestEntityTooLarge,
+	apperr.KindGone:               http.StatusGone,
 }
 
-type IDParsingError struct {
-	value string
+func writeProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
 }
 
-func NewIDParsingError(value string) error {
-	return &IDParsingError{value: value}
+// WriteError maps err to a problem details response. Errors that are not an
+// *apperr.Error are logged and reported as a bare 500 so that internal
+// details such as database errors never reach the client. Requests that ran
+// out of time get a 503, and cancelled ones, whose client has gone away,
+// are not logged.
+func WriteError(w http.ResponseWriter, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		WriteJSONError(w, http.StatusServiceUnavailable, "the request took too long")
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		WriteJSONError(w, http.StatusServiceUnavailable, "the request was cancelled")
+		return
+	}
+
+	var appErr *apperr.Error
+	if !errors.As(err, &appErr) {
+		log.Printf("Internal error: %s", err)
+		WriteJSONError(w, http.StatusInternalServerError, "")
+		return
+	}
+
+	status, ok := kindStatus[appErr.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	writeProblem(w, Problem{
+		Type:   "/problems/" + string(appErr.Kind),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: appErr.Message,
+		Errors: appErr.Fields,
+	})
 }
 
-func (e *IDParsingError) Error() string {
-	return fmt.Sprintf("parsing id: invalid value '%v'", e.value)
+// WriteJSONError writes a problem details response that has no more specific
+// type than its status.
+func WriteJSONError(w http.ResponseWriter, status int, msg string) {
+	writeProblem(w, Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: msg,
+	})
 }
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+)
+
+var ErrInvalidIfMatch = apperr.Invalid("If-Match must be '*' or a single strong entity tag")
+
+// ETag formats a row version as a strong entity tag.
+func ETag(version int32) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// IfMatch returns the version a conditional request expects, or nil when the
+// request is unconditional (no If-Match header, or If-Match: *).
+func IfMatch(r *http.Request) (*int32, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(v, "W/") || len(v) < 2 || v[0] != '"' || v[len(v)-1] != '"' {
+		return nil, ErrInvalidIfMatch
+	}
+
+	version, err := strconv.ParseInt(v[1:len(v)-1], 10, 32)
+	if err != nil {
+		return nil, ErrInvalidIfMatch
+	}
+
+	v32 := int32(version)
+	return &v32, nil
+}
+
+// NotModified reports whether the request's If-None-Match header already
+// matches etag.
+func NotModified(r *http.Request, etag string) bool {
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
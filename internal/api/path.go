@@ -0,0 +1,35 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+)
+
+// PathID parses the path value name as a 32-bit id. Values out of range are
+// rejected rather than wrapped onto another id.
+func PathID(r *http.Request, name string) (int32, error) {
+	v := r.PathValue(name)
+	id, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return 0, invalidID(v)
+	}
+	return int32(id), nil
+}
+
+// PathUUID parses the path value name as a UUID.
+func PathUUID(r *http.Request, name string) (uuid.UUID, error) {
+	v := r.PathValue(name)
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return uuid.Nil, invalidID(v)
+	}
+	return id, nil
+}
+
+func invalidID(v string) error {
+	return apperr.Invalid(fmt.Sprintf("parsing id: invalid value '%v'", v))
+}
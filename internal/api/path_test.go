@@ -0,0 +1,56 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hossokawa/go-nethttp-example/internal/api"
+)
+
+// TestPathID checks that ids outside the 32-bit range are rejected as an
+// invalid argument rather than wrapped onto another id.
+func TestPathID(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int32
+		ok    bool
+	}{
+		{"1", 1, true},
+		{"2147483647", 2147483647, true},
+		{"-5", -5, true},
+		{"2147483648", 0, false},
+		{"4294967297", 0, false},
+		{"abc", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			var got int32
+			var err error
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /player/{id}", func(w http.ResponseWriter, r *http.Request) {
+				got, err = api.PathID(r, "id")
+				if err != nil {
+					api.WriteError(w, err)
+				}
+			})
+
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/player/"+tt.value, nil))
+
+			if tt.ok {
+				if err != nil || got != tt.want {
+					t.Fatalf("PathID(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("PathID(%q) = %d, want an error", tt.value, got)
+			}
+			if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != "application/problem+json" {
+				t.Fatalf("got %d %s, want a 400 problem", rec.Code, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
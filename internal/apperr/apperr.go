@@ -0,0 +1,79 @@
+// Package apperr defines the errors the domain packages report to callers.
+// Each error has a Kind that says what went wrong independently of the
+// transport, and the api package maps kinds to HTTP responses.
+package apperr
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Kind string
+
+const (
+	KindInvalid            Kind = "invalid"
+	KindValidation         Kind = "validation"
+	KindNotFound           Kind = "not-found"
+	KindConflict           Kind = "conflict"
+	KindInsufficientFunds  Kind = "insufficient-funds"
+	KindPreconditionFailed Kind = "precondition-failed"
+	KindPaymentRequired    Kind = "payment-required"
+	KindTooLarge           Kind = "too-large"
+	KindGone               Kind = "gone"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Kind    Kind
+	Message string
+	Fields  []FieldError
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = fmt.Sprintf("%s: %s", f.Field, f.Message)
+	}
+	return e.Message + ": " + strings.Join(msgs, "; ")
+}
+
+func New(kind Kind, msg string) *Error {
+	return &Error{Kind: kind, Message: msg}
+}
+
+func Invalid(msg string) *Error {
+	return New(KindInvalid, msg)
+}
+
+func Conflict(msg string) *Error {
+	return New(KindConflict, msg)
+}
+
+func NotFound(resource, attribute string, value any) *Error {
+	return New(KindNotFound, fmt.Sprintf("%s with %s '%v' not found", resource, attribute, value))
+}
+
+// Fields collects every invalid field of a request so they can be reported
+// together.
+type Fields []FieldError
+
+func (f *Fields) Add(field, msg string) {
+	*f = append(*f, FieldError{Field: field, Message: msg})
+}
+
+// Err returns a validation error for resource listing the collected fields,
+// or nil if there are none.
+func (f Fields) Err(resource string) error {
+	if len(f) == 0 {
+		return nil
+	}
+	return &Error{Kind: KindValidation, Message: "invalid " + resource, Fields: f}
+}
@@ -0,0 +1,24 @@
+package bank
+
+import "github.com/hossokawa/go-nethttp-example/internal/item"
+
+const (
+	// KindBank is a player's personal bank.
+	KindBank = "bank"
+	// KindShared is the storage shared by every player of an account.
+	KindShared = "shared"
+)
+
+// StorageRef identifies a storage: a player id for a bank, an account id for
+// shared storage.
+type StorageRef struct {
+	Kind    string `json:"kind"`
+	OwnerID int32  `json:"owner_id"`
+}
+
+type Storage struct {
+	StorageRef
+	Capacity int32       `json:"capacity"`
+	Gold     int32       `json:"gold"`
+	Items    []item.Item `json:"items"`
+}
@@ -0,0 +1,185 @@
+package bank
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+)
+
+type memRepository struct {
+	db *memdb.DB
+}
+
+// NewMemoryRepository returns a BankRepository that keeps banks and shared
+// storages in db. It behaves like the Postgres repository, down to the errors
+// it returns.
+func NewMemoryRepository(db *memdb.DB) BankRepository {
+	return &memRepository{db: db}
+}
+
+// ensure creates the storage on first use, like lock does in Postgres, and
+// returns it.
+func ensure(t *memdb.Tables, ref StorageRef) (memdb.StorageKey, memdb.Storage, error) {
+	q, err := queriesFor(ref.Kind)
+	if err != nil {
+		return memdb.StorageKey{}, memdb.Storage{}, err
+	}
+
+	key := memdb.StorageKey{Kind: ref.Kind, OwnerID: ref.OwnerID}
+	if s, ok := t.Storages[key]; ok {
+		return key, s, nil
+	}
+
+	var ownerOK bool
+	switch ref.Kind {
+	case KindBank:
+		_, ownerOK = t.Players[ref.OwnerID]
+	case KindShared:
+		_, ownerOK = t.Accounts[ref.OwnerID]
+	}
+	if !ownerOK {
+		return key, memdb.Storage{}, fmt.Errorf("creating storage: %w", memdb.ErrForeignKey)
+	}
+
+	s := memdb.Storage{Capacity: q.capacity}
+	t.Storages[key] = s
+
+	return key, s, nil
+}
+
+func (r *memRepository) GetStorage(ctx context.Context, ref StorageRef) (*Storage, error) {
+	var s *Storage
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		key, row, err := ensure(t, ref)
+		if err != nil {
+			return err
+		}
+
+		s = &Storage{StorageRef: ref, Capacity: row.Capacity, Gold: row.Gold, Items: []item.Item{}}
+		for k := range t.StorageItems {
+			if k.Storage != key {
+				continue
+			}
+			i, ok := t.Items[k.ItemID]
+			if !ok || i.DeletedAt != nil {
+				continue
+			}
+			s.Items = append(s.Items, item.Item{ID: i.ID, Name: i.Name, Value: i.Value, Stats: maps.Clone(i.Stats)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(s.Items, func(a, b item.Item) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+
+	return s, nil
+}
+
+func (r *memRepository) DepositItem(ctx context.Context, args MoveItemParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		key, s, err := ensure(t, args.Storage)
+		if err != nil {
+			return err
+		}
+
+		var count int32
+		for k := range t.StorageItems {
+			if k.Storage == key {
+				count++
+			}
+		}
+		if count >= s.Capacity {
+			return ErrStorageFull
+		}
+
+		invKey := memdb.InventoryKey{PlayerID: args.PlayerID, ItemID: args.ItemID}
+		inv, ok := t.Inventory[invKey]
+		if !ok {
+			return ErrItemNotInInventory
+		}
+		if inv.Equipped {
+			return ErrItemEquipped
+		}
+		delete(t.Inventory, invKey)
+
+		itemKey := memdb.StorageItemKey{Storage: key, ItemID: args.ItemID}
+		if t.StorageItems[itemKey] {
+			return ErrItemAlreadyStored
+		}
+		t.StorageItems[itemKey] = true
+
+		return nil
+	})
+}
+
+func (r *memRepository) WithdrawItem(ctx context.Context, args MoveItemParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		key, _, err := ensure(t, args.Storage)
+		if err != nil {
+			return err
+		}
+
+		itemKey := memdb.StorageItemKey{Storage: key, ItemID: args.ItemID}
+		if !t.StorageItems[itemKey] {
+			return ErrItemNotInStorage
+		}
+		delete(t.StorageItems, itemKey)
+
+		if _, ok := t.Players[args.PlayerID]; !ok {
+			return fmt.Errorf("adding item to player's inventory: %w", memdb.ErrForeignKey)
+		}
+		invKey := memdb.InventoryKey{PlayerID: args.PlayerID, ItemID: args.ItemID}
+		if _, ok := t.Inventory[invKey]; ok {
+			return ErrItemAlreadyCarried
+		}
+		t.Inventory[invKey] = memdb.InventoryRow{}
+
+		return nil
+	})
+}
+
+func (r *memRepository) DepositGold(ctx context.Context, args MoveGoldParams) error {
+	return r.moveGold(ctx, args, -args.Amount)
+}
+
+func (r *memRepository) WithdrawGold(ctx context.Context, args MoveGoldParams) error {
+	return r.moveGold(ctx, args, args.Amount)
+}
+
+// moveGold adds delta to the player's gold and takes it from the storage,
+// failing if either would go below zero.
+func (r *memRepository) moveGold(ctx context.Context, args MoveGoldParams, delta int32) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		key, s, err := ensure(t, args.Storage)
+		if err != nil {
+			return err
+		}
+
+		p, ok := t.Players[args.PlayerID]
+		if !ok || p.DeletedAt != nil || p.Gold+delta < 0 {
+			return ErrInsufficientFunds
+		}
+		if s.Gold-delta < 0 {
+			return ErrInsufficientFunds
+		}
+
+		p.Gold += delta
+		p.Version++
+		p.UpdatedAt = time.Now()
+		t.Players[p.ID] = p
+
+		s.Gold -= delta
+		t.Storages[key] = s
+
+		return nil
+	})
+}
@@ -0,0 +1,350 @@
+package bank
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrStorageFull        = apperr.Conflict("storage is full")
+	ErrItemNotInInventory = apperr.New(apperr.KindNotFound, "item not in inventory")
+	ErrItemEquipped       = apperr.Conflict("equipped items cannot be stored")
+	ErrItemNotInStorage   = apperr.New(apperr.KindNotFound, "item not in storage")
+	ErrItemAlreadyStored  = apperr.Conflict("item is already in storage")
+	ErrItemAlreadyCarried = apperr.Conflict("item is already in inventory")
+	ErrInsufficientFunds  = apperr.New(apperr.KindInsufficientFunds, "insufficient funds")
+)
+
+const (
+	DefaultBankCapacity   = 50
+	DefaultSharedCapacity = 100
+)
+
+type BankRepository interface {
+	GetStorage(ctx context.Context, ref StorageRef) (*Storage, error)
+	DepositItem(ctx context.Context, args MoveItemParams) error
+	WithdrawItem(ctx context.Context, args MoveItemParams) error
+	DepositGold(ctx context.Context, args MoveGoldParams) error
+	WithdrawGold(ctx context.Context, args MoveGoldParams) error
+}
+
+type pgRepository struct {
+	db *database.DB
+}
+
+func NewPostgresRepository(db *database.DB) BankRepository {
+	return &pgRepository{db: db}
+}
+
+// Banks and shared storages have the same shape and only differ in the
+// tables they live in and the column that identifies their owner.
+type storageQueries struct {
+	capacity     int32
+	ensure       string
+	lock         string
+	listItems    string
+	countItems   string
+	insertItem   string
+	deleteItem   string
+	increaseGold string
+	decreaseGold string
+}
+
+func newStorageQueries(storage, items, owner string, capacity int32) storageQueries {
+	return storageQueries{
+		capacity: capacity,
+		ensure: fmt.Sprintf(`
+INSERT INTO %[1]s (%[2]s, capacity, gold) VALUES ($1, %[3]d, 0)
+ON CONFLICT (%[2]s) DO NOTHING
+`, storage, owner, capacity),
+		lock: fmt.Sprintf(`
+SELECT capacity, gold FROM %[1]s WHERE %[2]s = $1 FOR UPDATE
+`, storage, owner),
+		listItems: fmt.Sprintf(`
+SELECT item.id, item.name, item.value, item.stats
+FROM %[1]s
+JOIN item ON item.id = item_id
+WHERE %[2]s = $1 AND item.deleted_at IS NULL
+`, items, owner),
+		countItems: fmt.Sprintf(`
+SELECT count(*) FROM %[1]s WHERE %[2]s = $1
+`, items, owner),
+		insertItem: fmt.Sprintf(`
+INSERT INTO %[1]s (%[2]s, item_id) VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`, items, owner),
+		deleteItem: fmt.Sprintf(`
+DELETE FROM %[1]s WHERE %[2]s = $1 AND item_id = $2
+`, items, owner),
+		increaseGold: fmt.Sprintf(`
+UPDATE %[1]s SET gold = gold + $2 WHERE %[2]s = $1
+`, storage, owner),
+		decreaseGold: fmt.Sprintf(`
+UPDATE %[1]s SET gold = gold - $2 WHERE %[2]s = $1 AND gold >= $2
+`, storage, owner),
+	}
+}
+
+var queries = map[string]storageQueries{
+	KindBank:   newStorageQueries("bank", "bank_item", "player_id", DefaultBankCapacity),
+	KindShared: newStorageQueries("account_storage", "account_storage_item", "account_id", DefaultSharedCapacity),
+}
+
+func queriesFor(kind string) (storageQueries, error) {
+	q, ok := queries[kind]
+	if !ok {
+		return storageQueries{}, fmt.Errorf("unknown storage kind '%v'", kind)
+	}
+	return q, nil
+}
+
+// lock creates the storage on first use and locks its row for the rest of the
+// transaction, so concurrent moves into the same storage cannot exceed its
+// capacity.
+func lock(ctx context.Context, tx pgx.Tx, q storageQueries, ownerID int32) (capacity, gold int32, err error) {
+	if _, err = tx.Exec(ctx, q.ensure, ownerID); err != nil {
+		return 0, 0, fmt.Errorf("creating storage: %w", err)
+	}
+	if err = tx.QueryRow(ctx, q.lock, ownerID).Scan(&capacity, &gold); err != nil {
+		return 0, 0, fmt.Errorf("locking storage: %w", err)
+	}
+	return capacity, gold, nil
+}
+
+func (r *pgRepository) GetStorage(ctx context.Context, ref StorageRef) (*Storage, error) {
+	ctx = querylabel.With(ctx, "bank", "GetStorage")
+
+	q, err := queriesFor(ref.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	s := Storage{StorageRef: ref}
+
+	s.Capacity, s.Gold, err = lock(ctx, tx, q, ref.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, q.listItems, ref.OwnerID)
+	if err != nil {
+		return nil, fmt.Errorf("getting all items in storage: %w", err)
+	}
+
+	s.Items, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (item.Item, error) {
+		var i item.Item
+		err := row.Scan(&i.ID, &i.Name, &i.Value, &i.Stats)
+		return i, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning rows from storage into item struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return &s, nil
+}
+
+const takeFromInventory = `
+DELETE FROM inventory
+WHERE player_id = $1
+AND item_id = $2
+RETURNING equipped
+`
+
+const putInInventory = `
+INSERT INTO inventory (player_id, item_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type MoveItemParams struct {
+	PlayerID int32      `json:"player_id"`
+	Storage  StorageRef `json:"storage"`
+	ItemID   uuid.UUID  `json:"item_id"`
+}
+
+// DepositItem moves an item from the player's inventory into the storage in
+// a single transaction.
+func (r *pgRepository) DepositItem(ctx context.Context, args MoveItemParams) error {
+	ctx = querylabel.With(ctx, "bank", "DepositItem")
+
+	q, err := queriesFor(args.Storage.Kind)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	capacity, _, err := lock(ctx, tx, q, args.Storage.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	var count int32
+	if err = tx.QueryRow(ctx, q.countItems, args.Storage.OwnerID).Scan(&count); err != nil {
+		return fmt.Errorf("counting items in storage: %w", err)
+	}
+	if count >= capacity {
+		return ErrStorageFull
+	}
+
+	var equipped bool
+	err = tx.QueryRow(ctx, takeFromInventory, args.PlayerID, args.ItemID).Scan(&equipped)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrItemNotInInventory
+		}
+		return fmt.Errorf("removing item from player's inventory: %w", err)
+	}
+	if equipped {
+		return ErrItemEquipped
+	}
+
+	tag, err := tx.Exec(ctx, q.insertItem, args.Storage.OwnerID, args.ItemID)
+	if err != nil {
+		return fmt.Errorf("adding item to storage: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrItemAlreadyStored
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+// WithdrawItem moves an item from the storage into the player's inventory in
+// a single transaction.
+func (r *pgRepository) WithdrawItem(ctx context.Context, args MoveItemParams) error {
+	ctx = querylabel.With(ctx, "bank", "WithdrawItem")
+
+	q, err := queriesFor(args.Storage.Kind)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, _, err = lock(ctx, tx, q, args.Storage.OwnerID); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, q.deleteItem, args.Storage.OwnerID, args.ItemID)
+	if err != nil {
+		return fmt.Errorf("removing item from storage: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrItemNotInStorage
+	}
+
+	tag, err = tx.Exec(ctx, putInInventory, args.PlayerID, args.ItemID)
+	if err != nil {
+		return fmt.Errorf("adding item to player's inventory: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrItemAlreadyCarried
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Deposits are debited like any other spending of gold. Withdrawals are not
+// clamped to the gold cap like earnings are, since the gold was the player's
+// already and would otherwise be lost.
+const givePlayerGold = `
+UPDATE player SET gold = gold + $2, version = version + 1, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type MoveGoldParams struct {
+	PlayerID int32      `json:"player_id"`
+	Storage  StorageRef `json:"storage"`
+	Amount   int32      `json:"amount"`
+}
+
+func (r *pgRepository) DepositGold(ctx context.Context, args MoveGoldParams) error {
+	ctx = querylabel.With(ctx, "bank", "DepositGold")
+
+	q, err := queriesFor(args.Storage.Kind)
+	if err != nil {
+		return err
+	}
+
+	return r.moveGold(ctx, q, args, player.DecreaseGoldSQL, q.increaseGold)
+}
+
+func (r *pgRepository) WithdrawGold(ctx context.Context, args MoveGoldParams) error {
+	ctx = querylabel.With(ctx, "bank", "WithdrawGold")
+
+	q, err := queriesFor(args.Storage.Kind)
+	if err != nil {
+		return err
+	}
+
+	return r.moveGold(ctx, q, args, givePlayerGold, q.decreaseGold)
+}
+
+func (r *pgRepository) moveGold(ctx context.Context, q storageQueries, args MoveGoldParams, playerQuery, storageQuery string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, _, err = lock(ctx, tx, q, args.Storage.OwnerID); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, playerQuery, args.PlayerID, args.Amount)
+	if err != nil {
+		return fmt.Errorf("updating player gold: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInsufficientFunds
+	}
+
+	tag, err = tx.Exec(ctx, storageQuery, args.Storage.OwnerID, args.Amount)
+	if err != nil {
+		return fmt.Errorf("updating storage gold: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInsufficientFunds
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package bank
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+)
+
+// PlayerLookup is the part of the player service the bank depends on.
+type PlayerLookup interface {
+	GetPlayerByID(ctx context.Context, id int32) (*player.Player, error)
+}
+
+type BankService struct {
+	repo    BankRepository
+	players PlayerLookup
+}
+
+func NewBankService(repo BankRepository, players PlayerLookup) *BankService {
+	return &BankService{repo: repo, players: players}
+}
+
+var (
+	ErrNoAccount      = apperr.Conflict("player does not belong to an account")
+	ErrInvalidAmount  = apperr.Invalid("amount must be positive")
+	ErrUnknownStorage = apperr.Invalid("unknown storage kind")
+)
+
+// storageOf resolves which storage of the given kind the player can access.
+func (s *BankService) storageOf(ctx context.Context, playerID int32, kind string) (StorageRef, error) {
+	p, err := s.players.GetPlayerByID(ctx, playerID)
+	if err != nil {
+		return StorageRef{}, err
+	}
+
+	switch kind {
+	case KindBank:
+		return StorageRef{Kind: KindBank, OwnerID: p.ID}, nil
+	case KindShared:
+		if p.AccountID == nil {
+			return StorageRef{}, ErrNoAccount
+		}
+		return StorageRef{Kind: KindShared, OwnerID: *p.AccountID}, nil
+	default:
+		return StorageRef{}, ErrUnknownStorage
+	}
+}
+
+func (s *BankService) GetStorage(ctx context.Context, playerID int32, kind string) (*Storage, error) {
+	ref, err := s.storageOf(ctx, playerID, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := s.repo.GetStorage(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("getting %v storage for player with id %v: %w", kind, playerID, err)
+	}
+	if st.Items == nil {
+		st.Items = []item.Item{}
+	}
+
+	return st, nil
+}
+
+func (s *BankService) DepositItem(ctx context.Context, playerID int32, kind string, itemID uuid.UUID) error {
+	ref, err := s.storageOf(ctx, playerID, kind)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.DepositItem(ctx, MoveItemParams{PlayerID: playerID, Storage: ref, ItemID: itemID})
+	if err != nil {
+		return fmt.Errorf("depositing item with id %v into %v storage of player with id %v: %w", itemID, kind, playerID, err)
+	}
+
+	return nil
+}
+
+func (s *BankService) WithdrawItem(ctx context.Context, playerID int32, kind string, itemID uuid.UUID) error {
+	ref, err := s.storageOf(ctx, playerID, kind)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.WithdrawItem(ctx, MoveItemParams{PlayerID: playerID, Storage: ref, ItemID: itemID})
+	if err != nil {
+		return fmt.Errorf("withdrawing item with id %v from %v storage of player with id %v: %w", itemID, kind, playerID, err)
+	}
+
+	return nil
+}
+
+func (s *BankService) DepositGold(ctx context.Context, playerID int32, kind string, amount int32) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
+	ref, err := s.storageOf(ctx, playerID, kind)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.DepositGold(ctx, MoveGoldParams{PlayerID: playerID, Storage: ref, Amount: amount})
+	if err != nil {
+		return fmt.Errorf("depositing gold into %v storage of player with id %v: %w", kind, playerID, err)
+	}
+
+	return nil
+}
+
+func (s *BankService) WithdrawGold(ctx context.Context, playerID int32, kind string, amount int32) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
+	ref, err := s.storageOf(ctx, playerID, kind)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.WithdrawGold(ctx, MoveGoldParams{PlayerID: playerID, Storage: ref, Amount: amount})
+	if err != nil {
+		return fmt.Errorf("withdrawing gold from %v storage of player with id %v: %w", kind, playerID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,323 @@
+// Package config loads the application settings. Every setting has a default
+// and can be overridden by an optional JSON config file, then by an
+// environment variable, then by a command-line flag.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+	"github.com/hossokawa/go-nethttp-example/internal/storage"
+)
+
+type Config struct {
+	Server   Server
+	Storage  Storage
+	Database Database
+	Username Username
+	Game     Game
+	Shop     Shop
+
+	// DevMode enables shortcuts that are unsafe in production, such as
+	// accepting fake purchase receipts.
+	DevMode bool
+
+	// sources records where each setting was last set, by key.
+	sources map[string]string
+}
+
+type Server struct {
+	Addr         string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// RequestTimeout is how long a request may take, unless its route
+	// allows longer. Queries still running when it runs out are cancelled.
+	RequestTimeout time.Duration
+	// ShutdownTimeout is how long in-flight requests and background workers
+	// get to finish on shutdown.
+	ShutdownTimeout time.Duration
+	// ShutdownDelay is how long the server keeps serving after a shutdown
+	// signal while reporting not ready, so the orchestrator can stop routing
+	// requests to it first.
+	ShutdownDelay time.Duration
+}
+
+type Storage struct {
+	// Backend is storage.Postgres, storage.Memory or storage.SQLite.
+	Backend    string
+	SQLitePath string
+}
+
+type Database struct {
+	database.Config
+	// AutoMigrate applies the pending migrations on startup.
+	AutoMigrate bool
+}
+
+// Username names word lists that extend the built-in reserved and profane
+// usernames.
+type Username struct {
+	ReservedFile  string
+	ProfanityFile string
+}
+
+type Shop struct {
+	// VerifierURL is the payment provider endpoint purchase receipts are
+	// verified with. It is required to sell anything outside of dev mode.
+	VerifierURL string
+}
+
+type Game struct {
+	StartingGold int32
+	// RestoreGracePeriod is how long deleted players and items can be
+	// restored before they are purged.
+	RestoreGracePeriod time.Duration
+	RenameCooldown     time.Duration
+	NameHoldPeriod     time.Duration
+}
+
+// Default returns the settings used when nothing overrides them.
+func Default() *Config {
+	policy := player.DefaultUsernamePolicy()
+
+	return &Config{
+		Server: Server{
+			Addr:            ":8080",
+			ReadTimeout:     5 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     time.Minute,
+			RequestTimeout:  5 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+		},
+		Storage: Storage{
+			Backend:    storage.Postgres,
+			SQLitePath: "mmorpg.db",
+		},
+		Game: Game{
+			RestoreGracePeriod: 30 * 24 * time.Hour,
+			RenameCooldown:     policy.RenameCooldown,
+			NameHoldPeriod:     policy.HoldPeriod,
+		},
+		sources: make(map[string]string),
+	}
+}
+
+// setting binds a field of Config to its key, which names it in the config
+// file and as a flag, and to its environment variable.
+type setting struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	value  value
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "server.addr", env: "HTTP_ADDR", usage: "address to listen on", value: stringValue{&c.Server.Addr}},
+		{key: "server.read_timeout", env: "HTTP_READ_TIMEOUT", usage: "maximum time to read a request", value: durationValue{&c.Server.ReadTimeout}},
+		{key: "server.write_timeout", env: "HTTP_WRITE_TIMEOUT", usage: "maximum time to write a response", value: durationValue{&c.Server.WriteTimeout}},
+		{key: "server.idle_timeout", env: "HTTP_IDLE_TIMEOUT", usage: "how long idle keep-alive connections stay open", value: durationValue{&c.Server.IdleTimeout}},
+		{key: "server.request_timeout", env: "HTTP_REQUEST_TIMEOUT", usage: "how long a request may take before its queries are cancelled", value: durationValue{&c.Server.RequestTimeout}},
+		{key: "server.shutdown_timeout", env: "HTTP_SHUTDOWN_TIMEOUT", usage: "how long to wait for requests and workers to finish on shutdown", value: durationValue{&c.Server.ShutdownTimeout}},
+		{key: "server.shutdown_delay", env: "HTTP_SHUTDOWN_DELAY", usage: "how long to keep serving, reported not ready, before shutting down", value: durationValue{&c.Server.ShutdownDelay}},
+		{key: "storage.backend", env: "STORAGE", usage: "storage backend: postgres, memory or sqlite (built with -tags sqlite)", value: stringValue{&c.Storage.Backend}},
+		{key: "storage.sqlite_path", env: "SQLITE_PATH", usage: "database file of the sqlite backend", value: stringValue{&c.Storage.SQLitePath}},
+		{key: "db.url", env: "DB_URL", usage: "Postgres connection URL", secret: true, value: stringValue{&c.Database.URL}},
+		{key: "db.max_conns", env: "DB_MAX_CONNS", usage: "maximum pool size (0 keeps the pool default)", value: int32Value{&c.Database.MaxConns}},
+		{key: "db.min_conns", env: "DB_MIN_CONNS", usage: "minimum pool size (0 keeps the pool default)", value: int32Value{&c.Database.MinConns}},
+		{key: "db.max_conn_lifetime", env: "DB_MAX_CONN_LIFETIME", usage: "maximum connection age (0 keeps the pool default)", value: durationValue{&c.Database.MaxConnLifetime}},
+		{key: "db.max_conn_idle_time", env: "DB_MAX_CONN_IDLE_TIME", usage: "maximum connection idle time (0 keeps the pool default)", value: durationValue{&c.Database.MaxConnIdleTime}},
+		{key: "db.auto_migrate", env: "DB_AUTO_MIGRATE", usage: "apply pending migrations on startup", value: boolValue{&c.Database.AutoMigrate}},
+		{key: "username.reserved_file", env: "USERNAME_RESERVED_FILE", usage: "file of extra reserved usernames", value: stringValue{&c.Username.ReservedFile}},
+		{key: "username.profanity_file", env: "USERNAME_PROFANITY_FILE", usage: "file of extra profane words", value: stringValue{&c.Username.ProfanityFile}},
+		{key: "game.starting_gold", env: "GAME_STARTING_GOLD", usage: "gold new players start with", value: int32Value{&c.Game.StartingGold}},
+		{key: "game.restore_grace_period", env: "GAME_RESTORE_GRACE_PERIOD", usage: "how long deleted players and items can be restored", value: durationValue{&c.Game.RestoreGracePeriod}},
+		{key: "game.rename_cooldown", env: "GAME_RENAME_COOLDOWN", usage: "time a player has to wait between renames", value: durationValue{&c.Game.RenameCooldown}},
+		{key: "game.name_hold_period", env: "GAME_NAME_HOLD_PERIOD", usage: "how long a name given up in a rename stays reserved", value: durationValue{&c.Game.NameHoldPeriod}},
+		{key: "shop.verifier_url", env: "SHOP_VERIFIER_URL", usage: "payment provider endpoint that verifies purchase receipts", secret: true, value: stringValue{&c.Shop.VerifierURL}},
+		{key: "dev_mode", env: "DEV_MODE", usage: "accept fake purchase receipts; never enable in production", value: boolValue{&c.DevMode}},
+	}
+}
+
+// Sources a setting can come from, as reported by Print.
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// Load reads the settings. The config file is named by the -config flag or
+// the CONFIG_FILE environment variable; without either, only the environment
+// and the flags override the defaults. args are the command-line arguments
+// without the program name, and Load returns the ones left after the flags.
+// The settings are not validated, so that commands needing only some of them
+// work with the rest missing; commands that open storage call Validate.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet("mmorpg", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "JSON config file")
+
+	// Flags are recorded as they are parsed and only applied last, so they
+	// override the config file they may have named.
+	var flags []flagValue
+	for _, s := range settings {
+		fs.Var(&flagValue{setting: s, set: &flags}, s.key, s.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(settings, *configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		v := os.Getenv(s.env)
+		if v == "" {
+			continue
+		}
+		if err := s.value.Set(v); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s '%s': %w", s.env, v, err)
+		}
+		cfg.sources[s.key] = sourceEnv
+	}
+
+	for _, f := range flags {
+		if err := f.setting.value.Set(f.raw); err != nil {
+			return nil, nil, fmt.Errorf("invalid -%s '%s': %w", f.setting.key, f.raw, err)
+		}
+		cfg.sources[f.setting.key] = sourceFlag
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+const usage = `usage: mmorpg [flags] [command]
+
+commands:
+  (none)         serve the API
+  migrate ...    manage the database schema, see 'mmorpg migrate'
+  config print   print the effective settings with secrets redacted
+
+flags:`
+
+// flagValue records the raw value of a setting given as a flag.
+type flagValue struct {
+	setting setting
+	set     *[]flagValue
+	raw     string
+}
+
+func (f *flagValue) String() string {
+	if f.setting.value == nil {
+		return ""
+	}
+	return f.setting.value.String()
+}
+
+func (f *flagValue) Set(s string) error {
+	// Check the value now so the error points at the flag, but apply it
+	// only after the file and the environment.
+	if err := f.setting.value.check(s); err != nil {
+		return err
+	}
+	*f.set = append(*f.set, flagValue{setting: f.setting, raw: s})
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	_, ok := f.setting.value.(boolValue)
+	return ok
+}
+
+// Validate reports every invalid setting together.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr is required")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.RequestTimeout > 0, "server.request_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay must not be negative")
+	check(c.Server.ShutdownDelay < c.Server.ShutdownTimeout, "server.shutdown_delay must be shorter than server.shutdown_timeout")
+
+	backends := []string{storage.Postgres, storage.Memory, storage.SQLite}
+	check(slices.Contains(backends, c.Storage.Backend), "storage.backend must be one of %v, not '%s'", backends, c.Storage.Backend)
+	check(c.Storage.Backend != storage.SQLite || c.Storage.SQLitePath != "", "storage.sqlite_path is required by the sqlite backend")
+	check(c.Storage.Backend != storage.Postgres || c.Database.URL != "", "db.url is required by the postgres backend")
+
+	check(c.Database.MaxConns >= 0, "db.max_conns must not be negative")
+	check(c.Database.MinConns >= 0, "db.min_conns must not be negative")
+	check(c.Database.MaxConns == 0 || c.Database.MinConns <= c.Database.MaxConns, "db.min_conns must not exceed db.max_conns")
+	check(c.Database.MaxConnLifetime >= 0, "db.max_conn_lifetime must not be negative")
+	check(c.Database.MaxConnIdleTime >= 0, "db.max_conn_idle_time must not be negative")
+
+	check(c.Game.StartingGold >= 0, "game.starting_gold must not be negative")
+	check(c.Game.RestoreGracePeriod >= 0, "game.restore_grace_period must not be negative")
+	check(c.Game.RenameCooldown >= 0, "game.rename_cooldown must not be negative")
+	check(c.Game.NameHoldPeriod >= 0, "game.name_hold_period must not be negative")
+
+	if c.Shop.VerifierURL != "" {
+		u, err := url.Parse(c.Shop.VerifierURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "shop.verifier_url must be an http or https URL")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// Print writes every setting with its value and where the value came from.
+// Secrets are redacted.
+func (c *Config) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tENV\tVALUE\tSOURCE")
+
+	for _, s := range c.settings() {
+		v := s.value.String()
+		if s.secret {
+			v = redact(v)
+		}
+		if v == "" {
+			v = `""`
+		}
+
+		source, ok := c.sources[s.key]
+		if !ok {
+			source = sourceDefault
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.key, s.env, v, source)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// loadFile applies the settings of a JSON config file. Keys are nested by
+// section, so db.max_conns is read from {"db": {"max_conns": 10}}. Durations
+// are strings such as "30s".
+func (c *Config) loadFile(settings []setting, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", doc, values); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	for _, s := range settings {
+		v, ok := values[s.key]
+		if !ok {
+			continue
+		}
+		if err := s.value.Set(v); err != nil {
+			return fmt.Errorf("invalid %s '%s' in config file: %w", s.key, v, err)
+		}
+		c.sources[s.key] = sourceFile
+		delete(values, s.key)
+	}
+
+	if len(values) > 0 {
+		unknown := slices.Sorted(maps.Keys(values))
+		return fmt.Errorf("unknown settings in config file: %s", strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+// flatten collects the leaves of a decoded JSON object into values, keyed by
+// their dotted path.
+func flatten(prefix string, obj map[string]any, values map[string]string) error {
+	for k, v := range obj {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch v := v.(type) {
+		case map[string]any:
+			if err := flatten(key, v, values); err != nil {
+				return err
+			}
+		case string:
+			values[key] = v
+		case json.Number:
+			values[key] = v.String()
+		case bool:
+			values[key] = strconv.FormatBool(v)
+		default:
+			return fmt.Errorf("setting '%s' must be a string, number or boolean", key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// value parses a setting into the Config field it points to.
+type value interface {
+	String() string
+	Set(s string) error
+	// check reports whether Set would accept s, without setting it.
+	check(s string) error
+}
+
+type stringValue struct{ p *string }
+
+func (v stringValue) String() string { return *v.p }
+
+func (v stringValue) Set(s string) error {
+	*v.p = s
+	return nil
+}
+
+func (v stringValue) check(string) error { return nil }
+
+type int32Value struct{ p *int32 }
+
+func (v int32Value) String() string { return strconv.FormatInt(int64(*v.p), 10) }
+
+func (v int32Value) Set(s string) error {
+	n, err := parseInt32(s)
+	if err != nil {
+		return err
+	}
+	*v.p = n
+	return nil
+}
+
+func (v int32Value) check(s string) error {
+	_, err := parseInt32(s)
+	return err
+}
+
+func parseInt32(s string) (int32, error) {
+	n, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0, errors.New("not a 32-bit integer")
+	}
+	return int32(n), nil
+}
+
+type durationValue struct{ p *time.Duration }
+
+func (v durationValue) String() string { return v.p.String() }
+
+func (v durationValue) Set(s string) error {
+	d, err := parseDuration(s)
+	if err != nil {
+		return err
+	}
+	*v.p = d
+	return nil
+}
+
+func (v durationValue) check(s string) error {
+	_, err := parseDuration(s)
+	return err
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.New("not a duration such as 30s or 1h")
+	}
+	return d, nil
+}
+
+type boolValue struct{ p *bool }
+
+func (v boolValue) String() string { return strconv.FormatBool(*v.p) }
+
+func (v boolValue) Set(s string) error {
+	b, err := parseBool(s)
+	if err != nil {
+		return err
+	}
+	*v.p = b
+	return nil
+}
+
+func (v boolValue) check(s string) error {
+	_, err := parseBool(s)
+	return err
+}
+
+func parseBool(s string) (bool, error) {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, errors.New("not a boolean")
+	}
+	return b, nil
+}
+
+// redact hides the password of a connection URL, or the whole value if it is
+// not a URL.
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" {
+		return "REDACTED"
+	}
+
+	if q := u.Query(); q.Has("password") {
+		q.Set("password", "xxxxx")
+		u.RawQuery = q.Encode()
+	}
+
+	return u.Redacted()
+}
@@ -0,0 +1,17 @@
+package currency
+
+// Gold is stored on the player row itself; every other currency is kept in
+// player_currency.
+const Gold = "gold"
+
+type Currency struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Cap  *int32 `json:"cap"`
+}
+
+type Balance struct {
+	PlayerID int32  `json:"player_id"`
+	Currency string `json:"currency"`
+	Amount   int32  `json:"amount"`
+}
@@ -0,0 +1,162 @@
+package currency
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+)
+
+type memRepository struct {
+	db *memdb.DB
+}
+
+// NewMemoryRepository returns a CurrencyRepository that keeps currencies and
+// balances in db. It behaves like the Postgres repository, down to the errors
+// it returns.
+func NewMemoryRepository(db *memdb.DB) CurrencyRepository {
+	return &memRepository{db: db}
+}
+
+func toCurrency(row memdb.Currency) *Currency {
+	return &Currency{Code: row.Code, Name: row.Name, Cap: row.Cap}
+}
+
+func (r *memRepository) GetAllCurrencies(ctx context.Context) ([]*Currency, error) {
+	var currencies []*Currency
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Currencies {
+			currencies = append(currencies, toCurrency(row))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(currencies, func(a, b *Currency) int { return cmp.Compare(a.Code, b.Code) })
+
+	return currencies, nil
+}
+
+func (r *memRepository) GetCurrencyByCode(ctx context.Context, code string) (*Currency, error) {
+	var c *Currency
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		if row, ok := t.Currencies[code]; ok {
+			c = toCurrency(row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (r *memRepository) ListPlayerBalances(ctx context.Context, playerID int32) ([]*Balance, error) {
+	var balances []*Balance
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		p, ok := t.Players[playerID]
+		if !ok || p.DeletedAt != nil {
+			return nil
+		}
+
+		for code := range t.Currencies {
+			b := &Balance{PlayerID: playerID, Currency: code}
+			if code == Gold {
+				b.Amount = p.Gold
+			} else {
+				b.Amount = t.Balances[memdb.BalanceKey{PlayerID: playerID, Currency: code}]
+			}
+			balances = append(balances, b)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(balances, func(a, b *Balance) int { return cmp.Compare(a.Currency, b.Currency) })
+
+	return balances, nil
+}
+
+// capped adds amount to balance, clamping the result to the currency cap like
+// the LEAST expression in increaseBalance.
+func capped(balance, amount int32, c memdb.Currency) int32 {
+	limit := int64(math.MaxInt32)
+	if c.Cap != nil {
+		limit = int64(*c.Cap)
+	}
+	return int32(min(int64(balance)+int64(amount), limit))
+}
+
+func (r *memRepository) IncreaseBalance(ctx context.Context, args UpdateBalanceParams) (int32, error) {
+	var balance int32
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		if args.Currency == Gold {
+			var ok bool
+			if balance, ok = t.IncreaseGold(args.PlayerID, args.Amount); !ok {
+				return fmt.Errorf("increasing player balance: %w", memdb.ErrNoRows)
+			}
+			return nil
+		}
+
+		c, ok := t.Currencies[args.Currency]
+		if !ok {
+			return fmt.Errorf("increasing player balance: %w", memdb.ErrNoRows)
+		}
+		if _, ok := t.Players[args.PlayerID]; !ok {
+			return fmt.Errorf("increasing player balance: %w", memdb.ErrForeignKey)
+		}
+
+		key := memdb.BalanceKey{PlayerID: args.PlayerID, Currency: args.Currency}
+		balance = capped(t.Balances[key], args.Amount, c)
+		t.Balances[key] = balance
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
+
+func (r *memRepository) DecreaseBalance(ctx context.Context, args UpdateBalanceParams) (int32, error) {
+	var balance int32
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		if args.Currency == Gold {
+			var ok bool
+			if balance, ok = t.DecreaseGold(args.PlayerID, args.Amount); !ok {
+				return ErrInsufficientFunds
+			}
+			return nil
+		}
+
+		key := memdb.BalanceKey{PlayerID: args.PlayerID, Currency: args.Currency}
+		current, ok := t.Balances[key]
+		if !ok || current < args.Amount {
+			return ErrInsufficientFunds
+		}
+
+		balance = current - args.Amount
+		t.Balances[key] = balance
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
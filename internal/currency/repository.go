@@ -0,0 +1,200 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInsufficientFunds is the error of the player repository, since gold is
+// debited through it too.
+var ErrInsufficientFunds = player.ErrInsufficientFunds
+
+type CurrencyRepository interface {
+	GetAllCurrencies(ctx context.Context) ([]*Currency, error)
+	GetCurrencyByCode(ctx context.Context, code string) (*Currency, error)
+	ListPlayerBalances(ctx context.Context, playerID int32) ([]*Balance, error)
+	IncreaseBalance(ctx context.Context, args UpdateBalanceParams) (int32, error)
+	DecreaseBalance(ctx context.Context, args UpdateBalanceParams) (int32, error)
+}
+
+type pgRepository struct {
+	db *database.DB
+}
+
+func NewPostgresRepository(db *database.DB) CurrencyRepository {
+	return &pgRepository{db: db}
+}
+
+const getAllCurrencies = `
+SELECT code, name, cap FROM currency ORDER BY code
+`
+
+func (r *pgRepository) GetAllCurrencies(ctx context.Context) ([]*Currency, error) {
+	ctx = querylabel.With(ctx, "currency", "GetAllCurrencies")
+
+	rows, err := r.db.Query(ctx, getAllCurrencies)
+	if err != nil {
+		return nil, fmt.Errorf("querying for all currencies: %w", err)
+	}
+	defer rows.Close()
+
+	var currencies []*Currency
+
+	for rows.Next() {
+		var c Currency
+
+		if err := rows.Scan(&c.Code, &c.Name, &c.Cap); err != nil {
+			return nil, fmt.Errorf("scanning rows into currency struct: %w", err)
+		}
+
+		currencies = append(currencies, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return currencies, nil
+}
+
+const getCurrencyByCode = `
+SELECT code, name, cap FROM currency WHERE code = $1
+`
+
+func (r *pgRepository) GetCurrencyByCode(ctx context.Context, code string) (*Currency, error) {
+	ctx = querylabel.With(ctx, "currency", "GetCurrencyByCode")
+
+	var c Currency
+
+	err := r.db.QueryRow(ctx, getCurrencyByCode, code).Scan(&c.Code, &c.Name, &c.Cap)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into currency struct: %w", err)
+	}
+
+	return &c, nil
+}
+
+const listPlayerBalances = `
+SELECT p.id, c.code, COALESCE(CASE WHEN c.code = 'gold' THEN p.gold ELSE pc.balance END, 0)
+FROM currency c
+JOIN player p ON p.id = $1 AND p.deleted_at IS NULL
+LEFT JOIN player_currency pc ON pc.currency_code = c.code AND pc.player_id = p.id
+ORDER BY c.code
+`
+
+func (r *pgRepository) ListPlayerBalances(ctx context.Context, playerID int32) ([]*Balance, error) {
+	ctx = querylabel.With(ctx, "currency", "ListPlayerBalances")
+
+	rows, err := r.db.Query(ctx, listPlayerBalances, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("querying for player balances: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []*Balance
+
+	for rows.Next() {
+		var b Balance
+
+		if err := rows.Scan(&b.PlayerID, &b.Currency, &b.Amount); err != nil {
+			return nil, fmt.Errorf("scanning rows into balance struct: %w", err)
+		}
+
+		balances = append(balances, &b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+type UpdateBalanceParams struct {
+	PlayerID int32  `json:"player_id"`
+	Currency string `json:"currency"`
+	Amount   int32  `json:"amount"`
+}
+
+// Balances are clamped to the currency cap on increase; anything earned above
+// the cap is lost. Gold is credited with player.IncreaseGoldSQL, which does
+// the same.
+const increaseBalance = `
+INSERT INTO player_currency (player_id, currency_code, balance)
+SELECT $1, c.code, LEAST($3::bigint, COALESCE(c.cap, 2147483647))::int FROM currency c WHERE c.code = $2
+ON CONFLICT (player_id, currency_code) DO UPDATE SET
+  balance = LEAST(
+    player_currency.balance::bigint + $3,
+    COALESCE((SELECT cap FROM currency WHERE code = $2), 2147483647)
+  )::int
+RETURNING balance
+`
+
+func (r *pgRepository) IncreaseBalance(ctx context.Context, args UpdateBalanceParams) (int32, error) {
+	ctx = querylabel.With(ctx, "currency", "IncreaseBalance")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var balance int32
+
+	if args.Currency == Gold {
+		err = tx.QueryRow(ctx, player.IncreaseGoldSQL, args.PlayerID, args.Amount).Scan(&balance)
+	} else {
+		err = tx.QueryRow(ctx, increaseBalance, args.PlayerID, args.Currency, args.Amount).Scan(&balance)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("increasing player balance: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return balance, nil
+}
+
+const decreaseBalance = `
+UPDATE player_currency SET balance = balance - $3
+WHERE player_id = $1 AND currency_code = $2 AND balance >= $3
+RETURNING balance
+`
+
+func (r *pgRepository) DecreaseBalance(ctx context.Context, args UpdateBalanceParams) (int32, error) {
+	ctx = querylabel.With(ctx, "currency", "DecreaseBalance")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var balance int32
+
+	if args.Currency == Gold {
+		err = tx.QueryRow(ctx, player.DecreaseGoldSQL, args.PlayerID, args.Amount).Scan(&balance)
+	} else {
+		err = tx.QueryRow(ctx, decreaseBalance, args.PlayerID, args.Currency, args.Amount).Scan(&balance)
+	}
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrInsufficientFunds
+		}
+		return 0, fmt.Errorf("decreasing player balance: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return balance, nil
+}
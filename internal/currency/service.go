@@ -0,0 +1,97 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+)
+
+// PlayerLookup is the part of the player service currencies depend on.
+type PlayerLookup interface {
+	GetPlayerByID(ctx context.Context, id int32) (*player.Player, error)
+}
+
+type CurrencyService struct {
+	repo    CurrencyRepository
+	players PlayerLookup
+}
+
+func NewCurrencyService(repo CurrencyRepository, players PlayerLookup) *CurrencyService {
+	return &CurrencyService{repo: repo, players: players}
+}
+
+var ErrInvalidAmount = apperr.Invalid("amount must be positive")
+
+func (s *CurrencyService) GetAllCurrencies(ctx context.Context) ([]*Currency, error) {
+	return s.repo.GetAllCurrencies(ctx)
+}
+
+func (s *CurrencyService) GetCurrencyByCode(ctx context.Context, code string) (*Currency, error) {
+	c, err := s.repo.GetCurrencyByCode(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("getting currency with code '%v': %w", code, err)
+	}
+	if c == nil {
+		return nil, apperr.NotFound("currency", "code", code)
+	}
+
+	return c, nil
+}
+
+func (s *CurrencyService) ListPlayerBalances(ctx context.Context, playerID int32) ([]*Balance, error) {
+	if _, err := s.players.GetPlayerByID(ctx, playerID); err != nil {
+		return nil, err
+	}
+
+	balances, err := s.repo.ListPlayerBalances(ctx, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("getting balances for player with id %v: %w", playerID, err)
+	}
+
+	return balances, nil
+}
+
+func (s *CurrencyService) validate(ctx context.Context, playerID int32, code string, amount int32) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	if _, err := s.GetCurrencyByCode(ctx, code); err != nil {
+		return err
+	}
+	if _, err := s.players.GetPlayerByID(ctx, playerID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// IncreaseBalance adds amount to the player's balance, clamped to the currency
+// cap, and returns the new balance.
+func (s *CurrencyService) IncreaseBalance(ctx context.Context, playerID int32, code string, amount int32) (int32, error) {
+	if err := s.validate(ctx, playerID, code, amount); err != nil {
+		return 0, err
+	}
+
+	balance, err := s.repo.IncreaseBalance(ctx, UpdateBalanceParams{PlayerID: playerID, Currency: code, Amount: amount})
+	if err != nil {
+		return 0, fmt.Errorf("increasing %v for player with id %v: %w", code, playerID, err)
+	}
+
+	return balance, nil
+}
+
+// DecreaseBalance subtracts amount from the player's balance and returns the
+// new balance. It fails with ErrInsufficientFunds instead of going negative.
+func (s *CurrencyService) DecreaseBalance(ctx context.Context, playerID int32, code string, amount int32) (int32, error) {
+	if err := s.validate(ctx, playerID, code, amount); err != nil {
+		return 0, err
+	}
+
+	balance, err := s.repo.DecreaseBalance(ctx, UpdateBalanceParams{PlayerID: playerID, Currency: code, Amount: amount})
+	if err != nil {
+		return 0, fmt.Errorf("decreasing %v for player with id %v: %w", code, playerID, err)
+	}
+
+	return balance, nil
+}
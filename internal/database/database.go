@@ -0,0 +1,143 @@
+// Package database provides the Postgres connection pool shared by the
+// repositories, and the unit of work that lets one transaction span several
+// of them.
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Config sizes the connection pool. Zero values keep the pgxpool defaults.
+type Config struct {
+	URL               string
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	// Tracer, if set, is told about every query, such as to record metrics.
+	Tracer pgx.QueryTracer
+}
+
+// DB runs queries on a connection pool, or on the transaction carried by the
+// context when called inside InTx.
+type DB struct {
+	pool *pgxpool.Pool
+}
+
+// Open creates the pool and checks that the database is reachable.
+func Open(ctx context.Context, cfg Config) (*DB, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing database url: %w", err)
+	}
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolCfg.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	if cfg.Tracer != nil {
+		poolCfg.ConnConfig.Tracer = cfg.Tracer
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating connection pool: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("pinging database: %w", err)
+	}
+
+	return &DB{pool: pool}, nil
+}
+
+func (db *DB) Close() {
+	db.pool.Close()
+}
+
+// Ping checks that a connection can be acquired and the database answers.
+func (db *DB) Ping(ctx context.Context) error {
+	return db.pool.Ping(ctx)
+}
+
+// Pool returns the underlying pool, for callers that need its statistics.
+func (db *DB) Pool() *pgxpool.Pool {
+	return db.pool
+}
+
+type txKey struct{}
+
+func txFrom(ctx context.Context) pgx.Tx {
+	tx, _ := ctx.Value(txKey{}).(pgx.Tx)
+	return tx
+}
+
+// Begin starts a transaction. Inside InTx it starts a savepoint in the
+// surrounding transaction instead, so repositories can keep managing their
+// own transactions and still take part in a larger unit of work.
+func (db *DB) Begin(ctx context.Context) (pgx.Tx, error) {
+	if tx := txFrom(ctx); tx != nil {
+		return tx.Begin(ctx)
+	}
+	return db.pool.Begin(ctx)
+}
+
+func (db *DB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if tx := txFrom(ctx); tx != nil {
+		return tx.Exec(ctx, sql, args...)
+	}
+	return db.pool.Exec(ctx, sql, args...)
+}
+
+func (db *DB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if tx := txFrom(ctx); tx != nil {
+		return tx.Query(ctx, sql, args...)
+	}
+	return db.pool.Query(ctx, sql, args...)
+}
+
+func (db *DB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if tx := txFrom(ctx); tx != nil {
+		return tx.QueryRow(ctx, sql, args...)
+	}
+	return db.pool.QueryRow(ctx, sql, args...)
+}
+
+// InTx runs fn in a transaction that every DB call made with the context it
+// is given joins. The transaction commits if fn returns nil and rolls back
+// otherwise. Nested calls run in a savepoint of the outer transaction.
+func (db *DB) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
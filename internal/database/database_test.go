@@ -0,0 +1,33 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/repotest"
+)
+
+// TestQueryCancelledInFlight runs against the database at TEST_DB_URL.
+func TestQueryCancelledInFlight(t *testing.T) {
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+
+	db, err := database.Open(t.Context(), database.Config{URL: url})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	repotest.QueryCancelledInFlight(t, repotest.Backend{
+		Slow: func(ctx context.Context) error {
+			_, err := db.Exec(ctx, repotest.SlowPostgresQuery)
+			return err
+		},
+		InUse: func() int { return int(db.Pool().Stat().AcquiredConns()) },
+		Ping:  db.Ping,
+	})
+}
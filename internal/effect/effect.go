@@ -0,0 +1,31 @@
+package effect
+
+import (
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/stats"
+)
+
+type Effect struct {
+	ID        int32     `json:"id"`
+	PlayerID  int32     `json:"player_id"`
+	Type      string    `json:"type"`
+	Stat      string    `json:"stat"`
+	Magnitude int32     `json:"magnitude"`
+	Stacks    int32     `json:"stacks"`
+	MaxStacks int32     `json:"max_stacks"`
+	Source    string    `json:"source"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (e *Effect) Expired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
+
+// Modifier returns the stat change the effect applies. Debuffs are effects
+// with a negative magnitude.
+func (e *Effect) Modifier() stats.Modifier {
+	return stats.Modifier{Stat: e.Stat, Value: e.Magnitude * e.Stacks}
+}
@@ -0,0 +1,186 @@
+package effect
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+)
+
+type memRepository struct {
+	db *memdb.DB
+}
+
+// NewMemoryRepository returns an EffectRepository that keeps effects in db. It
+// behaves like the Postgres repository, down to the errors it returns.
+func NewMemoryRepository(db *memdb.DB) EffectRepository {
+	return &memRepository{db: db}
+}
+
+func toEffect(row memdb.Effect) *Effect {
+	return &Effect{
+		ID:        row.ID,
+		PlayerID:  row.PlayerID,
+		Type:      row.Type,
+		Stat:      row.Stat,
+		Magnitude: row.Magnitude,
+		Stacks:    row.Stacks,
+		MaxStacks: row.MaxStacks,
+		Source:    row.Source,
+		ExpiresAt: row.ExpiresAt,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
+
+// ApplyEffect stacks effects the same way as the upsert in applyEffect.
+func (r *memRepository) ApplyEffect(ctx context.Context, args ApplyEffectParams) (*Effect, error) {
+	var e *Effect
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		if _, ok := t.Players[args.PlayerID]; !ok {
+			return fmt.Errorf("scanning row into effect struct: %w", memdb.ErrForeignKey)
+		}
+
+		now := time.Now()
+		row := memdb.Effect{
+			ID:        t.NextID("player_effect"),
+			PlayerID:  args.PlayerID,
+			Type:      args.Type,
+			Source:    args.Source,
+			Stacks:    1,
+			CreatedAt: now,
+		}
+		for _, existing := range t.Effects {
+			if existing.PlayerID == args.PlayerID && existing.Type == args.Type && existing.Source == args.Source {
+				row = existing
+				if existing.ExpiresAt.After(now) {
+					row.Stacks = min(existing.Stacks+1, args.MaxStacks)
+				} else {
+					row.Stacks = 1
+				}
+				break
+			}
+		}
+
+		row.Stat = args.Stat
+		row.Magnitude = args.Magnitude
+		row.MaxStacks = args.MaxStacks
+		row.ExpiresAt = args.ExpiresAt
+		row.UpdatedAt = now
+		t.Effects[row.ID] = row
+
+		e = toEffect(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *memRepository) GetEffectByID(ctx context.Context, id int32) (*Effect, error) {
+	var e *Effect
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		if row, ok := t.Effects[id]; ok {
+			e = toEffect(row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *memRepository) ListPlayerEffects(ctx context.Context, playerID int32) ([]*Effect, error) {
+	var effects []*Effect
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		now := time.Now()
+		for _, row := range t.Effects {
+			if row.PlayerID == playerID && row.ExpiresAt.After(now) {
+				effects = append(effects, toEffect(row))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(effects, func(a, b *Effect) int {
+		if c := a.ExpiresAt.Compare(b.ExpiresAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return effects, nil
+}
+
+func (r *memRepository) RefreshEffect(ctx context.Context, args RefreshEffectParams) (*Effect, error) {
+	var e *Effect
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		row, ok := t.Effects[args.ID]
+		if !ok {
+			return nil
+		}
+
+		row.ExpiresAt = args.ExpiresAt
+		row.UpdatedAt = time.Now()
+		t.Effects[row.ID] = row
+
+		e = toEffect(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *memRepository) RemoveEffect(ctx context.Context, id int32) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		delete(t.Effects, id)
+		return nil
+	})
+}
+
+// deleteExpired removes the expired effects matching fn and returns how many
+// it removed.
+func (r *memRepository) deleteExpired(ctx context.Context, fn func(row memdb.Effect) bool) (int64, error) {
+	var n int64
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		now := time.Now()
+		for id, row := range t.Effects {
+			if !row.ExpiresAt.After(now) && fn(row) {
+				delete(t.Effects, id)
+				n++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (r *memRepository) DeleteExpiredPlayerEffects(ctx context.Context, playerID int32) (int64, error) {
+	return r.deleteExpired(ctx, func(row memdb.Effect) bool { return row.PlayerID == playerID })
+}
+
+func (r *memRepository) DeleteExpiredEffects(ctx context.Context) (int64, error) {
+	return r.deleteExpired(ctx, func(memdb.Effect) bool { return true })
+}
@@ -0,0 +1,255 @@
+package effect
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+	"github.com/jackc/pgx/v5"
+)
+
+type EffectRepository interface {
+	ApplyEffect(ctx context.Context, args ApplyEffectParams) (*Effect, error)
+	GetEffectByID(ctx context.Context, id int32) (*Effect, error)
+	ListPlayerEffects(ctx context.Context, playerID int32) ([]*Effect, error)
+	RefreshEffect(ctx context.Context, args RefreshEffectParams) (*Effect, error)
+	RemoveEffect(ctx context.Context, id int32) error
+	DeleteExpiredPlayerEffects(ctx context.Context, playerID int32) (int64, error)
+	DeleteExpiredEffects(ctx context.Context) (int64, error)
+}
+
+type pgRepository struct {
+	db *database.DB
+}
+
+func NewPostgresRepository(db *database.DB) EffectRepository {
+	return &pgRepository{db: db}
+}
+
+func scanEffect(row pgx.Row) (*Effect, error) {
+	var e Effect
+
+	err := row.Scan(
+		&e.ID,
+		&e.PlayerID,
+		&e.Type,
+		&e.Stat,
+		&e.Magnitude,
+		&e.Stacks,
+		&e.MaxStacks,
+		&e.Source,
+		&e.ExpiresAt,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// An effect that is still active when re-applied gains a stack (up to
+// max_stacks) and has its duration reset. An effect that already expired but
+// has not been cleaned up yet starts over from a single stack.
+const applyEffect = `
+INSERT INTO player_effect (player_id, type, stat, magnitude, stacks, max_stacks, source, expires_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, 1, $5, $6, $7, now(), now())
+ON CONFLICT (player_id, type, source) DO UPDATE SET
+  stat = EXCLUDED.stat,
+  magnitude = EXCLUDED.magnitude,
+  max_stacks = EXCLUDED.max_stacks,
+  stacks = CASE
+    WHEN player_effect.expires_at <= now() THEN 1
+    ELSE LEAST(player_effect.stacks + 1, EXCLUDED.max_stacks)
+  END,
+  expires_at = EXCLUDED.expires_at,
+  updated_at = now()
+RETURNING id, player_id, type, stat, magnitude, stacks, max_stacks, source, expires_at, created_at, updated_at
+`
+
+type ApplyEffectParams struct {
+	PlayerID  int32     `json:"player_id"`
+	Type      string    `json:"type"`
+	Stat      string    `json:"stat"`
+	Magnitude int32     `json:"magnitude"`
+	MaxStacks int32     `json:"max_stacks"`
+	Source    string    `json:"source"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (r *pgRepository) ApplyEffect(ctx context.Context, args ApplyEffectParams) (*Effect, error) {
+	ctx = querylabel.With(ctx, "effect", "ApplyEffect")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	row := tx.QueryRow(ctx, applyEffect,
+		args.PlayerID,
+		args.Type,
+		args.Stat,
+		args.Magnitude,
+		args.MaxStacks,
+		args.Source,
+		args.ExpiresAt,
+	)
+	e, err := scanEffect(row)
+	if err != nil {
+		return nil, fmt.Errorf("scanning row into effect struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return e, nil
+}
+
+const getEffectByID = `
+SELECT id, player_id, type, stat, magnitude, stacks, max_stacks, source, expires_at, created_at, updated_at
+FROM player_effect
+WHERE id = $1
+`
+
+func (r *pgRepository) GetEffectByID(ctx context.Context, id int32) (*Effect, error) {
+	ctx = querylabel.With(ctx, "effect", "GetEffectByID")
+
+	e, err := scanEffect(r.db.QueryRow(ctx, getEffectByID, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into effect struct: %w", err)
+	}
+
+	return e, nil
+}
+
+const listPlayerEffects = `
+SELECT id, player_id, type, stat, magnitude, stacks, max_stacks, source, expires_at, created_at, updated_at
+FROM player_effect
+WHERE player_id = $1
+AND expires_at > now()
+ORDER BY expires_at
+`
+
+func (r *pgRepository) ListPlayerEffects(ctx context.Context, playerID int32) ([]*Effect, error) {
+	ctx = querylabel.With(ctx, "effect", "ListPlayerEffects")
+
+	rows, err := r.db.Query(ctx, listPlayerEffects, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("querying for player effects: %w", err)
+	}
+	defer rows.Close()
+
+	var effects []*Effect
+
+	for rows.Next() {
+		e, err := scanEffect(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning rows into effect struct: %w", err)
+		}
+
+		effects = append(effects, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return effects, nil
+}
+
+const refreshEffect = `
+UPDATE player_effect SET expires_at = $2, updated_at = now()
+WHERE id = $1
+RETURNING id, player_id, type, stat, magnitude, stacks, max_stacks, source, expires_at, created_at, updated_at
+`
+
+type RefreshEffectParams struct {
+	ID        int32     `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (r *pgRepository) RefreshEffect(ctx context.Context, args RefreshEffectParams) (*Effect, error) {
+	ctx = querylabel.With(ctx, "effect", "RefreshEffect")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	e, err := scanEffect(tx.QueryRow(ctx, refreshEffect, args.ID, args.ExpiresAt))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into effect struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return e, nil
+}
+
+const removeEffect = `
+DELETE FROM player_effect WHERE id = $1
+`
+
+func (r *pgRepository) RemoveEffect(ctx context.Context, id int32) error {
+	ctx = querylabel.With(ctx, "effect", "RemoveEffect")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, removeEffect, id)
+	if err != nil {
+		return fmt.Errorf("removing effect: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+const deleteExpiredPlayerEffects = `
+DELETE FROM player_effect WHERE player_id = $1 AND expires_at <= now()
+`
+
+func (r *pgRepository) DeleteExpiredPlayerEffects(ctx context.Context, playerID int32) (int64, error) {
+	ctx = querylabel.With(ctx, "effect", "DeleteExpiredPlayerEffects")
+
+	tag, err := r.db.Exec(ctx, deleteExpiredPlayerEffects, playerID)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired player effects: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+const deleteExpiredEffects = `
+DELETE FROM player_effect WHERE expires_at <= now()
+`
+
+func (r *pgRepository) DeleteExpiredEffects(ctx context.Context) (int64, error) {
+	ctx = querylabel.With(ctx, "effect", "DeleteExpiredEffects")
+
+	tag, err := r.db.Exec(ctx, deleteExpiredEffects)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired effects: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
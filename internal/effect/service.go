@@ -0,0 +1,151 @@
+package effect
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/stats"
+)
+
+type EffectService struct {
+	repo EffectRepository
+	now  func() time.Time
+}
+
+func NewEffectService(repo EffectRepository) *EffectService {
+	return &EffectService{repo: repo, now: time.Now}
+}
+
+type ApplyEffectRequest struct {
+	Type      string
+	Stat      string
+	Magnitude int32
+	MaxStacks int32
+	Source    string
+	Duration  time.Duration
+}
+
+func (req ApplyEffectRequest) validate() error {
+	if req.Type == "" || req.Source == "" {
+		return apperr.Invalid("effect type and/or source cannot be empty")
+	}
+	if !stats.IsValidStat(req.Stat) {
+		return apperr.Invalid(fmt.Sprintf("unknown stat '%v'", req.Stat))
+	}
+	if req.Magnitude == 0 {
+		return apperr.Invalid("effect magnitude cannot be zero")
+	}
+	if req.Duration <= 0 {
+		return apperr.Invalid("effect duration must be positive")
+	}
+	if req.MaxStacks < 0 {
+		return apperr.Invalid("effect max stacks cannot be negative")
+	}
+	return nil
+}
+
+// ApplyEffect adds an effect to a player. Re-applying an effect of the same
+// type from the same source refreshes its duration and, if it is stackable
+// (MaxStacks > 1), adds a stack.
+func (s *EffectService) ApplyEffect(ctx context.Context, playerID int32, req ApplyEffectRequest) (*Effect, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	maxStacks := max(req.MaxStacks, 1)
+
+	e, err := s.repo.ApplyEffect(ctx, ApplyEffectParams{
+		PlayerID:  playerID,
+		Type:      req.Type,
+		Stat:      req.Stat,
+		Magnitude: req.Magnitude,
+		MaxStacks: maxStacks,
+		Source:    req.Source,
+		ExpiresAt: s.now().Add(req.Duration),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("applying effect '%v' to player with id %v: %w", req.Type, playerID, err)
+	}
+
+	return e, nil
+}
+
+// GetPlayerEffect returns an active effect of the given player. Expired
+// effects are removed on access and reported as not found.
+func (s *EffectService) GetPlayerEffect(ctx context.Context, playerID, id int32) (*Effect, error) {
+	e, err := s.repo.GetEffectByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting effect with id %v: %w", id, err)
+	}
+	if e == nil || e.PlayerID != playerID {
+		return nil, apperr.NotFound("effect", "id", id)
+	}
+	if e.Expired(s.now()) {
+		if err := s.repo.RemoveEffect(ctx, id); err != nil {
+			return nil, fmt.Errorf("removing expired effect with id %v: %w", id, err)
+		}
+		return nil, apperr.NotFound("effect", "id", id)
+	}
+
+	return e, nil
+}
+
+// ListPlayerEffects returns the active effects of a player, lazily deleting
+// the ones that have already expired.
+func (s *EffectService) ListPlayerEffects(ctx context.Context, playerID int32) ([]*Effect, error) {
+	if _, err := s.repo.DeleteExpiredPlayerEffects(ctx, playerID); err != nil {
+		return nil, fmt.Errorf("cleaning up expired effects for player with id %v: %w", playerID, err)
+	}
+
+	effects, err := s.repo.ListPlayerEffects(ctx, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("getting effects for player with id %v: %w", playerID, err)
+	}
+
+	return effects, nil
+}
+
+// RefreshEffect resets the remaining duration of an active effect without
+// changing its stacks.
+func (s *EffectService) RefreshEffect(ctx context.Context, playerID, id int32, duration time.Duration) (*Effect, error) {
+	if duration <= 0 {
+		return nil, apperr.Invalid("effect duration must be positive")
+	}
+
+	if _, err := s.GetPlayerEffect(ctx, playerID, id); err != nil {
+		return nil, err
+	}
+
+	e, err := s.repo.RefreshEffect(ctx, RefreshEffectParams{ID: id, ExpiresAt: s.now().Add(duration)})
+	if err != nil {
+		return nil, fmt.Errorf("refreshing effect with id %v: %w", id, err)
+	}
+	if e == nil {
+		return nil, apperr.NotFound("effect", "id", id)
+	}
+
+	return e, nil
+}
+
+func (s *EffectService) RemoveEffect(ctx context.Context, playerID, id int32) error {
+	if _, err := s.GetPlayerEffect(ctx, playerID, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveEffect(ctx, id); err != nil {
+		return fmt.Errorf("removing effect with id %v: %w", id, err)
+	}
+
+	return nil
+}
+
+func (s *EffectService) DeleteExpiredEffects(ctx context.Context) (int64, error) {
+	n, err := s.repo.DeleteExpiredEffects(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired effects: %w", err)
+	}
+
+	return n, nil
+}
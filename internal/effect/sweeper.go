@@ -0,0 +1,40 @@
+package effect
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Sweeper periodically deletes expired effects so that players who are never
+// looked up again do not accumulate stale rows.
+type Sweeper struct {
+	service  *EffectService
+	interval time.Duration
+}
+
+func NewSweeper(service *EffectService, interval time.Duration) *Sweeper {
+	return &Sweeper{service: service, interval: interval}
+}
+
+// Run sweeps on every tick until ctx is cancelled.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.service.DeleteExpiredEffects(ctx)
+			if err != nil {
+				log.Printf("Error sweeping expired effects: %s", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("Swept %d expired effects", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hossokawa/go-nethttp-example/internal/account"
+	"github.com/hossokawa/go-nethttp-example/internal/api"
+)
+
+type AccountHandler struct {
+	service *account.AccountService
+}
+
+func NewAccountHandler(service *account.AccountService) *AccountHandler {
+	return &AccountHandler{service: service}
+}
+
+type createAccountBody struct {
+	Name string `json:"name" validate:"required,notblank,max=32,charset=printable"`
+}
+
+type setPlayerAccountBody struct {
+	AccountID *int32 `json:"account_id" validate:"min=1"`
+}
+
+func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var body createAccountBody
+	if err := api.DecodeJSON(w, r, &body); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	if body.Name == "" {
+		api.WriteJSONError(w, http.StatusBadRequest, "Account name cannot be empty")
+		return
+	}
+
+	a, err := h.service.CreateAccount(r.Context(), body.Name)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(a)
+}
+
+func (h *AccountHandler) GetAccountByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	a, err := h.service.GetAccountByID(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(a)
+}
+
+func (h *AccountHandler) SetPlayerAccount(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	var body setPlayerAccountBody
+	if err := api.DecodeJSON(w, r, &body); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	if err := h.service.SetPlayerAccount(r.Context(), id, body.AccountID); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
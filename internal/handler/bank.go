@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/api"
+	"github.com/hossokawa/go-nethttp-example/internal/bank"
+)
+
+// BankHandler serves both a player's bank and their account's shared
+// storage; each method takes the storage kind and returns the handler for it.
+type BankHandler struct {
+	service *bank.BankService
+}
+
+func NewBankHandler(service *bank.BankService) *BankHandler {
+	return &BankHandler{service: service}
+}
+
+type depositItemBody struct {
+	ItemID uuid.UUID `json:"item_id" validate:"required"`
+}
+
+type moveGoldBody struct {
+	Amount int32 `json:"amount" validate:"min=1"`
+}
+
+func (h *BankHandler) GetStorage(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, err := api.PathID(r, "id")
+		if err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		st, err := h.service.GetStorage(r.Context(), id, kind)
+		if err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(st)
+	}
+}
+
+func (h *BankHandler) DepositItem(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, err := api.PathID(r, "id")
+		if err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		var body depositItemBody
+		if err := api.DecodeJSON(w, r, &body); err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		if err := h.service.DepositItem(r.Context(), id, kind, body.ItemID); err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (h *BankHandler) WithdrawItem(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, err := api.PathID(r, "id")
+		if err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		itemID, err := api.PathUUID(r, "itemID")
+		if err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		if err := h.service.WithdrawItem(r.Context(), id, kind, itemID); err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (h *BankHandler) DepositGold(kind string) http.HandlerFunc {
+	return h.moveGold(kind, h.service.DepositGold)
+}
+
+func (h *BankHandler) WithdrawGold(kind string) http.HandlerFunc {
+	return h.moveGold(kind, h.service.WithdrawGold)
+}
+
+func (h *BankHandler) moveGold(kind string, move func(ctx context.Context, playerID int32, kind string, amount int32) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, err := api.PathID(r, "id")
+		if err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		var body moveGoldBody
+		if err := api.DecodeJSON(w, r, &body); err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		if err := move(r.Context(), id, kind, body.Amount); err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		st, err := h.service.GetStorage(r.Context(), id, kind)
+		if err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(st)
+	}
+}
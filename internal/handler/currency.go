@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/hossokawa/go-nethttp-example/internal/api"
+	"github.com/hossokawa/go-nethttp-example/internal/currency"
+)
+
+type CurrencyHandler struct {
+	service *currency.CurrencyService
+}
+
+func NewCurrencyHandler(service *currency.CurrencyService) *CurrencyHandler {
+	return &CurrencyHandler{service: service}
+}
+
+type updateBalanceBody struct {
+	Amount int32 `json:"amount" validate:"min=1"`
+}
+
+func (h *CurrencyHandler) GetAllCurrencies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	currencies, err := h.service.GetAllCurrencies(r.Context())
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(currencies)
+}
+
+func (h *CurrencyHandler) ListPlayerBalances(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	balances, err := h.service.ListPlayerBalances(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(balances)
+}
+
+func (h *CurrencyHandler) IncreaseBalance(w http.ResponseWriter, r *http.Request) {
+	h.updateBalance(w, r, h.service.IncreaseBalance)
+}
+
+func (h *CurrencyHandler) DecreaseBalance(w http.ResponseWriter, r *http.Request) {
+	h.updateBalance(w, r, h.service.DecreaseBalance)
+}
+
+func (h *CurrencyHandler) updateBalance(w http.ResponseWriter, r *http.Request, update func(ctx context.Context, playerID int32, code string, amount int32) (int32, error)) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	var body updateBalanceBody
+	if err := api.DecodeJSON(w, r, &body); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	code := r.PathValue("currency")
+
+	balance, err := update(r.Context(), id, code, body.Amount)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(currency.Balance{PlayerID: id, Currency: code, Amount: balance})
+}
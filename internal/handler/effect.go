@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/api"
+	"github.com/hossokawa/go-nethttp-example/internal/effect"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+)
+
+type EffectHandler struct {
+	service       *effect.EffectService
+	playerService *player.PlayerService
+}
+
+func NewEffectHandler(service *effect.EffectService, playerService *player.PlayerService) *EffectHandler {
+	return &EffectHandler{service: service, playerService: playerService}
+}
+
+type applyEffectBody struct {
+	Type            string `json:"type" validate:"required,max=32,charset=code"`
+	Stat            string `json:"stat" validate:"required,oneof=strength agility intellect stamina health mana"`
+	Magnitude       int32  `json:"magnitude" validate:"required"`
+	MaxStacks       int32  `json:"max_stacks" validate:"min=0,max=100"`
+	Source          string `json:"source" validate:"required,max=64,charset=printable"`
+	DurationSeconds int64  `json:"duration_seconds" validate:"min=1"`
+}
+
+type refreshEffectBody struct {
+	DurationSeconds int64 `json:"duration_seconds" validate:"min=1"`
+}
+
+// playerID parses the {id} path value and checks that the player exists,
+// writing the error response itself when it does not.
+func (h *EffectHandler) playerID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return 0, false
+	}
+
+	_, err = h.playerService.GetPlayerByID(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return 0, false
+	}
+
+	return id, true
+}
+
+func (h *EffectHandler) ListPlayerEffects(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	playerID, ok := h.playerID(w, r)
+	if !ok {
+		return
+	}
+
+	effects, err := h.service.ListPlayerEffects(r.Context(), playerID)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(effects)
+}
+
+func (h *EffectHandler) ApplyEffect(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	playerID, ok := h.playerID(w, r)
+	if !ok {
+		return
+	}
+
+	var body applyEffectBody
+	if err := api.DecodeJSON(w, r, &body); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	e, err := h.service.ApplyEffect(r.Context(), playerID, effect.ApplyEffectRequest{
+		Type:      body.Type,
+		Stat:      body.Stat,
+		Magnitude: body.Magnitude,
+		MaxStacks: body.MaxStacks,
+		Source:    body.Source,
+		Duration:  time.Duration(body.DurationSeconds) * time.Second,
+	})
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(e)
+}
+
+func (h *EffectHandler) RefreshEffect(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	playerID, ok := h.playerID(w, r)
+	if !ok {
+		return
+	}
+
+	effectID, err := api.PathID(r, "effectID")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	var body refreshEffectBody
+	if err := api.DecodeJSON(w, r, &body); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	e, err := h.service.RefreshEffect(r.Context(), playerID, effectID, time.Duration(body.DurationSeconds)*time.Second)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(e)
+}
+
+func (h *EffectHandler) RemoveEffect(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	playerID, ok := h.playerID(w, r)
+	if !ok {
+		return
+	}
+
+	effectID, err := api.PathID(r, "effectID")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	if err := h.service.RemoveEffect(r.Context(), playerID, effectID); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hossokawa/go-nethttp-example/internal/health"
+)
+
+type HealthHandler struct {
+	service *health.Service
+}
+
+func NewHealthHandler(service *health.Service) *HealthHandler {
+	return &HealthHandler{service: service}
+}
+
+// Healthz reports that the process is up and serving. It checks nothing
+// else, so a failing dependency does not get the process restarted.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": health.StatusOK})
+}
+
+// Readyz reports whether the instance should get traffic, failing with a 503
+// that lists the failed checks.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+
+	report, ready := h.service.Ready(r.Context())
+
+	if ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.service.Version(r.Context()))
+}
@@ -1 +1,108 @@
 package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/api"
+	"github.com/hossokawa/go-nethttp-example/internal/inventory"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+)
+
+type InventoryHandler struct {
+	service       *inventory.InventoryService
+	playerService *player.PlayerService
+}
+
+func NewInventoryHandler(service *inventory.InventoryService, playerService *player.PlayerService) *InventoryHandler {
+	return &InventoryHandler{service: service, playerService: playerService}
+}
+
+func (h *InventoryHandler) ListInventory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+
+	params, err := listing.Parse(query, inventory.InventorySortFields, "name")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	var equipped *bool
+	if v := query.Get("equipped"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			api.WriteJSONError(w, http.StatusBadRequest, "equipped must be a boolean")
+			return
+		}
+		equipped = &b
+	}
+
+	_, err = h.playerService.GetPlayerByID(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	page, err := h.service.ListInventory(r.Context(), inventory.ListInventoryParams{
+		Params:     params,
+		PlayerID:   id,
+		NamePrefix: query.Get("name_prefix"),
+		Equipped:   equipped,
+	})
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// EquipItem equips an item in the player's inventory.
+func (h *InventoryHandler) EquipItem(w http.ResponseWriter, r *http.Request) {
+	h.setEquipped(w, r, h.service.EquipItem)
+}
+
+// UnequipItem unequips an item in the player's inventory.
+func (h *InventoryHandler) UnequipItem(w http.ResponseWriter, r *http.Request) {
+	h.setEquipped(w, r, h.service.UnequipItem)
+}
+
+func (h *InventoryHandler) setEquipped(w http.ResponseWriter, r *http.Request, set func(context.Context, int32, uuid.UUID) error) {
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	itemID, err := api.PathUUID(r, "itemID")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	_, err = h.playerService.GetPlayerByID(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	if err := set(r.Context(), id, itemID); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1 +1,222 @@
 package handler
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/hossokawa/go-nethttp-example/internal/api"
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+)
+
+type ItemHandler struct {
+	service *item.ItemService
+}
+
+func NewItemHandler(service *item.ItemService) *ItemHandler {
+	return &ItemHandler{service: service}
+}
+
+type setItemPriceBody struct {
+	Amount int32 `json:"amount" validate:"min=1"`
+}
+
+func (h *ItemHandler) ListItems(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+
+	params, err := listing.Parse(query, item.ItemSortFields, "name")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	minValue, err := listing.ParseInt32(query, "min_value")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	maxValue, err := listing.ParseInt32(query, "max_value")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	page, err := h.service.ListItems(r.Context(), item.ListItemsParams{
+		Params:     params,
+		NamePrefix: query.Get("name_prefix"),
+		MinValue:   minValue,
+		MaxValue:   maxValue,
+	})
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+func (h *ItemHandler) GetItemByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathUUID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	i, err := h.service.GetItemByID(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	etag := api.ETag(i.Version)
+	w.Header().Set("ETag", etag)
+	if api.NotModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(i)
+}
+
+// PatchItem applies a JSON Merge Patch to the name and value of an item.
+func (h *ItemHandler) PatchItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathUUID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	version, err := api.IfMatch(r)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+		api.WriteJSONError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/merge-patch+json")
+		return
+	}
+
+	body, err := api.ReadBody(w, r)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	args, err := item.ParsePatch(item.UpdateItemParams{ID: id, Version: version}, body)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	i, err := h.service.UpdateItem(r.Context(), args)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", api.ETag(i.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(i)
+}
+
+func (h *ItemHandler) DeleteItemByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathUUID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	version, err := api.IfMatch(r)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	if err := h.service.DeleteItemByID(r.Context(), id, version); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ItemHandler) RestoreItem(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathUUID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	i, err := h.service.RestoreItem(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", api.ETag(i.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(i)
+}
+
+func (h *ItemHandler) SetItemPrice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathUUID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	var body setItemPriceBody
+	if err := api.DecodeJSON(w, r, &body); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	if err := h.service.SetItemPrice(r.Context(), id, r.PathValue("currency"), body.Amount); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	i, err := h.service.GetItemByID(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(i)
+}
+
+func (h *ItemHandler) RemoveItemPrice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathUUID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	if err := h.service.RemoveItemPrice(r.Context(), id, r.PathValue("currency")); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,40 +3,86 @@ package handler
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"mime"
 	"net/http"
-	"strconv"
+	"net/url"
 
 	"github.com/hossokawa/go-nethttp-example/internal/api"
+	"github.com/hossokawa/go-nethttp-example/internal/effect"
+	"github.com/hossokawa/go-nethttp-example/internal/inventory"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
 	"github.com/hossokawa/go-nethttp-example/internal/player"
+	"github.com/hossokawa/go-nethttp-example/internal/stats"
 )
 
 type PlayerHandler struct {
-	service *player.PlayerService
+	service          *player.PlayerService
+	inventoryService *inventory.InventoryService
+	effectService    *effect.EffectService
 }
 
-func NewPlayerHandler(service *player.PlayerService) *PlayerHandler {
-	return &PlayerHandler{service: service}
+func NewPlayerHandler(service *player.PlayerService, inventoryService *inventory.InventoryService, effectService *effect.EffectService) *PlayerHandler {
+	return &PlayerHandler{service: service, inventoryService: inventoryService, effectService: effectService}
+}
+
+type renamePlayerBody struct {
+	Username string `json:"username" validate:"required"`
+}
+
+type playerDetails struct {
+	*player.Player
+	Stats   stats.Stats      `json:"stats"`
+	Effects []*effect.Effect `json:"effects"`
+}
+
+// details computes the player's stats from their class, level, equipped
+// items and active effects.
+func (h *PlayerHandler) details(ctx context.Context, p *player.Player) (*playerDetails, error) {
+	equipment, err := h.inventoryService.ListEquippedItems(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// effectService is nil when the storage backend keeps no effects.
+	var effects []*effect.Effect
+	if h.effectService != nil {
+		effects, err = h.effectService.ListPlayerEffects(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var mods []stats.Modifier
+	for _, i := range equipment {
+		mods = append(mods, i.Modifiers()...)
+	}
+	for _, e := range effects {
+		mods = append(mods, e.Modifier())
+	}
+
+	if effects == nil {
+		effects = []*effect.Effect{}
+	}
+
+	return &playerDetails{
+		Player:  p,
+		Stats:   stats.Compute(p.Class, p.Level, mods),
+		Effects: effects,
+	}, nil
 }
 
 func (h *PlayerHandler) CreatePlayer(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var params player.CreatePlayerParams
-	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
-		api.WriteJSONError(w, http.StatusBadRequest, "Error decoding request body into CreatePlayerParams struct")
+	if err := api.DecodeJSON(w, r, &params); err != nil {
+		api.WriteError(w, err)
 		return
 	}
-	defer r.Body.Close()
 
-	if params.Username == "" || params.Class == "" {
-		api.WriteJSONError(w, http.StatusBadRequest, "Username and/or class cannot be empty")
-		return
-	}
-
-	p, err := h.service.CreatePlayer(context.Background(), params.Username, params.Class)
+	p, err := h.service.CreatePlayer(r.Context(), params.Username, params.Class)
 	if err != nil {
-		api.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+		api.WriteError(w, err)
 		return
 	}
 
@@ -44,84 +90,256 @@ func (h *PlayerHandler) CreatePlayer(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(p)
 }
 
-func (h *PlayerHandler) GetAllPlayers(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/`json")
+func (h *PlayerHandler) ListPlayers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	username := r.URL.Query().Get("username")
+	query := r.URL.Query()
+	username := query.Get("username")
 
 	if username != "" {
-		p, err := h.service.GetPlayerByUsername(context.Background(), username)
+		p, err := h.service.GetPlayerByUsername(r.Context(), username)
 		if err != nil {
-			var notFoundErr *player.NotFoundErr
-			if errors.As(err, &notFoundErr) {
-				api.WriteJSONError(w, http.StatusNotFound, notFoundErr.Error())
-				return
-			}
-			api.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+			api.WriteError(w, err)
 			return
 		}
+		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(p)
-	} else {
-		players, err := h.service.GetAllPlayers(context.Background())
-		if err != nil {
-			api.WriteJSONError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-		json.NewEncoder(w).Encode(players)
+		return
+	}
+
+	params, err := parseListPlayersParams(query)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	page, err := h.service.ListPlayers(r.Context(), params)
+	if err != nil {
+		api.WriteError(w, err)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+func parseListPlayersParams(query url.Values) (player.ListPlayersParams, error) {
+	params, err := listing.Parse(query, player.PlayerSortFields, "id")
+	if err != nil {
+		return player.ListPlayersParams{}, err
+	}
+
+	minLevel, err := listing.ParseInt32(query, "min_level")
+	if err != nil {
+		return player.ListPlayersParams{}, err
+	}
+
+	maxLevel, err := listing.ParseInt32(query, "max_level")
+	if err != nil {
+		return player.ListPlayersParams{}, err
+	}
+
+	return player.ListPlayersParams{
+		Params:     params,
+		Class:      query.Get("class"),
+		MinLevel:   minLevel,
+		MaxLevel:   maxLevel,
+		NamePrefix: query.Get("name_prefix"),
+	}, nil
 }
 
+// GetPlayerByID returns a player with their computed stats and active
+// effects. The ETag is the player's version, for If-Match on later writes;
+// it does not cover the stats and effects, which change without bumping the
+// version when effects expire or gear changes, so If-None-Match is ignored
+// rather than answered with a stale 304.
 func (h *PlayerHandler) GetPlayerByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	idStr := r.PathValue("id")
-	id, err := strconv.Atoi(idStr)
+	id, err := api.PathID(r, "id")
 	if err != nil {
-		api.WriteJSONError(w, http.StatusInternalServerError, api.NewIDParsingError(idStr).Error())
+		api.WriteError(w, err)
 		return
 	}
 
-	p, err := h.service.GetPlayerByID(context.Background(), int32(id))
+	p, err := h.service.GetPlayerByID(r.Context(), id)
 	if err != nil {
-		var notFoundErr *player.NotFoundErr
-		if errors.As(err, &notFoundErr) {
-			api.WriteJSONError(w, http.StatusNotFound, notFoundErr.Error())
-			return
-		}
-		api.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+		api.WriteError(w, err)
 		return
 	}
 
+	details, err := h.details(r.Context(), p)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", api.ETag(p.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(details)
+}
+
+// PatchPlayer applies a JSON Merge Patch to the mutable fields of a player.
+func (h *PlayerHandler) PatchPlayer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	version, err := api.IfMatch(r)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+		api.WriteJSONError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/merge-patch+json")
+		return
+	}
+
+	body, err := api.ReadBody(w, r)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	args, err := player.ParsePatch(id, body)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	args.Version = version
+
+	p, err := h.service.UpdatePlayer(r.Context(), args)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", api.ETag(p.Version))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(p)
 }
 
+func (h *PlayerHandler) RenamePlayer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	var body renamePlayerBody
+	if err := api.DecodeJSON(w, r, &body); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	p, err := h.service.RenamePlayer(r.Context(), id, body.Username)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", api.ETag(p.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *PlayerHandler) PurgePlayer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	version, err := api.IfMatch(r)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	report, err := h.service.PurgePlayer(r.Context(), id, version)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *PlayerHandler) RestorePlayer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	p, err := h.service.RestorePlayer(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", api.ETag(p.Version))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *PlayerHandler) ListNameHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	history, err := h.service.ListNameHistory(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
 func (h *PlayerHandler) DeletePlayerByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	idStr := r.PathValue("id")
-	id, err := strconv.Atoi(idStr)
+	id, err := api.PathID(r, "id")
 	if err != nil {
-		api.WriteJSONError(w, http.StatusInternalServerError, api.NewIDParsingError(idStr).Error())
+		api.WriteError(w, err)
 		return
 	}
 
-	_, err = h.service.GetPlayerByID(context.Background(), int32(id))
+	version, err := api.IfMatch(r)
 	if err != nil {
-		var notFoundErr *player.NotFoundErr
-		if errors.As(err, &notFoundErr) {
-			api.WriteJSONError(w, http.StatusNotFound, notFoundErr.Error())
-			return
-		}
-		api.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+		api.WriteError(w, err)
+		return
+	}
+
+	_, err = h.service.GetPlayerByID(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
 		return
 	}
 
-	err = h.service.DeletePlayerByID(context.Background(), int32(id))
+	err = h.service.DeletePlayerByID(r.Context(), id, version)
 	if err != nil {
-		api.WriteJSONError(w, http.StatusInternalServerError, err.Error())
+		api.WriteError(w, err)
 		return
 	}
 
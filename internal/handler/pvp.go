@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hossokawa/go-nethttp-example/internal/api"
+	"github.com/hossokawa/go-nethttp-example/internal/pvp"
+)
+
+type PvPHandler struct {
+	service *pvp.PvPService
+}
+
+func NewPvPHandler(service *pvp.PvPService) *PvPHandler {
+	return &PvPHandler{service: service}
+}
+
+type joinQueueBody struct {
+	PlayerID int32 `json:"player_id" validate:"required"`
+}
+
+type matchResultBody struct {
+	WinnerID *int32 `json:"winner_id"`
+	Draw     bool   `json:"draw"`
+}
+
+func (h *PvPHandler) JoinQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var body joinQueueBody
+	if err := api.DecodeJSON(w, r, &body); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	e, err := h.service.JoinQueue(r.Context(), body.PlayerID)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(e)
+}
+
+func (h *PvPHandler) GetQueueEntry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "playerID")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	e, err := h.service.GetQueueEntry(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(e)
+}
+
+func (h *PvPHandler) LeaveQueue(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "playerID")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	if err := h.service.LeaveQueue(r.Context(), id); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PvPHandler) GetRating(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	rating, err := h.service.GetRating(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rating)
+}
+
+func (h *PvPHandler) ListPlayerMatches(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	matches, err := h.service.ListPlayerMatches(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(matches)
+}
+
+func (h *PvPHandler) GetMatchByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	m, err := h.service.GetMatchByID(r.Context(), id)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(m)
+}
+
+func (h *PvPHandler) ReportResult(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := api.PathID(r, "id")
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	var body matchResultBody
+	if err := api.DecodeJSON(w, r, &body); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	if body.Draw == (body.WinnerID != nil) {
+		api.WriteJSONError(w, http.StatusBadRequest, "Exactly one of winner_id and draw must be set")
+		return
+	}
+
+	m, err := h.service.ReportResult(r.Context(), id, body.WinnerID)
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(m)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hossokawa/go-nethttp-example/internal/api"
+	"github.com/hossokawa/go-nethttp-example/internal/shop"
+)
+
+type ShopHandler struct {
+	service *shop.ShopService
+}
+
+func NewShopHandler(service *shop.ShopService) *ShopHandler {
+	return &ShopHandler{service: service}
+}
+
+type purchaseBody struct {
+	PlayerID      int32  `json:"player_id" validate:"required"`
+	BundleID      string `json:"bundle_id" validate:"required,max=64"`
+	TransactionID string `json:"transaction_id" validate:"required,max=255"`
+	Receipt       string `json:"receipt" validate:"required"`
+}
+
+func (h *ShopHandler) GetAllBundles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	bundles, err := h.service.GetAllBundles(r.Context())
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bundles)
+}
+
+// Purchase responds with 201 when the receipt was granted by this request
+// and 200 when an already processed receipt is replayed.
+func (h *ShopHandler) Purchase(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var body purchaseBody
+	if err := api.DecodeJSON(w, r, &body); err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	if body.TransactionID == "" || body.BundleID == "" {
+		api.WriteJSONError(w, http.StatusBadRequest, "Transaction id and/or bundle id cannot be empty")
+		return
+	}
+
+	rc, granted, err := h.service.Purchase(r.Context(), shop.PurchaseRequest{
+		PlayerID:      body.PlayerID,
+		BundleID:      body.BundleID,
+		TransactionID: body.TransactionID,
+		Receipt:       body.Receipt,
+	})
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	if granted {
+		w.WriteHeader(http.StatusCreated)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(rc)
+}
+
+func (h *ShopHandler) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rc, err := h.service.GetReceipt(r.Context(), r.PathValue("transactionID"))
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rc)
+}
+
+func (h *ShopHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	rc, err := h.service.Refund(r.Context(), r.PathValue("transactionID"))
+	if err != nil {
+		api.WriteError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rc)
+}
@@ -0,0 +1,131 @@
+// Package health reports whether the application is alive and ready to serve,
+// and which build and schema it runs, for the orchestrator running it.
+package health
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// Check is one thing the application needs in order to serve requests, such
+// as a reachable database. Run returns nil if it is usable.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Service runs the readiness checks. It reports not ready once Shutdown is
+// called, so the orchestrator stops routing requests to an instance that is
+// about to go away.
+type Service struct {
+	checks        []Check
+	schemaVersion func(ctx context.Context) (int, error)
+	shuttingDown  atomic.Bool
+}
+
+// NewService returns a Service running checks. schemaVersion reads the
+// version of the database schema, and is nil for storage without one.
+func NewService(schemaVersion func(ctx context.Context) (int, error), checks ...Check) *Service {
+	return &Service{checks: checks, schemaVersion: schemaVersion}
+}
+
+// Shutdown makes the service report not ready from now on.
+func (s *Service) Shutdown() {
+	s.shuttingDown.Store(true)
+}
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Report is the outcome of the readiness checks. Checks maps the name of each
+// check to StatusOK or StatusUnavailable. Why a check failed is logged rather
+// than reported, since errors such as the database's can reveal internals.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Ready runs every check and reports whether all of them passed.
+func (s *Service) Ready(ctx context.Context) (*Report, bool) {
+	report := &Report{Status: StatusOK, Checks: make(map[string]string)}
+
+	if s.shuttingDown.Load() {
+		report.Status = StatusUnavailable
+		report.Checks["shutdown"] = "shutting down"
+		return report, false
+	}
+
+	for _, c := range s.checks {
+		if err := c.Run(ctx); err != nil {
+			log.Printf("Readiness check '%s' failed: %s", c.Name, err)
+			report.Status = StatusUnavailable
+			report.Checks[c.Name] = StatusUnavailable
+			continue
+		}
+		report.Checks[c.Name] = StatusOK
+	}
+
+	return report, report.Status == StatusOK
+}
+
+// Commit is the revision the binary was built from. It can be set with
+// -ldflags "-X github.com/hossokawa/go-nethttp-example/internal/health.Commit=<rev>",
+// and is otherwise read from the version control information Go embeds.
+var Commit string
+
+type Version struct {
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+	// SchemaVersion is nil if the storage has no versioned schema, or if it
+	// could not be read.
+	SchemaVersion *int `json:"schema_version"`
+}
+
+func (s *Service) Version(ctx context.Context) *Version {
+	v := &Version{Commit: commit(), GoVersion: runtime.Version()}
+
+	if s.schemaVersion != nil {
+		schema, err := s.schemaVersion(ctx)
+		if err != nil {
+			log.Printf("Error reading schema version: %s", err)
+		} else {
+			v.SchemaVersion = &schema
+		}
+	}
+
+	return v
+}
+
+func commit() string {
+	if Commit != "" {
+		return Commit
+	}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision == "" {
+		return "unknown"
+	}
+	if modified == "true" {
+		revision += "-dirty"
+	}
+
+	return revision
+}
@@ -0,0 +1,26 @@
+package idempotency
+
+import (
+	"net/http"
+	"time"
+)
+
+// Record is the stored outcome of a request made with an Idempotency-Key. It
+// is claimed before the request is handled and completed with the response
+// once the handler returns. Keys are chosen by clients, so they are only
+// unique within a scope identifying the client.
+type Record struct {
+	Scope       string      `json:"scope"`
+	Key         string      `json:"key"`
+	Fingerprint string      `json:"fingerprint"`
+	StatusCode  *int32      `json:"status_code"`
+	Headers     http.Header `json:"headers"`
+	Body        []byte      `json:"body"`
+	CreatedAt   time.Time   `json:"created_at"`
+	CompletedAt *time.Time  `json:"completed_at"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+}
+
+func (r *Record) Completed() bool {
+	return r.CompletedAt != nil
+}
@@ -0,0 +1,111 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+)
+
+type memRepository struct {
+	db *memdb.DB
+}
+
+// NewMemoryRepository returns an IdempotencyRepository that keeps keys in db.
+// It behaves like the Postgres repository, down to the errors it returns.
+func NewMemoryRepository(db *memdb.DB) IdempotencyRepository {
+	return &memRepository{db: db}
+}
+
+func (r *memRepository) Claim(ctx context.Context, args ClaimParams) (*Record, bool, error) {
+	var rc *Record
+	var claimed bool
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		now := time.Now()
+
+		id := memdb.IdempotencyKeyID{Scope: args.Scope, Key: args.Key}
+		row, ok := t.IdempotencyKeys[id]
+		if !ok || row.ExpiresAt.Before(now) {
+			row = memdb.IdempotencyKey{
+				Scope:       args.Scope,
+				Key:         args.Key,
+				Fingerprint: args.Fingerprint,
+				CreatedAt:   now,
+				ExpiresAt:   args.ExpiresAt,
+			}
+			t.IdempotencyKeys[id] = row
+			claimed = true
+		}
+
+		rc = &Record{
+			Scope:       row.Scope,
+			Key:         row.Key,
+			Fingerprint: row.Fingerprint,
+			StatusCode:  row.StatusCode,
+			Headers:     row.Headers.Clone(),
+			Body:        bytes.Clone(row.Body),
+			CreatedAt:   row.CreatedAt,
+			CompletedAt: row.CompletedAt,
+			ExpiresAt:   row.ExpiresAt,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return rc, claimed, nil
+}
+
+func (r *memRepository) Complete(ctx context.Context, args CompleteParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		id := memdb.IdempotencyKeyID{Scope: args.Scope, Key: args.Key}
+		row, ok := t.IdempotencyKeys[id]
+		if !ok || row.CompletedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		status := args.StatusCode
+		row.StatusCode = &status
+		row.Headers = args.Headers.Clone()
+		row.Body = bytes.Clone(args.Body)
+		row.CompletedAt = &now
+		row.ExpiresAt = args.ExpiresAt
+		t.IdempotencyKeys[id] = row
+
+		return nil
+	})
+}
+
+func (r *memRepository) Release(ctx context.Context, args ReleaseParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		id := memdb.IdempotencyKeyID{Scope: args.Scope, Key: args.Key}
+		if row, ok := t.IdempotencyKeys[id]; ok && row.CompletedAt == nil {
+			delete(t.IdempotencyKeys, id)
+		}
+		return nil
+	})
+}
+
+func (r *memRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	var n int64
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		now := time.Now()
+		for key, row := range t.IdempotencyKeys {
+			if row.ExpiresAt.Before(now) {
+				delete(t.IdempotencyKeys, key)
+				n++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,212 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/api"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderClient   = "Idempotency-Client"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	MaxKeyLength = 255
+
+	// A client id must be long enough to be a random value no other client
+	// will guess.
+	MinClientLength = 16
+	MaxClientLength = 255
+)
+
+// Middleware makes mutating requests that carry an Idempotency-Key safe to
+// retry. The first request with a key is handled normally and its response is
+// stored; later requests from the same client with the same key and the same
+// method, path and body get the stored response back without reaching the
+// handler.
+//
+// Keys are only accepted from a client that identifies itself, with its
+// credentials or with an id of its own in the Idempotency-Client header, so
+// that one client is never replayed another's response. A client id is not a
+// credential: it keeps clients that choose random ids apart, but anyone who
+// learns a client's id and key can read the response stored for them.
+type Middleware struct {
+	repo  IdempotencyRepository
+	ttl   time.Duration
+	lease time.Duration
+}
+
+// NewMiddleware returns a Middleware that replays responses for ttl. A
+// request holds its key for lease while it is handled; lease must be longer
+// than any request may take, and is how long retries have to wait for the
+// key if the server stops before finishing the request.
+func NewMiddleware(repo IdempotencyRepository, ttl, lease time.Duration) *Middleware {
+	return &Middleware{repo: repo, ttl: ttl, lease: lease}
+}
+
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderKey)
+		if key == "" || !mutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > MaxKeyLength {
+			api.WriteJSONError(w, http.StatusBadRequest, "Idempotency-Key must be at most "+strconv.Itoa(MaxKeyLength)+" characters")
+			return
+		}
+
+		scope, ok := ClientScope(r)
+		if !ok {
+			api.WriteJSONError(w, http.StatusBadRequest, "Idempotency-Key requires an Authorization header or a random "+HeaderClient+" of "+strconv.Itoa(MinClientLength)+" to "+strconv.Itoa(MaxClientLength)+" characters")
+			return
+		}
+
+		body, err := api.ReadBody(w, r)
+		if err != nil {
+			api.WriteError(w, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		rc, created, err := m.repo.Claim(r.Context(), ClaimParams{
+			Scope:       scope,
+			Key:         key,
+			Fingerprint: fingerprint(r, body),
+			ExpiresAt:   time.Now().Add(m.lease),
+		})
+		if err != nil {
+			api.WriteError(w, err)
+			return
+		}
+
+		if !created {
+			m.replay(w, r, rc, body)
+			return
+		}
+
+		// The outcome is stored even if the client goes away mid-request,
+		// since the mutation itself has already happened by then.
+		ctx := context.WithoutCancel(r.Context())
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			if p := recover(); p != nil {
+				m.release(ctx, scope, key)
+				panic(p)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+
+		// Server errors are not stored so that the client can retry them.
+		if rec.status >= http.StatusInternalServerError {
+			m.release(ctx, scope, key)
+			return
+		}
+
+		err = m.repo.Complete(ctx, CompleteParams{
+			Scope:      scope,
+			Key:        key,
+			StatusCode: int32(rec.status),
+			Headers:    w.Header().Clone(),
+			Body:       rec.body.Bytes(),
+			ExpiresAt:  time.Now().Add(m.ttl),
+		})
+		if err != nil {
+			log.Printf("Error storing response for idempotency key '%s': %s", key, err)
+		}
+	})
+}
+
+func (m *Middleware) replay(w http.ResponseWriter, r *http.Request, rc *Record, body []byte) {
+	if rc.Fingerprint != fingerprint(r, body) {
+		api.WriteJSONError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+		return
+	}
+
+	if !rc.Completed() {
+		api.WriteJSONError(w, http.StatusConflict, "A request with this Idempotency-Key is still being processed")
+		return
+	}
+
+	for name, values := range rc.Headers {
+		w.Header()[name] = values
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(int(*rc.StatusCode))
+	w.Write(rc.Body)
+}
+
+func (m *Middleware) release(ctx context.Context, scope, key string) {
+	if err := m.repo.Release(ctx, ReleaseParams{Scope: scope, Key: key}); err != nil {
+		log.Printf("Error releasing idempotency key '%s': %s", key, err)
+	}
+}
+
+// ClientScope identifies the client making r, so that keys chosen by different
+// clients never collide: by its credentials if it sent any, else by the id it
+// sent in the Idempotency-Client header. It returns false if r carries
+// neither, or a client id of the wrong length. The address of the client is
+// never used, since clients behind the same proxy share it.
+func ClientScope(r *http.Request) (string, bool) {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		return "auth:" + hex.EncodeToString(sum[:]), true
+	}
+
+	id := r.Header.Get(HeaderClient)
+	if len(id) < MinClientLength || len(id) > MaxClientLength {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(id))
+	return "client:" + hex.EncodeToString(sum[:]), true
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// fingerprint identifies a request by everything that determines its effect,
+// so that a key cannot be reused for a different operation.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder passes the response through while keeping a copy of it.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,138 @@
+package idempotency_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/idempotency"
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+)
+
+func newServer(t *testing.T, repo idempotency.IdempotencyRepository, lease time.Duration) (http.Handler, *int) {
+	t.Helper()
+
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	return idempotency.NewMiddleware(repo, time.Hour, lease).Wrap(next), &calls
+}
+
+// Client ids of anonymous clients.
+const (
+	alice = "alice-7f3c9a1e5b2d"
+	bob   = "bob-4e8d2c6a9f1b03"
+)
+
+// post sends a request with key from the anonymous client with the given id.
+func post(h http.Handler, key, client string) *httptest.ResponseRecorder {
+	return postWith(h, key, http.Header{idempotency.HeaderClient: {client}})
+}
+
+func postWith(h http.Handler, key string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/player", strings.NewReader(`{}`))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set(idempotency.HeaderKey, key)
+	// Every request comes from the same address, as behind a proxy.
+	req.RemoteAddr = "10.0.0.1:1234"
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestKeysAreScopedPerClient(t *testing.T) {
+	h, calls := newServer(t, idempotency.NewMemoryRepository(memdb.New()), time.Minute)
+
+	post(h, "key", alice)
+	if rec := post(h, "key", alice); rec.Header().Get(idempotency.HeaderReplayed) != "true" {
+		t.Fatalf("retry from the same client was not replayed")
+	}
+	if rec := post(h, "key", bob); rec.Header().Get(idempotency.HeaderReplayed) == "true" {
+		t.Fatalf("another client's request was answered with the first client's response")
+	}
+
+	// Credentials take precedence over a client id, so an authenticated
+	// client cannot reach the responses stored for an anonymous one.
+	auth := http.Header{"Authorization": {"Bearer token"}, idempotency.HeaderClient: {alice}}
+	if rec := postWith(h, "key", auth); rec.Header().Get(idempotency.HeaderReplayed) == "true" {
+		t.Fatalf("an authenticated request was answered with an anonymous client's response")
+	}
+	if rec := postWith(h, "key", http.Header{"Authorization": {"Bearer token"}}); rec.Header().Get(idempotency.HeaderReplayed) != "true" {
+		t.Fatalf("retry from the same credentials was not replayed")
+	}
+
+	if *calls != 3 {
+		t.Fatalf("handler called %d times, want 3", *calls)
+	}
+}
+
+// TestKeysRequireAClient checks that a key is refused from a client that
+// does not identify itself, rather than scoped by its address.
+func TestKeysRequireAClient(t *testing.T) {
+	h, calls := newServer(t, idempotency.NewMemoryRepository(memdb.New()), time.Minute)
+
+	for name, header := range map[string]http.Header{
+		"anonymous":        nil,
+		"short client id":  {idempotency.HeaderClient: {"abc"}},
+		"long client id":   {idempotency.HeaderClient: {strings.Repeat("a", idempotency.MaxClientLength+1)}},
+		"empty credential": {"Authorization": {""}},
+	} {
+		if rec := postWith(h, "key", header); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want %d", name, rec.Code, http.StatusBadRequest)
+		}
+	}
+	if *calls != 0 {
+		t.Fatalf("handler called %d times, want 0", *calls)
+	}
+
+	// Requests without a key are not affected.
+	req := httptest.NewRequest(http.MethodPost, "/player", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("request without a key got %d, want %d", rec.Code, http.StatusCreated)
+	}
+}
+
+// TestAbandonedClaimIsReclaimed checks that a claim never completed, as when
+// the server stops mid-request, blocks retries only until its lease ends.
+func TestAbandonedClaimIsReclaimed(t *testing.T) {
+	repo := idempotency.NewMemoryRepository(memdb.New())
+	lease := 50 * time.Millisecond
+
+	// The first attempt stops its goroutine inside the handler, leaving the
+	// key claimed but neither completed nor released.
+	abandon := idempotency.NewMiddleware(repo, time.Hour, lease).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runtime.Goexit()
+	}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		post(abandon, "key", alice)
+	}()
+	<-done
+
+	h, calls := newServer(t, repo, lease)
+
+	if rec := post(h, "key", alice); rec.Code != http.StatusConflict {
+		t.Fatalf("retry during the lease got %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	time.Sleep(2 * lease)
+
+	if rec := post(h, "key", alice); rec.Code != http.StatusCreated {
+		t.Fatalf("retry after the lease got %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if *calls != 1 {
+		t.Fatalf("handler called %d times, want 1", *calls)
+	}
+}
@@ -0,0 +1,181 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+)
+
+type IdempotencyRepository interface {
+	Claim(ctx context.Context, args ClaimParams) (*Record, bool, error)
+	Complete(ctx context.Context, args CompleteParams) error
+	Release(ctx context.Context, args ReleaseParams) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type pgRepository struct {
+	db *database.DB
+}
+
+func NewPostgresRepository(db *database.DB) IdempotencyRepository {
+	return &pgRepository{db: db}
+}
+
+const deleteExpiredKey = `
+DELETE FROM idempotency_key WHERE scope = $1 AND key = $2 AND expires_at < now()
+`
+
+const claimKey = `
+INSERT INTO idempotency_key (scope, key, fingerprint, created_at, expires_at)
+VALUES ($1, $2, $3, now(), $4)
+ON CONFLICT (scope, key) DO NOTHING
+`
+
+const getKey = `
+SELECT scope, key, fingerprint, status_code, headers, body, created_at, completed_at, expires_at
+FROM idempotency_key
+WHERE scope = $1 AND key = $2
+`
+
+type ClaimParams struct {
+	Scope       string    `json:"scope"`
+	Key         string    `json:"key"`
+	Fingerprint string    `json:"fingerprint"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Claim records that a request with the given key is being handled. The
+// returned bool is false when the key was already claimed, in which case the
+// existing record is returned unchanged. An expired record is replaced, so a
+// claim that is never completed, such as because the server crashed while
+// handling the request, can be claimed again once ExpiresAt has passed.
+func (r *pgRepository) Claim(ctx context.Context, args ClaimParams) (*Record, bool, error) {
+	ctx = querylabel.With(ctx, "idempotency", "Claim")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, deleteExpiredKey, args.Scope, args.Key); err != nil {
+		return nil, false, fmt.Errorf("deleting expired idempotency key: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, claimKey, args.Scope, args.Key, args.Fingerprint, args.ExpiresAt)
+	if err != nil {
+		return nil, false, fmt.Errorf("claiming idempotency key: %w", err)
+	}
+
+	var rc Record
+
+	row := tx.QueryRow(ctx, getKey, args.Scope, args.Key)
+	err = row.Scan(
+		&rc.Scope,
+		&rc.Key,
+		&rc.Fingerprint,
+		&rc.StatusCode,
+		&rc.Headers,
+		&rc.Body,
+		&rc.CreatedAt,
+		&rc.CompletedAt,
+		&rc.ExpiresAt,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("scanning row into idempotency record struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return &rc, tag.RowsAffected() == 1, nil
+}
+
+const completeKey = `
+UPDATE idempotency_key
+SET status_code = $3, headers = $4, body = $5, completed_at = now(), expires_at = $6
+WHERE scope = $1 AND key = $2 AND completed_at IS NULL
+`
+
+// CompleteParams store the response to a claimed request, to be replayed
+// until ExpiresAt.
+type CompleteParams struct {
+	Scope      string      `json:"scope"`
+	Key        string      `json:"key"`
+	StatusCode int32       `json:"status_code"`
+	Headers    http.Header `json:"headers"`
+	Body       []byte      `json:"body"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+}
+
+func (r *pgRepository) Complete(ctx context.Context, args CompleteParams) error {
+	ctx = querylabel.With(ctx, "idempotency", "Complete")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, completeKey, args.Scope, args.Key, args.StatusCode, args.Headers, args.Body, args.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("storing idempotent response: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+const releaseKey = `
+DELETE FROM idempotency_key WHERE scope = $1 AND key = $2 AND completed_at IS NULL
+`
+
+type ReleaseParams struct {
+	Scope string `json:"scope"`
+	Key   string `json:"key"`
+}
+
+// Release drops a claim that was never completed so the request can be
+// retried with the same key.
+func (r *pgRepository) Release(ctx context.Context, args ReleaseParams) error {
+	ctx = querylabel.With(ctx, "idempotency", "Release")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, releaseKey, args.Scope, args.Key); err != nil {
+		return fmt.Errorf("releasing idempotency key: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+const deleteExpiredKeys = `
+DELETE FROM idempotency_key WHERE expires_at < now()
+`
+
+func (r *pgRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	ctx = querylabel.With(ctx, "idempotency", "DeleteExpired")
+
+	tag, err := r.db.Exec(ctx, deleteExpiredKeys)
+	if err != nil {
+		return 0, fmt.Errorf("deleting expired idempotency keys: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,40 @@
+package idempotency
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Sweeper periodically deletes expired idempotency keys. Claim replaces an
+// expired key on its own, so this only keeps the table from growing.
+type Sweeper struct {
+	repo     IdempotencyRepository
+	interval time.Duration
+}
+
+func NewSweeper(repo IdempotencyRepository, interval time.Duration) *Sweeper {
+	return &Sweeper{repo: repo, interval: interval}
+}
+
+// Run sweeps on every tick until ctx is cancelled.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.repo.DeleteExpired(ctx)
+			if err != nil {
+				log.Printf("Error sweeping expired idempotency keys: %s", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("Swept %d expired idempotency keys", n)
+			}
+		}
+	}
+}
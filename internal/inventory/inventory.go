@@ -5,4 +5,5 @@ import "github.com/google/uuid"
 type Inventory struct {
 	PlayerID int32     `json:"player_id"`
 	ItemID   uuid.UUID `json:"item_id"`
+	Equipped bool      `json:"equipped"`
 }
@@ -0,0 +1,133 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+)
+
+type memRepository struct {
+	db *memdb.DB
+}
+
+// NewMemoryRepository returns an InventoryRepository that keeps inventories in
+// db. It behaves like the Postgres repository, down to the errors it returns.
+func NewMemoryRepository(db *memdb.DB) InventoryRepository {
+	return &memRepository{db: db}
+}
+
+func (r *memRepository) AddItem(ctx context.Context, args AddItemParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		_, playerOK := t.Players[args.PlayerID]
+		_, itemOK := t.Items[args.ItemID]
+		if !playerOK || !itemOK {
+			return fmt.Errorf("adding item to player's inventory: %w", memdb.ErrForeignKey)
+		}
+
+		key := memdb.InventoryKey{PlayerID: args.PlayerID, ItemID: args.ItemID}
+		if _, ok := t.Inventory[key]; ok {
+			return ErrItemAlreadyOwned
+		}
+		t.Inventory[key] = memdb.InventoryRow{}
+
+		return nil
+	})
+}
+
+// listItems returns the live items in a player's inventory that match fn,
+// ordered by id.
+func (r *memRepository) listItems(ctx context.Context, playerID int32, fn func(row memdb.Item, equipped bool) bool) ([]item.Item, error) {
+	var items []item.Item
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for key, inv := range t.Inventory {
+			if key.PlayerID != playerID {
+				continue
+			}
+			row, ok := t.Items[key.ItemID]
+			if !ok || row.DeletedAt != nil || !fn(row, inv.Equipped) {
+				continue
+			}
+			items = append(items, item.Item{
+				ID:    row.ID,
+				Name:  row.Name,
+				Value: row.Value,
+				Stats: maps.Clone(row.Stats),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(items, func(a, b item.Item) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+
+	return items, nil
+}
+
+func (r *memRepository) ListPlayerItems(ctx context.Context, playerID int32) ([]item.Item, error) {
+	return r.listItems(ctx, playerID, func(memdb.Item, bool) bool { return true })
+}
+
+func (r *memRepository) ListInventory(ctx context.Context, args ListInventoryParams) (*listing.Page[*item.Item], error) {
+	sort, ok := inventorySortColumns[args.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field '%v'", args.Sort)
+	}
+
+	items, err := r.listItems(ctx, args.PlayerID, func(row memdb.Item, equipped bool) bool {
+		switch {
+		case args.NamePrefix != "" && !strings.HasPrefix(row.Name, args.NamePrefix):
+			return false
+		case args.Equipped != nil && equipped != *args.Equipped:
+			return false
+		default:
+			return true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ptrs := make([]*item.Item, len(items))
+	for i := range items {
+		ptrs[i] = &items[i]
+	}
+
+	page, err := listing.Paginate(ptrs, args.Params, sort, inventorySortColumns["id"], item.ItemSortKey(args.Sort))
+	if err != nil {
+		return nil, fmt.Errorf("paginating inventory: %w", err)
+	}
+
+	return page, nil
+}
+
+func (r *memRepository) ListEquippedItems(ctx context.Context, playerID int32) ([]item.Item, error) {
+	return r.listItems(ctx, playerID, func(_ memdb.Item, equipped bool) bool { return equipped })
+}
+
+func (r *memRepository) SetItemEquipped(ctx context.Context, args SetItemEquippedParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		key := memdb.InventoryKey{PlayerID: args.PlayerID, ItemID: args.ItemID}
+		if _, ok := t.Inventory[key]; !ok {
+			return ErrItemNotInInventory
+		}
+		t.Inventory[key] = memdb.InventoryRow{Equipped: args.Equipped}
+
+		return nil
+	})
+}
+
+func (r *memRepository) RemoveItem(ctx context.Context, args RemoveItemParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		delete(t.Inventory, memdb.InventoryKey{PlayerID: args.PlayerID, ItemID: args.ItemID})
+		return nil
+	})
+}
@@ -2,24 +2,37 @@ package inventory
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/database"
 	"github.com/hossokawa/go-nethttp-example/internal/item"
-	"github.com/jackc/pgx/v5"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type InventoryRepository interface {
 	AddItem(ctx context.Context, args AddItemParams) error
 	ListPlayerItems(ctx context.Context, playerID int32) ([]item.Item, error)
+	ListInventory(ctx context.Context, args ListInventoryParams) (*listing.Page[*item.Item], error)
+	ListEquippedItems(ctx context.Context, playerID int32) ([]item.Item, error)
+	SetItemEquipped(ctx context.Context, args SetItemEquippedParams) error
 	RemoveItem(ctx context.Context, args RemoveItemParams) error
 }
 
+var (
+	ErrItemNotInInventory = apperr.New(apperr.KindNotFound, "item not in inventory")
+	ErrItemAlreadyOwned   = apperr.Conflict("item is already in inventory")
+)
+
 type pgRepository struct {
-	db *pgx.Conn
+	db *database.DB
 }
 
-func NewPostgresRepository(db *pgx.Conn) InventoryRepository {
+func NewPostgresRepository(db *database.DB) InventoryRepository {
 	return &pgRepository{db: db}
 }
 
@@ -29,11 +42,13 @@ VALUES ($1, $2)
 `
 
 type AddItemParams struct {
-	PlayerID int32     `json:"player_id"`
-	ItemID   uuid.UUID `json:"item_id"`
+	PlayerID int32     `json:"player_id" validate:"required,min=1"`
+	ItemID   uuid.UUID `json:"item_id" validate:"required"`
 }
 
 func (r *pgRepository) AddItem(ctx context.Context, args AddItemParams) error {
+	ctx = querylabel.With(ctx, "inventory", "AddItem")
+
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
@@ -42,6 +57,10 @@ func (r *pgRepository) AddItem(ctx context.Context, args AddItemParams) error {
 
 	_, err = tx.Exec(ctx, addItem, args.PlayerID, args.ItemID)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrItemAlreadyOwned
+		}
 		return fmt.Errorf("adding item to player's inventory: %w", err)
 	}
 
@@ -53,13 +72,15 @@ func (r *pgRepository) AddItem(ctx context.Context, args AddItemParams) error {
 }
 
 const listPlayerItems = `
-SELECT item.id, item.name, item.value
+SELECT item.id, item.name, item.value, item.stats
 FROM inventory
 JOIN item ON item.id = item_id
-WHERE player_id = $1
+WHERE player_id = $1 AND item.deleted_at IS NULL
 `
 
 func (r *pgRepository) ListPlayerItems(ctx context.Context, playerID int32) ([]item.Item, error) {
+	ctx = querylabel.With(ctx, "inventory", "ListPlayerItems")
+
 	rows, err := r.db.Query(ctx, listPlayerItems, playerID)
 	if err != nil {
 		return nil, fmt.Errorf("getting all items for player: %w", err)
@@ -71,7 +92,105 @@ func (r *pgRepository) ListPlayerItems(ctx context.Context, playerID int32) ([]i
 	for rows.Next() {
 		var i item.Item
 
-		if err := rows.Scan(&i.ID, &i.Name, &i.Value); err != nil {
+		if err := rows.Scan(&i.ID, &i.Name, &i.Value, &i.Stats); err != nil {
+			return nil, fmt.Errorf("scanning rows from inventory into item struct: %w", err)
+		}
+
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+const listInventory = `
+SELECT item.id, item.name, item.value, item.stats
+FROM inventory
+JOIN item ON item.id = item_id`
+
+var inventorySortColumns = map[string]listing.Column{
+	"id":    {Expr: "item.id", Type: listing.UUID},
+	"name":  {Expr: "item.name", Type: listing.Text},
+	"value": {Expr: "item.value", Type: listing.Int},
+}
+
+var InventorySortFields = item.ItemSortFields
+
+type ListInventoryParams struct {
+	listing.Params
+	PlayerID   int32
+	NamePrefix string
+	Equipped   *bool
+}
+
+func (r *pgRepository) ListInventory(ctx context.Context, args ListInventoryParams) (*listing.Page[*item.Item], error) {
+	ctx = querylabel.With(ctx, "inventory", "ListInventory")
+
+	sort, ok := inventorySortColumns[args.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field '%v'", args.Sort)
+	}
+
+	var q listing.Query
+	q.Where("player_id = " + q.Arg(args.PlayerID))
+	q.Where("item.deleted_at IS NULL")
+	if args.NamePrefix != "" {
+		q.Where("item.name LIKE " + q.Arg(listing.LikePrefix(args.NamePrefix)) + ` ESCAPE '\'`)
+	}
+	if args.Equipped != nil {
+		q.Where("equipped = " + q.Arg(*args.Equipped))
+	}
+	query := q.Build(listInventory, args.Params, sort, inventorySortColumns["id"])
+
+	rows, err := r.db.Query(ctx, query, q.Args()...)
+	if err != nil {
+		return nil, fmt.Errorf("getting inventory page for player: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*item.Item
+
+	for rows.Next() {
+		var i item.Item
+
+		if err := rows.Scan(&i.ID, &i.Name, &i.Value, &i.Stats); err != nil {
+			return nil, fmt.Errorf("scanning rows from inventory into item struct: %w", err)
+		}
+
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return listing.NewPage(items, args.Params, item.ItemSortKey(args.Sort)), nil
+}
+
+const listEquippedItems = `
+SELECT item.id, item.name, item.value, item.stats
+FROM inventory
+JOIN item ON item.id = item_id
+WHERE player_id = $1 AND item.deleted_at IS NULL
+AND equipped
+`
+
+func (r *pgRepository) ListEquippedItems(ctx context.Context, playerID int32) ([]item.Item, error) {
+	ctx = querylabel.With(ctx, "inventory", "ListEquippedItems")
+
+	rows, err := r.db.Query(ctx, listEquippedItems, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("getting equipped items for player: %w", err)
+	}
+	defer rows.Close()
+
+	var items []item.Item
+
+	for rows.Next() {
+		var i item.Item
+
+		if err := rows.Scan(&i.ID, &i.Name, &i.Value, &i.Stats); err != nil {
 			return nil, fmt.Errorf("scanning rows from inventory into item struct: %w", err)
 		}
 
@@ -84,6 +203,42 @@ func (r *pgRepository) ListPlayerItems(ctx context.Context, playerID int32) ([]i
 	return items, nil
 }
 
+const setItemEquipped = `
+UPDATE inventory SET equipped = $3
+WHERE player_id = $1
+AND item_id = $2
+`
+
+type SetItemEquippedParams struct {
+	PlayerID int32     `json:"player_id"`
+	ItemID   uuid.UUID `json:"item_id"`
+	Equipped bool      `json:"equipped"`
+}
+
+func (r *pgRepository) SetItemEquipped(ctx context.Context, args SetItemEquippedParams) error {
+	ctx = querylabel.With(ctx, "inventory", "SetItemEquipped")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, setItemEquipped, args.PlayerID, args.ItemID, args.Equipped)
+	if err != nil {
+		return fmt.Errorf("updating equipped state of inventory item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrItemNotInInventory
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
 const removeItem = `
 DELETE FROM inventory
 WHERE player_id = $1
@@ -96,6 +251,8 @@ type RemoveItemParams struct {
 }
 
 func (r *pgRepository) RemoveItem(ctx context.Context, args RemoveItemParams) error {
+	ctx = querylabel.With(ctx, "inventory", "RemoveItem")
+
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
@@ -6,6 +6,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/validate"
 )
 
 type InventoryService struct {
@@ -17,7 +19,12 @@ func NewInventoryService(repo InventoryRepository) *InventoryService {
 }
 
 func (s *InventoryService) AddItem(ctx context.Context, playerID int32, itemID uuid.UUID) error {
-	err := s.repo.AddItem(ctx, AddItemParams{PlayerID: playerID, ItemID: itemID})
+	args := AddItemParams{PlayerID: playerID, ItemID: itemID}
+	if err := validate.Struct(args).Err("inventory item"); err != nil {
+		return err
+	}
+
+	err := s.repo.AddItem(ctx, args)
 	if err != nil {
 		return fmt.Errorf("adding item with id %v to inventory of player with id %v: %w", itemID, playerID, err)
 	}
@@ -34,6 +41,42 @@ func (s *InventoryService) ListPlayerItems(ctx context.Context, playerID int32)
 	return items, nil
 }
 
+func (s *InventoryService) ListInventory(ctx context.Context, args ListInventoryParams) (*listing.Page[*item.Item], error) {
+	page, err := s.repo.ListInventory(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("listing inventory of player with id %v: %w", args.PlayerID, err)
+	}
+
+	return page, nil
+}
+
+func (s *InventoryService) ListEquippedItems(ctx context.Context, playerID int32) ([]item.Item, error) {
+	items, err := s.repo.ListEquippedItems(ctx, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("getting equipped items for player with id %v: %w", playerID, err)
+	}
+
+	return items, nil
+}
+
+func (s *InventoryService) EquipItem(ctx context.Context, playerID int32, itemID uuid.UUID) error {
+	err := s.repo.SetItemEquipped(ctx, SetItemEquippedParams{PlayerID: playerID, ItemID: itemID, Equipped: true})
+	if err != nil {
+		return fmt.Errorf("equipping item with id %v for player with id %v: %w", itemID, playerID, err)
+	}
+
+	return nil
+}
+
+func (s *InventoryService) UnequipItem(ctx context.Context, playerID int32, itemID uuid.UUID) error {
+	err := s.repo.SetItemEquipped(ctx, SetItemEquippedParams{PlayerID: playerID, ItemID: itemID, Equipped: false})
+	if err != nil {
+		return fmt.Errorf("unequipping item with id %v for player with id %v: %w", itemID, playerID, err)
+	}
+
+	return nil
+}
+
 func (s *InventoryService) RemoveItem(ctx context.Context, playerID int32, itemID uuid.UUID) error {
 	err := s.repo.RemoveItem(ctx, RemoveItemParams{PlayerID: playerID, ItemID: itemID})
 	if err != nil {
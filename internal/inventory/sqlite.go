@@ -0,0 +1,218 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+	"github.com/hossokawa/go-nethttp-example/internal/sqlite"
+)
+
+type sqliteRepository struct {
+	db *sqlite.DB
+}
+
+// NewSQLiteRepository returns an InventoryRepository that keeps inventories
+// in an SQLite database. It behaves like the Postgres repository, down to the
+// errors it returns.
+func NewSQLiteRepository(db *sqlite.DB) InventoryRepository {
+	return &sqliteRepository{db: db}
+}
+
+// scanSQLiteItem reads an item selected as id, name, value, stats, decoding
+// the stats stored as JSON text.
+func scanSQLiteItem(row interface{ Scan(...any) error }) (item.Item, error) {
+	var i item.Item
+	var stats string
+
+	if err := row.Scan(&i.ID, &i.Name, &i.Value, &stats); err != nil {
+		return i, err
+	}
+	if err := json.Unmarshal([]byte(stats), &i.Stats); err != nil {
+		return i, fmt.Errorf("decoding item stats: %w", err)
+	}
+
+	return i, nil
+}
+
+const sqliteAddItem = `
+INSERT INTO inventory (player_id, item_id)
+VALUES (?1, ?2)
+`
+
+func (r *sqliteRepository) AddItem(ctx context.Context, args AddItemParams) error {
+	ctx = querylabel.With(ctx, "inventory", "AddItem")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, sqliteAddItem, args.PlayerID, args.ItemID)
+	if err != nil {
+		if sqlite.IsUniqueViolation(err) {
+			return ErrItemAlreadyOwned
+		}
+		return fmt.Errorf("adding item to player's inventory: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+const sqliteListPlayerItems = `
+SELECT item.id, item.name, item.value, item.stats
+FROM inventory
+JOIN item ON item.id = item_id
+WHERE player_id = ?1 AND item.deleted_at IS NULL
+`
+
+func (r *sqliteRepository) ListPlayerItems(ctx context.Context, playerID int32) ([]item.Item, error) {
+	ctx = querylabel.With(ctx, "inventory", "ListPlayerItems")
+
+	return r.listItems(ctx, sqliteListPlayerItems, playerID)
+}
+
+const sqliteListEquippedItems = `
+SELECT item.id, item.name, item.value, item.stats
+FROM inventory
+JOIN item ON item.id = item_id
+WHERE player_id = ?1 AND item.deleted_at IS NULL
+AND equipped
+`
+
+func (r *sqliteRepository) ListEquippedItems(ctx context.Context, playerID int32) ([]item.Item, error) {
+	ctx = querylabel.With(ctx, "inventory", "ListEquippedItems")
+
+	return r.listItems(ctx, sqliteListEquippedItems, playerID)
+}
+
+func (r *sqliteRepository) listItems(ctx context.Context, query string, playerID int32) ([]item.Item, error) {
+	rows, err := r.db.Query(ctx, query, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("getting items for player: %w", err)
+	}
+	defer rows.Close()
+
+	var items []item.Item
+
+	for rows.Next() {
+		i, err := scanSQLiteItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning rows from inventory into item struct: %w", err)
+		}
+
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (r *sqliteRepository) ListInventory(ctx context.Context, args ListInventoryParams) (*listing.Page[*item.Item], error) {
+	ctx = querylabel.With(ctx, "inventory", "ListInventory")
+
+	sort, ok := inventorySortColumns[args.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field '%v'", args.Sort)
+	}
+
+	q := listing.Query{Dialect: sqlite.Dialect}
+	q.Where("player_id = " + q.Arg(args.PlayerID))
+	q.Where("item.deleted_at IS NULL")
+	if args.NamePrefix != "" {
+		q.Where("item.name LIKE " + q.Arg(listing.LikePrefix(args.NamePrefix)) + ` ESCAPE '\'`)
+	}
+	if args.Equipped != nil {
+		q.Where("equipped = " + q.Arg(*args.Equipped))
+	}
+	query := q.Build(listInventory, args.Params, sort, inventorySortColumns["id"])
+
+	rows, err := r.db.Query(ctx, query, q.Args()...)
+	if err != nil {
+		return nil, fmt.Errorf("getting inventory page for player: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*item.Item
+
+	for rows.Next() {
+		i, err := scanSQLiteItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning rows from inventory into item struct: %w", err)
+		}
+
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return listing.NewPage(items, args.Params, item.ItemSortKey(args.Sort)), nil
+}
+
+const sqliteSetItemEquipped = `
+UPDATE inventory SET equipped = ?3
+WHERE player_id = ?1
+AND item_id = ?2
+`
+
+func (r *sqliteRepository) SetItemEquipped(ctx context.Context, args SetItemEquippedParams) error {
+	ctx = querylabel.With(ctx, "inventory", "SetItemEquipped")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(ctx, sqliteSetItemEquipped, args.PlayerID, args.ItemID, args.Equipped)
+	if err != nil {
+		return fmt.Errorf("updating equipped state of inventory item: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrItemNotInInventory
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+const sqliteRemoveItem = `
+DELETE FROM inventory
+WHERE player_id = ?1
+AND item_id = ?2
+`
+
+func (r *sqliteRepository) RemoveItem(ctx context.Context, args RemoveItemParams) error {
+	ctx = querylabel.With(ctx, "inventory", "RemoveItem")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, sqliteRemoveItem, args.PlayerID, args.ItemID)
+	if err != nil {
+		return fmt.Errorf("removing item from player's inventory: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
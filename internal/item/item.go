@@ -1,9 +1,35 @@
 package item
 
-import "github.com/google/uuid"
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/stats"
+)
 
 type Item struct {
-	ID    uuid.UUID `json:"id"`
-	Name  string    `json:"name"`
-	Value int32     `json:"value"`
+	ID      uuid.UUID        `json:"id"`
+	Name    string           `json:"name"`
+	Value   int32            `json:"value"`
+	Stats   map[string]int32 `json:"stats"`
+	Prices  []Price          `json:"prices,omitempty"`
+	Version int32            `json:"version"`
+}
+
+// Price is what the item costs in a single currency. An item can be sold for
+// several currencies at once.
+type Price struct {
+	Currency string `json:"currency"`
+	Amount   int32  `json:"amount"`
+}
+
+// Modifiers returns the stat bonuses granted by the item while it is equipped.
+func (i Item) Modifiers() []stats.Modifier {
+	mods := make([]stats.Modifier, 0, len(i.Stats))
+	for stat, value := range i.Stats {
+		mods = append(mods, stats.Modifier{Stat: stat, Value: value})
+	}
+	sort.Slice(mods, func(a, b int) bool { return mods[a].Stat < mods[b].Stat })
+
+	return mods
 }
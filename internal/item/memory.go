@@ -0,0 +1,353 @@
+package item
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+)
+
+type memRepository struct {
+	db *memdb.DB
+}
+
+// NewMemoryRepository returns an ItemRepository that keeps items in db. It
+// behaves like the Postgres repository, down to the errors it returns.
+func NewMemoryRepository(db *memdb.DB) ItemRepository {
+	return &memRepository{db: db}
+}
+
+func toItem(row memdb.Item) *Item {
+	stats := maps.Clone(row.Stats)
+	if stats == nil {
+		stats = make(map[string]int32)
+	}
+
+	return &Item{
+		ID:      row.ID,
+		Name:    row.Name,
+		Value:   row.Value,
+		Stats:   stats,
+		Version: row.Version,
+	}
+}
+
+// liveItem returns the item with the given id unless it does not exist or was
+// soft-deleted.
+func liveItem(t *memdb.Tables, id uuid.UUID) (memdb.Item, bool) {
+	row, ok := t.Items[id]
+	return row, ok && row.DeletedAt == nil
+}
+
+// nameTaken reports whether another item, deleted or not, has the given name.
+func nameTaken(t *memdb.Tables, name string, id uuid.UUID) bool {
+	for _, row := range t.Items {
+		if row.Name == name && row.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memRepository) CreateItem(ctx context.Context, args CreateItemParams) (*Item, error) {
+	var i *Item
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		if nameTaken(t, args.Name, uuid.Nil) {
+			return ErrItemNameInUse
+		}
+
+		row := memdb.Item{
+			ID:      uuid.New(),
+			Name:    args.Name,
+			Value:   args.Value,
+			Stats:   make(map[string]int32),
+			Version: 1,
+		}
+		t.Items[row.ID] = row
+
+		i = toItem(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+func (r *memRepository) ListItems(ctx context.Context, args ListItemsParams) (*listing.Page[*Item], error) {
+	sort, ok := itemSortColumns[args.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field '%v'", args.Sort)
+	}
+
+	var items []*Item
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Items {
+			switch {
+			case row.DeletedAt != nil:
+			case args.NamePrefix != "" && !strings.HasPrefix(row.Name, args.NamePrefix):
+			case args.MinValue != nil && row.Value < *args.MinValue:
+			case args.MaxValue != nil && row.Value > *args.MaxValue:
+			default:
+				items = append(items, toItem(row))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := listing.Paginate(items, args.Params, sort, itemSortColumns["id"], ItemSortKey(args.Sort))
+	if err != nil {
+		return nil, fmt.Errorf("paginating items: %w", err)
+	}
+
+	return page, nil
+}
+
+// getItem returns the first live item matching fn, or nil if there is none.
+func (r *memRepository) getItem(ctx context.Context, fn func(row memdb.Item) bool) (*Item, error) {
+	var i *Item
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Items {
+			if row.DeletedAt == nil && fn(row) {
+				i = toItem(row)
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+func (r *memRepository) GetItemByID(ctx context.Context, id uuid.UUID) (*Item, error) {
+	return r.getItem(ctx, func(row memdb.Item) bool { return row.ID == id })
+}
+
+func (r *memRepository) GetItemByName(ctx context.Context, name string) (*Item, error) {
+	return r.getItem(ctx, func(row memdb.Item) bool { return row.Name == name })
+}
+
+// conditional looks up the live item a conditional write applies to. Like
+// missOrConflict, it reports a missing item as a nil error and an item at
+// another version as ErrVersionMismatch.
+func conditional(t *memdb.Tables, id uuid.UUID, version *int32) (memdb.Item, bool, error) {
+	row, ok := liveItem(t, id)
+	if !ok {
+		return row, false, nil
+	}
+	if version != nil && row.Version != *version {
+		return row, false, ErrVersionMismatch
+	}
+	return row, true, nil
+}
+
+func (r *memRepository) UpdateItem(ctx context.Context, args UpdateItemParams) (*Item, error) {
+	var i *Item
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		row, ok, err := conditional(t, args.ID, args.Version)
+		if !ok {
+			return err
+		}
+
+		if args.Name != nil {
+			if nameTaken(t, *args.Name, row.ID) {
+				return ErrItemNameInUse
+			}
+			row.Name = *args.Name
+		}
+		if args.Value != nil {
+			row.Value = *args.Value
+		}
+		row.Version++
+		t.Items[row.ID] = row
+
+		i = toItem(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+func (r *memRepository) DeleteItemByID(ctx context.Context, args DeleteItemParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		row, ok, err := conditional(t, args.ID, args.Version)
+		if !ok {
+			return err
+		}
+
+		now := time.Now()
+		row.DeletedAt = &now
+		row.Version++
+		t.Items[row.ID] = row
+
+		return nil
+	})
+}
+
+func (r *memRepository) RestoreItem(ctx context.Context, args RestoreItemParams) (*Item, error) {
+	var i *Item
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		row, ok := t.Items[args.ID]
+		if !ok || row.DeletedAt == nil {
+			return nil
+		}
+		if time.Since(*row.DeletedAt) > args.GracePeriod {
+			return ErrRestoreExpired
+		}
+
+		row.DeletedAt = nil
+		row.Version++
+		t.Items[row.ID] = row
+
+		i = toItem(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+func (r *memRepository) PurgeDeletedItems(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		ids := make(map[uuid.UUID]bool)
+		for _, row := range t.Items {
+			if row.DeletedAt != nil && row.DeletedAt.Before(before) {
+				ids[row.ID] = true
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		for id, b := range t.Bundles {
+			b.ItemIDs = slices.DeleteFunc(slices.Clone(b.ItemIDs), func(itemID uuid.UUID) bool {
+				return ids[itemID]
+			})
+			t.Bundles[id] = b
+		}
+		for key := range t.StorageItems {
+			if ids[key.ItemID] {
+				delete(t.StorageItems, key)
+			}
+		}
+		for key := range t.Inventory {
+			if ids[key.ItemID] {
+				delete(t.Inventory, key)
+			}
+		}
+		for key := range t.ItemPrices {
+			if ids[key.ItemID] {
+				delete(t.ItemPrices, key)
+			}
+		}
+		for id := range ids {
+			delete(t.Items, id)
+		}
+
+		n = int64(len(ids))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (r *memRepository) ListItemPrices(ctx context.Context, itemIDs []uuid.UUID) (map[uuid.UUID][]Price, error) {
+	prices := make(map[uuid.UUID][]Price)
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for key, amount := range t.ItemPrices {
+			if slices.Contains(itemIDs, key.ItemID) {
+				prices[key.ItemID] = append(prices[key.ItemID], Price{Currency: key.Currency, Amount: amount})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ps := range prices {
+		slices.SortFunc(ps, func(a, b Price) int { return cmp.Compare(a.Currency, b.Currency) })
+	}
+
+	return prices, nil
+}
+
+func (r *memRepository) SetItemPrice(ctx context.Context, args SetItemPriceParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		if _, ok := t.Currencies[args.Currency]; !ok {
+			return ErrUnknownCurrency
+		}
+		row, ok := t.Items[args.ID]
+		if !ok {
+			return fmt.Errorf("setting item price: %w", memdb.ErrForeignKey)
+		}
+
+		t.ItemPrices[memdb.ItemPriceKey{ItemID: args.ID, Currency: args.Currency}] = args.Amount
+		row.Version++
+		t.Items[row.ID] = row
+
+		return nil
+	})
+}
+
+func (r *memRepository) RemoveItemPrice(ctx context.Context, args RemoveItemPriceParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		key := memdb.ItemPriceKey{ItemID: args.ID, Currency: args.Currency}
+		if _, ok := t.ItemPrices[key]; !ok {
+			return nil
+		}
+
+		delete(t.ItemPrices, key)
+		if row, ok := t.Items[args.ID]; ok {
+			row.Version++
+			t.Items[row.ID] = row
+		}
+
+		return nil
+	})
+}
+
+func (r *memRepository) CountItems(ctx context.Context) (int64, error) {
+	var n int64
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Items {
+			if row.DeletedAt == nil {
+				n++
+			}
+		}
+		return nil
+	})
+
+	return n, err
+}
@@ -0,0 +1,69 @@
+package item
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/validate"
+)
+
+var readOnlyFields = map[string]bool{
+	"id":      true,
+	"stats":   true,
+	"prices":  true,
+	"version": true,
+}
+
+// ParsePatch reads a JSON Merge Patch (RFC 7396) document for an item into the
+// update it describes. Only name and value can be patched. Prices have their
+// own endpoints.
+func ParsePatch(args UpdateItemParams, data []byte) (UpdateItemParams, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return args, apperr.Invalid("patch must be a JSON object")
+	}
+
+	fields := make([]string, 0, len(doc))
+	for field := range doc {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var verr apperr.Fields
+
+	for _, field := range fields {
+		raw := doc[field]
+		if readOnlyFields[field] {
+			verr.Add(field, "field is read-only")
+			continue
+		}
+		if string(raw) == "null" {
+			verr.Add(field, "field cannot be removed")
+			continue
+		}
+
+		switch field {
+		case "name":
+			var name string
+			if err := json.Unmarshal(raw, &name); err != nil {
+				verr.Add(field, "must be a string")
+				continue
+			}
+			args.Name = &name
+		case "value":
+			var value int32
+			if err := json.Unmarshal(raw, &value); err != nil {
+				verr.Add(field, "must be an integer")
+				continue
+			}
+			args.Value = &value
+		default:
+			verr.Add(field, "unknown field")
+		}
+	}
+
+	verr = append(verr, validate.Struct(args)...)
+
+	return args, verr.Err("item")
+}
@@ -0,0 +1,40 @@
+package item
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Purger periodically hard-deletes items whose restore grace period has run
+// out.
+type Purger struct {
+	service  *ItemService
+	interval time.Duration
+}
+
+func NewPurger(service *ItemService, interval time.Duration) *Purger {
+	return &Purger{service: service, interval: interval}
+}
+
+// Run purges on every tick until ctx is cancelled.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := p.service.PurgeDeletedItems(ctx)
+			if err != nil {
+				log.Printf("Error purging deleted items: %s", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("Purged %d deleted items", n)
+			}
+		}
+	}
+}
@@ -2,41 +2,68 @@ package item
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type ItemRepository interface {
 	CreateItem(ctx context.Context, args CreateItemParams) (*Item, error)
-	GetAllItems(ctx context.Context) ([]*Item, error)
+	ListItems(ctx context.Context, args ListItemsParams) (*listing.Page[*Item], error)
 	GetItemByID(ctx context.Context, id uuid.UUID) (*Item, error)
 	GetItemByName(ctx context.Context, name string) (*Item, error)
-	UpdateItemValue(ctx context.Context, args UpdateItemValueParams) error
-	DeleteItemByID(ctx context.Context, id uuid.UUID) error
+	UpdateItem(ctx context.Context, args UpdateItemParams) (*Item, error)
+	DeleteItemByID(ctx context.Context, args DeleteItemParams) error
+	RestoreItem(ctx context.Context, args RestoreItemParams) (*Item, error)
+	PurgeDeletedItems(ctx context.Context, before time.Time) (int64, error)
+	ListItemPrices(ctx context.Context, itemIDs []uuid.UUID) (map[uuid.UUID][]Price, error)
+	SetItemPrice(ctx context.Context, args SetItemPriceParams) error
+	RemoveItemPrice(ctx context.Context, args RemoveItemPriceParams) error
+	CountItems(ctx context.Context) (int64, error)
 }
 
+var (
+	ErrUnknownCurrency = apperr.Invalid("unknown currency")
+	ErrItemNameInUse   = apperr.Conflict("item name already in use")
+	// ErrVersionMismatch is returned by conditional writes when the item was
+	// changed since the caller read it.
+	ErrVersionMismatch = apperr.New(apperr.KindPreconditionFailed, "item was modified by another request")
+	// ErrRestoreExpired is returned when restoring an item whose grace period
+	// has already run out.
+	ErrRestoreExpired = apperr.New(apperr.KindGone, "item was deleted too long ago to be restored")
+)
+
 type pgRepository struct {
-	db *pgx.Conn
+	db *database.DB
 }
 
-func NewPostgresRepository(db *pgx.Conn) ItemRepository {
+func NewPostgresRepository(db *database.DB) ItemRepository {
 	return &pgRepository{db: db}
 }
 
 const createItem = `
-INSERT INTO item (name, value)
-VALUES ($1, $2)
-RETURNING id, name, value
+INSERT INTO item (id, name, value)
+VALUES ($1, $2, $3)
+RETURNING id, name, value, stats, version
 `
 
 type CreateItemParams struct {
-	Name  string `json:"name"`
-	Value int32  `json:"value"`
+	Name  string `json:"name" validate:"required,notblank,max=64,charset=printable"`
+	Value int32  `json:"value" validate:"min=0"`
 }
 
 func (r *pgRepository) CreateItem(ctx context.Context, args CreateItemParams) (*Item, error) {
+	ctx = querylabel.With(ctx, "item", "CreateItem")
+
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("beginning transaction: %w", err)
@@ -45,13 +72,19 @@ func (r *pgRepository) CreateItem(ctx context.Context, args CreateItemParams) (*
 
 	var i Item
 
-	row := tx.QueryRow(ctx, createItem, args.Name, args.Value)
+	row := tx.QueryRow(ctx, createItem, uuid.New(), args.Name, args.Value)
 	err = row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.Value,
+		&i.Stats,
+		&i.Version,
 	)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrItemNameInUse
+		}
 		return nil, fmt.Errorf("scanning row into item struct: %w", err)
 	}
 
@@ -62,14 +95,67 @@ func (r *pgRepository) CreateItem(ctx context.Context, args CreateItemParams) (*
 	return &i, nil
 }
 
-const getAllItems = `
-SELECT id, name, value FROM item
-`
+const listItems = `
+SELECT id, name, value, stats, version FROM item`
+
+var itemSortColumns = map[string]listing.Column{
+	"id":    {Expr: "id", Type: listing.UUID},
+	"name":  {Expr: "name", Type: listing.Text},
+	"value": {Expr: "value", Type: listing.Int},
+}
+
+var ItemSortFields = []listing.Field{
+	{Name: "id", Type: listing.UUID},
+	{Name: "name", Type: listing.Text},
+	{Name: "value", Type: listing.Int},
+}
+
+type ListItemsParams struct {
+	listing.Params
+	NamePrefix string
+	MinValue   *int32
+	MaxValue   *int32
+}
+
+// ItemSortKey returns the cursor key of an item for the given sort field.
+func ItemSortKey(sort string) func(*Item) (string, string) {
+	return func(i *Item) (string, string) {
+		id := i.ID.String()
+		switch sort {
+		case "name":
+			return i.Name, id
+		case "value":
+			return strconv.Itoa(int(i.Value)), id
+		default:
+			return id, id
+		}
+	}
+}
+
+func (r *pgRepository) ListItems(ctx context.Context, args ListItemsParams) (*listing.Page[*Item], error) {
+	ctx = querylabel.With(ctx, "item", "ListItems")
+
+	sort, ok := itemSortColumns[args.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field '%v'", args.Sort)
+	}
 
-func (r *pgRepository) GetAllItems(ctx context.Context) ([]*Item, error) {
-	rows, err := r.db.Query(ctx, getAllItems)
+	var q listing.Query
+	q.Where("deleted_at IS NULL")
+	if args.NamePrefix != "" {
+		q.Where("name LIKE " + q.Arg(listing.LikePrefix(args.NamePrefix)) + ` ESCAPE '\'`)
+	}
+	if args.MinValue != nil {
+		q.Where("value >= " + q.Arg(*args.MinValue))
+	}
+	if args.MaxValue != nil {
+		q.Where("value <= " + q.Arg(*args.MaxValue))
+	}
+	query := q.Build(listItems, args.Params, sort, itemSortColumns["id"])
+
+	rows, err := r.db.Query(ctx, query, q.Args()...)
 	if err != nil {
-		return nil, fmt.Errorf("querying for all items: %w", err)
+		return nil, fmt.Errorf("querying for items: %w", err)
 	}
 	defer rows.Close()
 
@@ -82,6 +168,8 @@ func (r *pgRepository) GetAllItems(ctx context.Context) ([]*Item, error) {
 			&i.ID,
 			&i.Name,
 			&i.Value,
+			&i.Stats,
+			&i.Version,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scanning rows into item struct: %w", err)
@@ -89,15 +177,20 @@ func (r *pgRepository) GetAllItems(ctx context.Context) ([]*Item, error) {
 
 		items = append(items, &i)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return items, nil
+	return listing.NewPage(items, args.Params, ItemSortKey(args.Sort)), nil
 }
 
 const getItemByID = `
-SELECT id, name, value FROM item WHERE id = $1
+SELECT id, name, value, stats, version FROM item WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (r *pgRepository) GetItemByID(ctx context.Context, id uuid.UUID) (*Item, error) {
+	ctx = querylabel.With(ctx, "item", "GetItemByID")
+
 	var i Item
 
 	row := r.db.QueryRow(ctx, getItemByID, id)
@@ -105,8 +198,13 @@ func (r *pgRepository) GetItemByID(ctx context.Context, id uuid.UUID) (*Item, er
 		&i.ID,
 		&i.Name,
 		&i.Value,
+		&i.Stats,
+		&i.Version,
 	)
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
 		return nil, fmt.Errorf("scanning row into item struct: %w", err)
 	}
 
@@ -114,10 +212,12 @@ func (r *pgRepository) GetItemByID(ctx context.Context, id uuid.UUID) (*Item, er
 }
 
 const getItemByName = `
-SELECT id, name, value FROM item WHERE name = $1
+SELECT id, name, value, stats, version FROM item WHERE name = $1 AND deleted_at IS NULL
 `
 
 func (r *pgRepository) GetItemByName(ctx context.Context, name string) (*Item, error) {
+	ctx = querylabel.With(ctx, "item", "GetItemByName")
+
 	var i Item
 
 	row := r.db.QueryRow(ctx, getItemByName, name)
@@ -125,33 +225,309 @@ func (r *pgRepository) GetItemByName(ctx context.Context, name string) (*Item, e
 		&i.ID,
 		&i.Name,
 		&i.Value,
+		&i.Stats,
+		&i.Version,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into item struct: %w", err)
+	}
+
+	return &i, nil
+}
+
+const updateItem = `
+UPDATE item SET
+  name = COALESCE($2, name),
+  value = COALESCE($3, value),
+  version = version + 1
+WHERE id = $1 AND deleted_at IS NULL AND ($4::int IS NULL OR version = $4)
+RETURNING id, name, value, stats, version
+`
+
+// UpdateItemParams describes a partial update: nil fields are left as they
+// are. When Version is set the update only applies if the item is still at
+// that version.
+type UpdateItemParams struct {
+	ID      uuid.UUID `json:"id"`
+	Name    *string   `json:"name" validate:"notblank,max=64,charset=printable"`
+	Value   *int32    `json:"value" validate:"min=0"`
+	Version *int32    `json:"-"`
+}
+
+func (r *pgRepository) UpdateItem(ctx context.Context, args UpdateItemParams) (*Item, error) {
+	ctx = querylabel.With(ctx, "item", "UpdateItem")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var i Item
+
+	row := tx.QueryRow(ctx, updateItem, args.ID, args.Name, args.Value, args.Version)
+	err = row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Value,
+		&i.Stats,
+		&i.Version,
 	)
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, r.missOrConflict(ctx, tx, args.ID)
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrItemNameInUse
+		}
 		return nil, fmt.Errorf("scanning row into item struct: %w", err)
 	}
 
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
 	return &i, nil
 }
 
-const updateItemValue = `
-UPDATE item SET value = $2 WHERE id = $1
+const deleteItemByID = `
+UPDATE item SET deleted_at = now(), version = version + 1
+WHERE id = $1 AND deleted_at IS NULL AND ($2::int IS NULL OR version = $2)
+`
+
+type DeleteItemParams struct {
+	ID      uuid.UUID `json:"id"`
+	Version *int32    `json:"-"`
+}
+
+// DeleteItemByID soft-deletes an item: it is hidden from every other query,
+// including inventories and storages, until it is restored or purged.
+func (r *pgRepository) DeleteItemByID(ctx context.Context, args DeleteItemParams) error {
+	ctx = querylabel.With(ctx, "item", "DeleteItemByID")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, deleteItemByID, args.ID, args.Version)
+	if err != nil {
+		return fmt.Errorf("deleting item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return r.missOrConflict(ctx, tx, args.ID)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+const getDeletedAt = `
+SELECT deleted_at FROM item WHERE id = $1 FOR UPDATE
+`
+
+const restoreItem = `
+UPDATE item SET deleted_at = NULL, version = version + 1
+WHERE id = $1
+RETURNING id, name, value, stats, version
 `
 
-type UpdateItemValueParams struct {
+type RestoreItemParams struct {
+	ID          uuid.UUID     `json:"id"`
+	GracePeriod time.Duration `json:"-"`
+}
+
+// RestoreItem undoes a soft delete made less than GracePeriod ago. It returns
+// nil if there is no deleted item with the given id.
+func (r *pgRepository) RestoreItem(ctx context.Context, args RestoreItemParams) (*Item, error) {
+	ctx = querylabel.With(ctx, "item", "RestoreItem")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var deletedAt *time.Time
+
+	err = tx.QueryRow(ctx, getDeletedAt, args.ID).Scan(&deletedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("locking item for restore: %w", err)
+	}
+	if deletedAt == nil {
+		return nil, nil
+	}
+	if time.Since(*deletedAt) > args.GracePeriod {
+		return nil, ErrRestoreExpired
+	}
+
+	var i Item
+
+	row := tx.QueryRow(ctx, restoreItem, args.ID)
+	err = row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Value,
+		&i.Stats,
+		&i.Version,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("scanning row into item struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return &i, nil
+}
+
+const lockPurgeableItems = `
+SELECT id FROM item WHERE deleted_at < $1 FOR UPDATE
+`
+
+// purgeItemData lists the statements that remove every reference to the
+// items in $1 before the items themselves. Purchase receipt grants are kept:
+// they are a record of what was sold, not a reference the item needs.
+var purgeItemData = []string{
+	`DELETE FROM shop_bundle_item WHERE item_id = ANY($1)`,
+	`DELETE FROM account_storage_item WHERE item_id = ANY($1)`,
+	`DELETE FROM bank_item WHERE item_id = ANY($1)`,
+	`DELETE FROM inventory WHERE item_id = ANY($1)`,
+	`DELETE FROM item_price WHERE item_id = ANY($1)`,
+	`DELETE FROM item WHERE id = ANY($1)`,
+}
+
+// PurgeDeletedItems hard-deletes the items soft-deleted before the given time
+// together with the references to them, keeping purchase receipt grants, and
+// returns how many items were removed.
+func (r *pgRepository) PurgeDeletedItems(ctx context.Context, before time.Time) (int64, error) {
+	ctx = querylabel.With(ctx, "item", "PurgeDeletedItems")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, lockPurgeableItems, before)
+	if err != nil {
+		return 0, fmt.Errorf("locking deleted items: %w", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return 0, fmt.Errorf("scanning deleted item ids: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	for _, stmt := range purgeItemData {
+		if _, err = tx.Exec(ctx, stmt, ids); err != nil {
+			return 0, fmt.Errorf("purging deleted items: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return int64(len(ids)), nil
+}
+
+const itemExists = `
+SELECT EXISTS (SELECT 1 FROM item WHERE id = $1 AND deleted_at IS NULL)
+`
+
+// missOrConflict explains why a conditional write matched no row: nil if the
+// item does not exist, ErrVersionMismatch if it exists at another version.
+func (r *pgRepository) missOrConflict(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, itemExists, id).Scan(&exists); err != nil {
+		return fmt.Errorf("checking if item exists: %w", err)
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+const listItemPrices = `
+SELECT item_id, currency_code, amount FROM item_price WHERE item_id = ANY($1) ORDER BY currency_code
+`
+
+func (r *pgRepository) ListItemPrices(ctx context.Context, itemIDs []uuid.UUID) (map[uuid.UUID][]Price, error) {
+	ctx = querylabel.With(ctx, "item", "ListItemPrices")
+
+	rows, err := r.db.Query(ctx, listItemPrices, itemIDs)
+	if err != nil {
+		return nil, fmt.Errorf("querying for item prices: %w", err)
+	}
+	defer rows.Close()
+
+	prices := make(map[uuid.UUID][]Price)
+
+	for rows.Next() {
+		var id uuid.UUID
+		var p Price
+
+		if err := rows.Scan(&id, &p.Currency, &p.Amount); err != nil {
+			return nil, fmt.Errorf("scanning rows into price struct: %w", err)
+		}
+
+		prices[id] = append(prices[id], p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+const setItemPrice = `
+INSERT INTO item_price (item_id, currency_code, amount)
+VALUES ($1, $2, $3)
+ON CONFLICT (item_id, currency_code) DO UPDATE SET amount = EXCLUDED.amount
+`
+
+type SetItemPriceParams struct {
 	ID       uuid.UUID `json:"id"`
-	NewValue int32     `json:"new_value"`
+	Currency string    `json:"currency"`
+	Amount   int32     `json:"amount"`
 }
 
-func (r *pgRepository) UpdateItemValue(ctx context.Context, args UpdateItemValueParams) error {
+func (r *pgRepository) SetItemPrice(ctx context.Context, args SetItemPriceParams) error {
+	ctx = querylabel.With(ctx, "item", "SetItemPrice")
+
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, updateItemValue, args.ID, args.NewValue)
+	_, err = tx.Exec(ctx, setItemPrice, args.ID, args.Currency, args.Amount)
 	if err != nil {
-		return fmt.Errorf("updating item value: %w", err)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" && pgErr.ConstraintName == "item_price_currency_code_fkey" {
+			return ErrUnknownCurrency
+		}
+		return fmt.Errorf("setting item price: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, bumpItemVersion, args.ID); err != nil {
+		return fmt.Errorf("updating item version: %w", err)
 	}
 
 	if err = tx.Commit(ctx); err != nil {
@@ -161,20 +537,37 @@ func (r *pgRepository) UpdateItemValue(ctx context.Context, args UpdateItemValue
 	return nil
 }
 
-const deleteItemByID = `
-DELETE FROM item WHERE id = $1
+const bumpItemVersion = `
+UPDATE item SET version = version + 1 WHERE id = $1
 `
 
-func (r *pgRepository) DeleteItemByID(ctx context.Context, id uuid.UUID) error {
+const removeItemPrice = `
+DELETE FROM item_price WHERE item_id = $1 AND currency_code = $2
+`
+
+type RemoveItemPriceParams struct {
+	ID       uuid.UUID `json:"id"`
+	Currency string    `json:"currency"`
+}
+
+func (r *pgRepository) RemoveItemPrice(ctx context.Context, args RemoveItemPriceParams) error {
+	ctx = querylabel.With(ctx, "item", "RemoveItemPrice")
+
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, deleteItemByID, id)
+	tag, err := tx.Exec(ctx, removeItemPrice, args.ID, args.Currency)
 	if err != nil {
-		return fmt.Errorf("deleting item: %w", err)
+		return fmt.Errorf("removing item price: %w", err)
+	}
+
+	if tag.RowsAffected() > 0 {
+		if _, err = tx.Exec(ctx, bumpItemVersion, args.ID); err != nil {
+			return fmt.Errorf("updating item version: %w", err)
+		}
 	}
 
 	if err = tx.Commit(ctx); err != nil {
@@ -183,3 +576,18 @@ func (r *pgRepository) DeleteItemByID(ctx context.Context, id uuid.UUID) error {
 
 	return nil
 }
+
+const countItems = `
+SELECT count(*) FROM item WHERE deleted_at IS NULL
+`
+
+func (r *pgRepository) CountItems(ctx context.Context) (int64, error) {
+	ctx = querylabel.With(ctx, "item", "CountItems")
+
+	var n int64
+	if err := r.db.QueryRow(ctx, countItems).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting items: %w", err)
+	}
+
+	return n, nil
+}
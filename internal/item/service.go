@@ -2,26 +2,44 @@ package item
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/validate"
 )
 
 type ItemService struct {
-	repo ItemRepository
+	repo        ItemRepository
+	gracePeriod time.Duration
 }
 
-func NewItemService(repo ItemRepository) *ItemService {
-	return &ItemService{repo: repo}
+// NewItemService returns an ItemService. Deleted items can be restored for
+// gracePeriod, after which they are left to PurgeDeletedItems.
+func NewItemService(repo ItemRepository, gracePeriod time.Duration) *ItemService {
+	return &ItemService{repo: repo, gracePeriod: gracePeriod}
 }
 
+var ErrInvalidPrice = apperr.Invalid("price must be positive")
+
 func (s *ItemService) CreateItem(ctx context.Context, name string, value int32) (*Item, error) {
-	_, err := s.repo.GetItemByName(ctx, name)
-	if err == nil {
-		return nil, fmt.Errorf("item with name '%v' already exists", name)
+	args := CreateItemParams{Name: name, Value: value}
+	if err := validate.Struct(args).Err("item"); err != nil {
+		return nil, err
 	}
 
-	newItem, err := s.repo.CreateItem(ctx, CreateItemParams{Name: name, Value: value})
+	existing, err := s.repo.GetItemByName(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("getting item with name '%v': %w", name, err)
+	}
+	if existing != nil {
+		return nil, apperr.Conflict(fmt.Sprintf("item with name '%v' already exists", name))
+	}
+
+	newItem, err := s.repo.CreateItem(ctx, args)
 	if err != nil {
 		return nil, fmt.Errorf("creating new item: %w", err)
 	}
@@ -29,8 +47,17 @@ func (s *ItemService) CreateItem(ctx context.Context, name string, value int32)
 	return newItem, nil
 }
 
-func (s *ItemService) GetAllItems(ctx context.Context) ([]*Item, error) {
-	return s.repo.GetAllItems(ctx)
+func (s *ItemService) ListItems(ctx context.Context, args ListItemsParams) (*listing.Page[*Item], error) {
+	page, err := s.repo.ListItems(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("listing items: %w", err)
+	}
+
+	if err := s.attachPrices(ctx, page.Items...); err != nil {
+		return nil, err
+	}
+
+	return page, nil
 }
 
 func (s *ItemService) GetItemByID(ctx context.Context, id uuid.UUID) (*Item, error) {
@@ -38,33 +65,151 @@ func (s *ItemService) GetItemByID(ctx context.Context, id uuid.UUID) (*Item, err
 	if err != nil {
 		return nil, fmt.Errorf("getting item with id %v: %w", id, err)
 	}
+	if item == nil {
+		return nil, apperr.NotFound("item", "id", id)
+	}
+
+	if err := s.attachPrices(ctx, item); err != nil {
+		return nil, err
+	}
 
 	return item, nil
 }
 
+func (s *ItemService) attachPrices(ctx context.Context, items ...*Item) error {
+	ids := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+
+	prices, err := s.repo.ListItemPrices(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("getting item prices: %w", err)
+	}
+
+	for _, item := range items {
+		item.Prices = prices[item.ID]
+	}
+
+	return nil
+}
+
+func (s *ItemService) SetItemPrice(ctx context.Context, id uuid.UUID, currency string, amount int32) error {
+	if amount <= 0 {
+		return ErrInvalidPrice
+	}
+
+	if _, err := s.GetItemByID(ctx, id); err != nil {
+		return err
+	}
+
+	err := s.repo.SetItemPrice(ctx, SetItemPriceParams{ID: id, Currency: currency, Amount: amount})
+	if err != nil {
+		if errors.Is(err, ErrUnknownCurrency) {
+			return err
+		}
+		return fmt.Errorf("setting %v price for item with id %v: %w", currency, id, err)
+	}
+
+	return nil
+}
+
+func (s *ItemService) RemoveItemPrice(ctx context.Context, id uuid.UUID, currency string) error {
+	if _, err := s.GetItemByID(ctx, id); err != nil {
+		return err
+	}
+
+	err := s.repo.RemoveItemPrice(ctx, RemoveItemPriceParams{ID: id, Currency: currency})
+	if err != nil {
+		return fmt.Errorf("removing %v price for item with id %v: %w", currency, id, err)
+	}
+
+	return nil
+}
+
 func (s *ItemService) GetItemByName(ctx context.Context, name string) (*Item, error) {
 	item, err := s.repo.GetItemByName(ctx, name)
 	if err != nil {
 		return nil, fmt.Errorf("getting item with name '%v': %w", name, err)
 	}
+	if item == nil {
+		return nil, apperr.NotFound("item", "name", name)
+	}
 
 	return item, nil
 }
 
-func (s *ItemService) UpdateItemValue(ctx context.Context, id uuid.UUID, newValue int32) error {
-	err := s.repo.UpdateItemValue(ctx, UpdateItemValueParams{ID: id, NewValue: newValue})
+// UpdateItem applies a partial update to an item and returns the updated item.
+func (s *ItemService) UpdateItem(ctx context.Context, args UpdateItemParams) (*Item, error) {
+	if err := validate.Struct(args).Err("item"); err != nil {
+		return nil, err
+	}
+
+	i, err := s.repo.UpdateItem(ctx, args)
 	if err != nil {
-		return fmt.Errorf("updating value for item with id %v: %w", id, err)
+		return nil, fmt.Errorf("updating item with id %v: %w", args.ID, err)
+	}
+	if i == nil {
+		return nil, apperr.NotFound("item", "id", args.ID)
 	}
 
-	return nil
+	if err := s.attachPrices(ctx, i); err != nil {
+		return nil, err
+	}
+
+	return i, nil
 }
 
-func (s *ItemService) DeleteItemByID(ctx context.Context, id uuid.UUID) error {
-	err := s.repo.DeleteItemByID(ctx, id)
+// DeleteItemByID soft-deletes an item. A non-nil version makes the delete
+// conditional on the item still being at that version.
+func (s *ItemService) DeleteItemByID(ctx context.Context, id uuid.UUID, version *int32) error {
+	if _, err := s.GetItemByID(ctx, id); err != nil {
+		return err
+	}
+
+	err := s.repo.DeleteItemByID(ctx, DeleteItemParams{ID: id, Version: version})
 	if err != nil {
 		return fmt.Errorf("deleting item with id %v: %w", id, err)
 	}
 
 	return nil
 }
+
+// RestoreItem brings back a deleted item if it is still within the grace
+// period.
+func (s *ItemService) RestoreItem(ctx context.Context, id uuid.UUID) (*Item, error) {
+	i, err := s.repo.RestoreItem(ctx, RestoreItemParams{ID: id, GracePeriod: s.gracePeriod})
+	if err != nil {
+		return nil, fmt.Errorf("restoring item with id %v: %w", id, err)
+	}
+	if i == nil {
+		return nil, apperr.NotFound("deleted item", "id", id)
+	}
+
+	if err := s.attachPrices(ctx, i); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// PurgeDeletedItems permanently removes the items whose grace period has run
+// out, taking them out of every inventory, storage and shop bundle.
+func (s *ItemService) PurgeDeletedItems(ctx context.Context) (int64, error) {
+	n, err := s.repo.PurgeDeletedItems(ctx, time.Now().Add(-s.gracePeriod))
+	if err != nil {
+		return 0, fmt.Errorf("purging deleted items: %w", err)
+	}
+
+	return n, nil
+}
+
+// CountItems returns how many items exist, leaving out deleted ones.
+func (s *ItemService) CountItems(ctx context.Context) (int64, error) {
+	n, err := s.repo.CountItems(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("counting items: %w", err)
+	}
+
+	return n, nil
+}
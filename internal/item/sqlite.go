@@ -0,0 +1,464 @@
+package item
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+	"github.com/hossokawa/go-nethttp-example/internal/sqlite"
+)
+
+type sqliteRepository struct {
+	db *sqlite.DB
+}
+
+// NewSQLiteRepository returns an ItemRepository that keeps items in an SQLite
+// database. It behaves like the Postgres repository, down to the errors it
+// returns.
+func NewSQLiteRepository(db *sqlite.DB) ItemRepository {
+	return &sqliteRepository{db: db}
+}
+
+// scanSQLiteItem reads an item selected as id, name, value, stats, version,
+// decoding the stats stored as JSON text.
+func scanSQLiteItem(row interface{ Scan(...any) error }) (*Item, error) {
+	var i Item
+	var stats string
+
+	if err := row.Scan(&i.ID, &i.Name, &i.Value, &stats, &i.Version); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(stats), &i.Stats); err != nil {
+		return nil, fmt.Errorf("decoding item stats: %w", err)
+	}
+	if i.Stats == nil {
+		i.Stats = make(map[string]int32)
+	}
+
+	return &i, nil
+}
+
+const sqliteCreateItem = `
+INSERT INTO item (id, name, value)
+VALUES (?1, ?2, ?3)
+RETURNING id, name, value, stats, version
+`
+
+func (r *sqliteRepository) CreateItem(ctx context.Context, args CreateItemParams) (*Item, error) {
+	ctx = querylabel.With(ctx, "item", "CreateItem")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	i, err := scanSQLiteItem(tx.QueryRow(ctx, sqliteCreateItem, uuid.New(), args.Name, args.Value))
+	if err != nil {
+		if sqlite.IsUniqueViolation(err) {
+			return nil, ErrItemNameInUse
+		}
+		return nil, fmt.Errorf("scanning row into item struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return i, nil
+}
+
+const sqliteListItems = `
+SELECT id, name, value, stats, version FROM item`
+
+func (r *sqliteRepository) ListItems(ctx context.Context, args ListItemsParams) (*listing.Page[*Item], error) {
+	ctx = querylabel.With(ctx, "item", "ListItems")
+
+	sort, ok := itemSortColumns[args.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field '%v'", args.Sort)
+	}
+
+	q := listing.Query{Dialect: sqlite.Dialect}
+	q.Where("deleted_at IS NULL")
+	if args.NamePrefix != "" {
+		q.Where("name LIKE " + q.Arg(listing.LikePrefix(args.NamePrefix)) + ` ESCAPE '\'`)
+	}
+	if args.MinValue != nil {
+		q.Where("value >= " + q.Arg(*args.MinValue))
+	}
+	if args.MaxValue != nil {
+		q.Where("value <= " + q.Arg(*args.MaxValue))
+	}
+	query := q.Build(sqliteListItems, args.Params, sort, itemSortColumns["id"])
+
+	rows, err := r.db.Query(ctx, query, q.Args()...)
+	if err != nil {
+		return nil, fmt.Errorf("querying for items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*Item
+
+	for rows.Next() {
+		i, err := scanSQLiteItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning rows into item struct: %w", err)
+		}
+
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return listing.NewPage(items, args.Params, ItemSortKey(args.Sort)), nil
+}
+
+const sqliteGetItemByID = `
+SELECT id, name, value, stats, version FROM item WHERE id = ?1 AND deleted_at IS NULL
+`
+
+func (r *sqliteRepository) GetItemByID(ctx context.Context, id uuid.UUID) (*Item, error) {
+	ctx = querylabel.With(ctx, "item", "GetItemByID")
+
+	i, err := scanSQLiteItem(r.db.QueryRow(ctx, sqliteGetItemByID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into item struct: %w", err)
+	}
+
+	return i, nil
+}
+
+const sqliteGetItemByName = `
+SELECT id, name, value, stats, version FROM item WHERE name = ?1 AND deleted_at IS NULL
+`
+
+func (r *sqliteRepository) GetItemByName(ctx context.Context, name string) (*Item, error) {
+	ctx = querylabel.With(ctx, "item", "GetItemByName")
+
+	i, err := scanSQLiteItem(r.db.QueryRow(ctx, sqliteGetItemByName, name))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into item struct: %w", err)
+	}
+
+	return i, nil
+}
+
+const sqliteUpdateItem = `
+UPDATE item SET
+  name = COALESCE(?2, name),
+  value = COALESCE(?3, value),
+  version = version + 1
+WHERE id = ?1 AND deleted_at IS NULL AND (?4 IS NULL OR version = ?4)
+RETURNING id, name, value, stats, version
+`
+
+func (r *sqliteRepository) UpdateItem(ctx context.Context, args UpdateItemParams) (*Item, error) {
+	ctx = querylabel.With(ctx, "item", "UpdateItem")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	i, err := scanSQLiteItem(tx.QueryRow(ctx, sqliteUpdateItem, args.ID, args.Name, args.Value, args.Version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, r.missOrConflict(ctx, tx, args.ID)
+		}
+		if sqlite.IsUniqueViolation(err) {
+			return nil, ErrItemNameInUse
+		}
+		return nil, fmt.Errorf("scanning row into item struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return i, nil
+}
+
+const sqliteDeleteItemByID = `
+UPDATE item SET deleted_at = ?3, version = version + 1
+WHERE id = ?1 AND deleted_at IS NULL AND (?2 IS NULL OR version = ?2)
+`
+
+// DeleteItemByID soft-deletes an item: it is hidden from every other query,
+// including inventories, until it is restored or purged.
+func (r *sqliteRepository) DeleteItemByID(ctx context.Context, args DeleteItemParams) error {
+	ctx = querylabel.With(ctx, "item", "DeleteItemByID")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(ctx, sqliteDeleteItemByID, args.ID, args.Version, sqlite.Timestamp(time.Now()))
+	if err != nil {
+		return fmt.Errorf("deleting item: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return r.missOrConflict(ctx, tx, args.ID)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+const sqliteGetDeletedAt = `
+SELECT deleted_at FROM item WHERE id = ?1
+`
+
+const sqliteRestoreItem = `
+UPDATE item SET deleted_at = NULL, version = version + 1
+WHERE id = ?1
+RETURNING id, name, value, stats, version
+`
+
+// RestoreItem undoes a soft delete made less than GracePeriod ago. It returns
+// nil if there is no deleted item with the given id.
+func (r *sqliteRepository) RestoreItem(ctx context.Context, args RestoreItemParams) (*Item, error) {
+	ctx = querylabel.With(ctx, "item", "RestoreItem")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var deletedAt *time.Time
+
+	err = tx.QueryRow(ctx, sqliteGetDeletedAt, args.ID).Scan(sqlite.NullTime(&deletedAt))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading item for restore: %w", err)
+	}
+	if deletedAt == nil {
+		return nil, nil
+	}
+	if time.Since(*deletedAt) > args.GracePeriod {
+		return nil, ErrRestoreExpired
+	}
+
+	i, err := scanSQLiteItem(tx.QueryRow(ctx, sqliteRestoreItem, args.ID))
+	if err != nil {
+		return nil, fmt.Errorf("scanning row into item struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return i, nil
+}
+
+// sqlitePurgeItemData lists the statements that remove every reference to
+// the items deleted before ?1, and then the items themselves.
+var sqlitePurgeItemData = []string{
+	`DELETE FROM inventory WHERE item_id IN (SELECT id FROM item WHERE deleted_at < ?1)`,
+	`DELETE FROM item_price WHERE item_id IN (SELECT id FROM item WHERE deleted_at < ?1)`,
+	`DELETE FROM item WHERE deleted_at < ?1`,
+}
+
+// PurgeDeletedItems hard-deletes the items soft-deleted before the given time
+// together with every reference to them, and returns how many items were
+// removed.
+func (r *sqliteRepository) PurgeDeletedItems(ctx context.Context, before time.Time) (int64, error) {
+	ctx = querylabel.With(ctx, "item", "PurgeDeletedItems")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var n int64
+	for _, stmt := range sqlitePurgeItemData {
+		res, err := tx.Exec(ctx, stmt, sqlite.Timestamp(before))
+		if err != nil {
+			return 0, fmt.Errorf("purging deleted items: %w", err)
+		}
+		n, _ = res.RowsAffected()
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return n, nil
+}
+
+const sqliteItemExists = `
+SELECT EXISTS (SELECT 1 FROM item WHERE id = ?1 AND deleted_at IS NULL)
+`
+
+// missOrConflict explains why a conditional write matched no row: nil if the
+// item does not exist, ErrVersionMismatch if it exists at another version.
+func (r *sqliteRepository) missOrConflict(ctx context.Context, tx *sqlite.Tx, id uuid.UUID) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, sqliteItemExists, id).Scan(&exists); err != nil {
+		return fmt.Errorf("checking if item exists: %w", err)
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+const sqliteListItemPrices = `
+SELECT item_id, currency_code, amount FROM item_price WHERE item_id IN (%s) ORDER BY currency_code
+`
+
+func (r *sqliteRepository) ListItemPrices(ctx context.Context, itemIDs []uuid.UUID) (map[uuid.UUID][]Price, error) {
+	ctx = querylabel.With(ctx, "item", "ListItemPrices")
+
+	prices := make(map[uuid.UUID][]Price)
+	if len(itemIDs) == 0 {
+		return prices, nil
+	}
+
+	args := make([]any, len(itemIDs))
+	for n, id := range itemIDs {
+		args[n] = id
+	}
+	query := fmt.Sprintf(sqliteListItemPrices, strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", "))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying for item prices: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var p Price
+
+		if err := rows.Scan(&id, &p.Currency, &p.Amount); err != nil {
+			return nil, fmt.Errorf("scanning rows into price struct: %w", err)
+		}
+
+		prices[id] = append(prices[id], p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+const sqliteCurrencyExists = `
+SELECT EXISTS (SELECT 1 FROM currency WHERE code = ?1)
+`
+
+const sqliteSetItemPrice = `
+INSERT INTO item_price (item_id, currency_code, amount)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (item_id, currency_code) DO UPDATE SET amount = excluded.amount
+`
+
+const sqliteBumpItemVersion = `
+UPDATE item SET version = version + 1 WHERE id = ?1
+`
+
+// SetItemPrice checks the currency before writing: SQLite does not say which
+// foreign key a row violates, so the error cannot tell an unknown currency
+// from a missing item.
+func (r *sqliteRepository) SetItemPrice(ctx context.Context, args SetItemPriceParams) error {
+	ctx = querylabel.With(ctx, "item", "SetItemPrice")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var known bool
+	if err = tx.QueryRow(ctx, sqliteCurrencyExists, args.Currency).Scan(&known); err != nil {
+		return fmt.Errorf("checking if currency exists: %w", err)
+	}
+	if !known {
+		return ErrUnknownCurrency
+	}
+
+	if _, err = tx.Exec(ctx, sqliteSetItemPrice, args.ID, args.Currency, args.Amount); err != nil {
+		return fmt.Errorf("setting item price: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, sqliteBumpItemVersion, args.ID); err != nil {
+		return fmt.Errorf("updating item version: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+const sqliteRemoveItemPrice = `
+DELETE FROM item_price WHERE item_id = ?1 AND currency_code = ?2
+`
+
+func (r *sqliteRepository) RemoveItemPrice(ctx context.Context, args RemoveItemPriceParams) error {
+	ctx = querylabel.With(ctx, "item", "RemoveItemPrice")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(ctx, sqliteRemoveItemPrice, args.ID, args.Currency)
+	if err != nil {
+		return fmt.Errorf("removing item price: %w", err)
+	}
+
+	if n, _ := res.RowsAffected(); n > 0 {
+		if _, err = tx.Exec(ctx, sqliteBumpItemVersion, args.ID); err != nil {
+			return fmt.Errorf("updating item version: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+const sqliteCountItems = `
+SELECT count(*) FROM item WHERE deleted_at IS NULL
+`
+
+func (r *sqliteRepository) CountItems(ctx context.Context) (int64, error) {
+	ctx = querylabel.With(ctx, "item", "CountItems")
+
+	var n int64
+	if err := r.db.QueryRow(ctx, sqliteCountItems).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting items: %w", err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,131 @@
+// Package lifecycle starts and stops the long-running parts of the
+// application, such as the HTTP server and the background workers, in a fixed
+// order.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Hook is a component's start and stop functions. Either may be nil.
+type Hook struct {
+	Name string
+	// Start must not block: long-running work goes in a goroutine that Stop
+	// ends.
+	Start func(ctx context.Context) error
+	// Stop should return once the component has stopped, or when ctx is
+	// done.
+	Stop func(ctx context.Context) error
+}
+
+// Lifecycle starts hooks in the order they were appended and stops them in
+// reverse, so a component is stopped before the ones it was started after,
+// and that it may depend on.
+type Lifecycle struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+	stopped bool
+}
+
+func New() *Lifecycle {
+	return &Lifecycle{}
+}
+
+// Append registers a hook. Hooks appended after Start are not started.
+func (l *Lifecycle) Append(h Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hooks = append(l.hooks, h)
+}
+
+// Start runs every start hook. If one fails, the hooks already started are
+// stopped and the error is returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, h := range l.hooks[l.started:] {
+		if h.Start != nil {
+			if err := h.Start(ctx); err != nil {
+				err = fmt.Errorf("starting %s: %w", h.Name, err)
+				return errors.Join(err, l.stop(ctx))
+			}
+		}
+		l.started++
+	}
+
+	return nil
+}
+
+// Stop runs the stop hooks of the started components, newest first. Every
+// hook runs even if an earlier one fails; the errors are returned together.
+// Calling Stop again does nothing.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stopped {
+		return nil
+	}
+	l.stopped = true
+
+	return l.stop(ctx)
+}
+
+func (l *Lifecycle) stop(ctx context.Context) error {
+	var errs []error
+
+	for ; l.started > 0; l.started-- {
+		h := l.hooks[l.started-1]
+		if h.Stop == nil {
+			continue
+		}
+
+		log.Printf("Stopping %s", h.Name)
+
+		if err := h.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stopping %s: %w", h.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Worker returns a hook that runs fn in a goroutine. Stop cancels the context
+// fn was given and waits for it to return.
+func Worker(name string, fn func(ctx context.Context)) Hook {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+
+	return Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			// The worker outlives the start context, but keeps its values.
+			var workerCtx context.Context
+			workerCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+			go func() {
+				defer close(done)
+				fn(workerCtx)
+			}()
+
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
@@ -0,0 +1,181 @@
+package listing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Type is the type of the values of a sortable field. It names the Postgres
+// type cursor values are cast to.
+type Type string
+
+const (
+	Int       Type = "int"
+	Text      Type = "text"
+	Timestamp Type = "timestamptz"
+	UUID      Type = "uuid"
+)
+
+// Field is a field a list can be sorted by.
+type Field struct {
+	Name string
+	Type Type
+}
+
+// Params is the part of a list request shared by every list endpoint. Filters
+// are specific to each resource and live next to it.
+type Params struct {
+	Limit      int32
+	Sort       string
+	Descending bool
+	Cursor     *Cursor
+}
+
+// Cursor marks the last row of a page. It records the sort it was created for
+// so it cannot be replayed against a different ordering.
+type Cursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	ID         string `json:"i"`
+}
+
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func EncodeCursor(c Cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, apperr.Invalid("invalid cursor")
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, apperr.Invalid("invalid cursor")
+	}
+
+	return &c, nil
+}
+
+// Parse reads limit, cursor and sort from the query string. Sort takes a
+// field name, optionally prefixed with "-" for descending order, and must be
+// one of sortable, which must include "id". The values of the cursor must be
+// of the types of the sort field and of id, so a tampered cursor is rejected
+// here rather than failing the query.
+func Parse(values url.Values, sortable []Field, defaultSort string) (Params, error) {
+	p := Params{Limit: DefaultLimit, Sort: defaultSort}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return Params{}, apperr.Invalid(fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+		}
+		p.Limit = int32(limit)
+	}
+
+	if v := values.Get("sort"); v != "" {
+		p.Descending = strings.HasPrefix(v, "-")
+		p.Sort = strings.TrimPrefix(v, "-")
+		if !slices.ContainsFunc(sortable, func(f Field) bool { return f.Name == p.Sort }) {
+			names := make([]string, len(sortable))
+			for i, f := range sortable {
+				names[i] = f.Name
+			}
+			return Params{}, apperr.Invalid(fmt.Sprintf("cannot sort by '%v', expected one of %v", p.Sort, strings.Join(names, ", ")))
+		}
+	}
+
+	if v := values.Get("cursor"); v != "" {
+		c, err := DecodeCursor(v)
+		if err != nil {
+			return Params{}, err
+		}
+		if c.Sort != p.Sort || c.Descending != p.Descending {
+			return Params{}, apperr.Invalid("cursor was created for a different sort order")
+		}
+		if !valid(c.Value, typeOf(sortable, p.Sort)) || !valid(c.ID, typeOf(sortable, "id")) {
+			return Params{}, apperr.Invalid("invalid cursor")
+		}
+		p.Cursor = c
+	}
+
+	return p, nil
+}
+
+func typeOf(fields []Field, name string) Type {
+	for _, f := range fields {
+		if f.Name == name {
+			return f.Type
+		}
+	}
+	return Text
+}
+
+// valid reports whether v is a value of type typ.
+func valid(v string, typ Type) bool {
+	var err error
+	switch typ {
+	case Int:
+		_, err = strconv.ParseInt(v, 10, 32)
+	case Timestamp:
+		_, err = time.Parse(time.RFC3339Nano, v)
+	case UUID:
+		_, err = uuid.Parse(v)
+	}
+	return err == nil
+}
+
+// ParseInt32 reads an optional integer filter.
+func ParseInt32(values url.Values, key string) (*int32, error) {
+	v := values.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return nil, apperr.Invalid(fmt.Sprintf("%v must be an integer", key))
+	}
+
+	n32 := int32(n)
+	return &n32, nil
+}
+
+// NewPage builds a page from rows fetched with a limit of Limit+1: the extra
+// row only signals that another page exists. key returns the sort value and
+// id of a row for the next cursor.
+func NewPage[T any](rows []T, p Params, key func(T) (string, string)) *Page[T] {
+	page := &Page[T]{Items: rows}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+
+	if len(rows) > int(p.Limit) {
+		page.Items = rows[:p.Limit]
+		value, id := key(page.Items[len(page.Items)-1])
+		page.NextCursor = EncodeCursor(Cursor{Sort: p.Sort, Descending: p.Descending, Value: value, ID: id})
+	}
+
+	return page
+}
@@ -0,0 +1,177 @@
+package listing_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+)
+
+var playerFields = []listing.Field{
+	{Name: "id", Type: listing.Int},
+	{Name: "username", Type: listing.Text},
+	{Name: "level", Type: listing.Int},
+	{Name: "created_at", Type: listing.Timestamp},
+}
+
+var itemFields = []listing.Field{
+	{Name: "id", Type: listing.UUID},
+	{Name: "name", Type: listing.Text},
+}
+
+func TestParse(t *testing.T) {
+	levelCursor := listing.EncodeCursor(listing.Cursor{Sort: "level", Value: "12", ID: "7"})
+	descCursor := listing.EncodeCursor(listing.Cursor{Sort: "level", Descending: true, Value: "12", ID: "7"})
+
+	tests := []struct {
+		name  string
+		query string
+		want  listing.Params
+	}{
+		{"defaults", "", listing.Params{Limit: listing.DefaultLimit, Sort: "id"}},
+		{"limit", "limit=5", listing.Params{Limit: 5, Sort: "id"}},
+		{"smallest limit", "limit=1", listing.Params{Limit: 1, Sort: "id"}},
+		{"largest limit", "limit=100", listing.Params{Limit: listing.MaxLimit, Sort: "id"}},
+		{"ascending", "sort=level", listing.Params{Limit: listing.DefaultLimit, Sort: "level"}},
+		{"descending", "sort=-level", listing.Params{Limit: listing.DefaultLimit, Sort: "level", Descending: true}},
+		{"cursor", "sort=level&cursor=" + levelCursor, listing.Params{
+			Limit:  listing.DefaultLimit,
+			Sort:   "level",
+			Cursor: &listing.Cursor{Sort: "level", Value: "12", ID: "7"},
+		}},
+		{"descending cursor", "sort=-level&cursor=" + descCursor, listing.Params{
+			Limit:      listing.DefaultLimit,
+			Sort:       "level",
+			Descending: true,
+			Cursor:     &listing.Cursor{Sort: "level", Descending: true, Value: "12", ID: "7"},
+		}},
+		{"timestamp cursor", "sort=created_at&cursor=" + listing.EncodeCursor(listing.Cursor{Sort: "created_at", Value: "2026-01-02T03:04:05.123456Z", ID: "1"}), listing.Params{
+			Limit:  listing.DefaultLimit,
+			Sort:   "created_at",
+			Cursor: &listing.Cursor{Sort: "created_at", Value: "2026-01-02T03:04:05.123456Z", ID: "1"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+
+			got, err := listing.Parse(values, playerFields, "id")
+			if err != nil {
+				t.Fatalf("Parse(%q) = %v", tt.query, err)
+			}
+			if got.Limit != tt.want.Limit || got.Sort != tt.want.Sort || got.Descending != tt.want.Descending {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+			if (got.Cursor == nil) != (tt.want.Cursor == nil) || got.Cursor != nil && *got.Cursor != *tt.want.Cursor {
+				t.Errorf("Parse(%q) cursor = %+v, want %+v", tt.query, got.Cursor, tt.want.Cursor)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"zero limit", "limit=0"},
+		{"negative limit", "limit=-1"},
+		{"limit above the maximum", "limit=101"},
+		{"limit not a number", "limit=ten"},
+		{"unknown sort", "sort=gold"},
+		{"unknown descending sort", "sort=-gold"},
+		{"cursor not base64", "cursor=%25%25"},
+		{"cursor not json", "cursor=" + raw("level:12")},
+		{"cursor of another sort", "sort=username&cursor=" + listing.EncodeCursor(listing.Cursor{Sort: "level", Value: "12", ID: "7"})},
+		{"cursor of the other direction", "sort=-level&cursor=" + listing.EncodeCursor(listing.Cursor{Sort: "level", Value: "12", ID: "7"})},
+		{"cursor without a sort, on the default", "cursor=" + raw(`{"v":"1","i":"1"}`)},
+		{"tampered value", "sort=level&cursor=" + listing.EncodeCursor(listing.Cursor{Sort: "level", Value: "12; DROP TABLE player", ID: "7"})},
+		{"value out of range", "sort=level&cursor=" + listing.EncodeCursor(listing.Cursor{Sort: "level", Value: "4294967296", ID: "7"})},
+		{"tampered id", "sort=level&cursor=" + listing.EncodeCursor(listing.Cursor{Sort: "level", Value: "12", ID: "seven"})},
+		{"bad timestamp", "sort=created_at&cursor=" + listing.EncodeCursor(listing.Cursor{Sort: "created_at", Value: "yesterday", ID: "1"})},
+		{"cursor of another resource", "cursor=" + listing.EncodeCursor(listing.Cursor{Sort: "id", Value: "8c4f2b1e-1f0a-4c43-9d5b-3f7d1c2e9a10", ID: "8c4f2b1e-1f0a-4c43-9d5b-3f7d1c2e9a10"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = listing.Parse(values, playerFields, "id")
+			var appErr *apperr.Error
+			if !errors.As(err, &appErr) || appErr.Kind != apperr.KindInvalid {
+				t.Errorf("Parse(%q) = %v, want an invalid argument error", tt.query, err)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := listing.Cursor{Sort: "name", Descending: true, Value: "Sword of \"Ash\" & Ember", ID: "8c4f2b1e-1f0a-4c43-9d5b-3f7d1c2e9a10"}
+
+	got, err := listing.DecodeCursor(listing.EncodeCursor(c))
+	if err != nil {
+		t.Fatalf("DecodeCursor: %v", err)
+	}
+	if *got != c {
+		t.Errorf("DecodeCursor = %+v, want %+v", *got, c)
+	}
+
+	values := url.Values{"sort": {"-name"}, "cursor": {listing.EncodeCursor(c)}}
+	if _, err := listing.Parse(values, itemFields, "name"); err != nil {
+		t.Errorf("Parse of an item cursor = %v", err)
+	}
+}
+
+func TestParseInt32(t *testing.T) {
+	tests := []struct {
+		value string
+		want  *int32
+		ok    bool
+	}{
+		{"", nil, true},
+		{"42", ptr(int32(42)), true},
+		{"-3", ptr(int32(-3)), true},
+		{"2147483648", nil, false},
+		{"4.5", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, err := listing.ParseInt32(url.Values{"level": {tt.value}}, "level")
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseInt32(%q) error = %v, want ok %v", tt.value, err, tt.ok)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("ParseInt32(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLikePrefix(t *testing.T) {
+	tests := map[string]string{
+		"ann":   "ann%",
+		"50%":   `50\%%`,
+		"a_b":   `a\_b%`,
+		`back\`: `back\\%`,
+		"":      "%",
+	}
+
+	for prefix, want := range tests {
+		if got := listing.LikePrefix(prefix); got != want {
+			t.Errorf("LikePrefix(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,80 @@
+package listing
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Paginate is the in-memory counterpart of Query.Build: it orders rows by
+// their key, drops those up to the cursor and returns a single page. Key
+// values are compared as the column types say, like the casts in the SQL
+// keyset condition.
+func Paginate[T any](rows []T, p Params, sort, id Column, key func(T) (string, string)) (*Page[T], error) {
+	var cursorErr error
+	compare := func(av, ai, bv, bi string) int {
+		c, err := compareAs(sort.Type, av, bv)
+		if err == nil && c == 0 {
+			c, err = compareAs(id.Type, ai, bi)
+		}
+		if err != nil {
+			cursorErr = err
+		}
+		if p.Descending {
+			return -c
+		}
+		return c
+	}
+
+	rows = slices.Clone(rows)
+	slices.SortFunc(rows, func(a, b T) int {
+		av, ai := key(a)
+		bv, bi := key(b)
+		return compare(av, ai, bv, bi)
+	})
+
+	if p.Cursor != nil {
+		rows = slices.DeleteFunc(rows, func(row T) bool {
+			v, i := key(row)
+			return compare(v, i, p.Cursor.Value, p.Cursor.ID) <= 0
+		})
+	}
+	if cursorErr != nil {
+		return nil, cursorErr
+	}
+
+	if len(rows) > int(p.Limit)+1 {
+		rows = rows[:p.Limit+1]
+	}
+
+	return NewPage(rows, p, key), nil
+}
+
+func compareAs(typ Type, a, b string) (int, error) {
+	switch typ {
+	case Int:
+		x, err := strconv.ParseInt(a, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		y, err := strconv.ParseInt(b, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return cmp.Compare(x, y), nil
+	case Timestamp:
+		x, err := time.Parse(time.RFC3339Nano, a)
+		if err != nil {
+			return 0, err
+		}
+		y, err := time.Parse(time.RFC3339Nano, b)
+		if err != nil {
+			return 0, err
+		}
+		return x.Compare(y), nil
+	default:
+		return strings.Compare(a, b), nil
+	}
+}
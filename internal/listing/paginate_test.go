@@ -0,0 +1,167 @@
+package listing_test
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+)
+
+type row struct {
+	id    int
+	level int
+}
+
+func rowKey(r row) (string, string) {
+	return strconv.Itoa(r.level), strconv.Itoa(r.id)
+}
+
+var (
+	levelColumn = listing.Column{Expr: "level", Type: listing.Int}
+	idColumn    = listing.Column{Expr: "id", Type: listing.Int}
+)
+
+// walk pages through rows with the given limit and order, following the
+// cursors the way a client would, and returns the ids in the order seen.
+func walk(t *testing.T, rows []row, limit int32, descending bool) []int {
+	t.Helper()
+
+	var ids []int
+	p := listing.Params{Limit: limit, Sort: "level", Descending: descending}
+
+	for range len(rows) + 1 {
+		page, err := listing.Paginate(rows, p, levelColumn, idColumn, rowKey)
+		if err != nil {
+			t.Fatalf("Paginate: %v", err)
+		}
+		if len(page.Items) > int(limit) {
+			t.Fatalf("page of %d rows, limit %d", len(page.Items), limit)
+		}
+		if len(page.Items) == 0 {
+			t.Fatalf("empty page after ids %v; the previous page should not have had a cursor", ids)
+		}
+		for _, r := range page.Items {
+			ids = append(ids, r.id)
+		}
+
+		if page.NextCursor == "" {
+			return ids
+		}
+		c, err := listing.DecodeCursor(page.NextCursor)
+		if err != nil {
+			t.Fatalf("DecodeCursor: %v", err)
+		}
+		p.Cursor = c
+	}
+
+	t.Fatalf("pagination did not end after %d pages", len(rows)+1)
+	return nil
+}
+
+func TestPaginate(t *testing.T) {
+	// Levels tie across page boundaries, so the pages only line up if the
+	// id breaks the ties. Levels 9 and 10 catch a string comparison.
+	rows := []row{
+		{id: 4, level: 10}, {id: 1, level: 9}, {id: 7, level: 9}, {id: 2, level: 9},
+		{id: 10, level: 2}, {id: 3, level: 10}, {id: 5, level: 9}, {id: 6, level: 2},
+	}
+	ascending := []int{6, 10, 1, 2, 5, 7, 3, 4}
+	descending := slices.Clone(ascending)
+	slices.Reverse(descending)
+
+	for _, limit := range []int32{1, 2, 3, 4, 7, 8, 9, 100} {
+		if got := walk(t, rows, limit, false); !slices.Equal(got, ascending) {
+			t.Errorf("limit %d ascending: %v, want %v", limit, got, ascending)
+		}
+		if got := walk(t, rows, limit, true); !slices.Equal(got, descending) {
+			t.Errorf("limit %d descending: %v, want %v", limit, got, descending)
+		}
+	}
+}
+
+func TestPaginateLastPage(t *testing.T) {
+	rows := []row{{id: 1, level: 1}, {id: 2, level: 2}, {id: 3, level: 3}, {id: 4, level: 4}}
+	p := listing.Params{Limit: 2, Sort: "level"}
+
+	page, err := listing.Paginate(rows, p, levelColumn, idColumn, rowKey)
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("first of two full pages has no cursor")
+	}
+
+	p.Cursor, _ = listing.DecodeCursor(page.NextCursor)
+	page, err = listing.Paginate(rows, p, levelColumn, idColumn, rowKey)
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	if len(page.Items) != 2 || page.NextCursor != "" {
+		t.Errorf("last page = %v with cursor %q, want two rows and no cursor", page.Items, page.NextCursor)
+	}
+
+	// A cursor past the last row, such as one whose row was deleted since,
+	// gives an empty page rather than a nil one.
+	p.Cursor = &listing.Cursor{Sort: "level", Value: "5", ID: "0"}
+	page, err = listing.Paginate(rows, p, levelColumn, idColumn, rowKey)
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	if page.Items == nil || len(page.Items) != 0 || page.NextCursor != "" {
+		t.Errorf("page past the end = %#v, %q; want an empty page", page.Items, page.NextCursor)
+	}
+
+	page, err = listing.Paginate([]row(nil), listing.Params{Limit: 2, Sort: "level"}, levelColumn, idColumn, rowKey)
+	if err != nil || page.Items == nil || len(page.Items) != 0 {
+		t.Errorf("page of no rows = %#v, %v; want an empty page", page, err)
+	}
+}
+
+func TestPaginateDoesNotReorderInput(t *testing.T) {
+	rows := []row{{id: 2, level: 1}, {id: 1, level: 1}}
+
+	if _, err := listing.Paginate(rows, listing.Params{Limit: 10, Sort: "level"}, levelColumn, idColumn, rowKey); err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+	if rows[0].id != 2 {
+		t.Errorf("Paginate sorted the caller's rows: %v", rows)
+	}
+}
+
+func TestPaginateRejectsMalformedCursor(t *testing.T) {
+	rows := []row{{id: 1, level: 1}}
+	p := listing.Params{Limit: 10, Sort: "level", Cursor: &listing.Cursor{Sort: "level", Value: "one", ID: "1"}}
+
+	if _, err := listing.Paginate(rows, p, levelColumn, idColumn, rowKey); err == nil {
+		t.Error("Paginate with a cursor value that is not an int succeeded")
+	}
+}
+
+func TestPaginateTimestamps(t *testing.T) {
+	type event struct {
+		id int
+		at string
+	}
+	// Timestamps with different precision do not sort as strings.
+	rows := []event{
+		{id: 1, at: "2026-01-01T10:00:00.5Z"},
+		{id: 2, at: "2026-01-01T10:00:00Z"},
+		{id: 3, at: "2026-01-01T11:00:00+02:00"},
+	}
+	key := func(e event) (string, string) { return e.at, strconv.Itoa(e.id) }
+	at := listing.Column{Expr: "at", Type: listing.Timestamp}
+
+	page, err := listing.Paginate(rows, listing.Params{Limit: 10, Sort: "at"}, at, idColumn, key)
+	if err != nil {
+		t.Fatalf("Paginate: %v", err)
+	}
+
+	var ids []int
+	for _, e := range page.Items {
+		ids = append(ids, e.id)
+	}
+	if want := []int{3, 2, 1}; !slices.Equal(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+}
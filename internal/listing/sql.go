@@ -0,0 +1,107 @@
+package listing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Column maps a sortable field to its SQL expression and the type of its
+// values.
+type Column struct {
+	Expr string
+	Type Type
+}
+
+// Dialect is the SQL flavour a Query is written in. The database packages
+// provide theirs, so that this package depends on none of them.
+type Dialect interface {
+	// Placeholder returns the placeholder of the nth argument, counting
+	// from 1.
+	Placeholder(n int) string
+	// CursorValue adds the cursor value v, which Parse checked is of type
+	// typ, to the arguments of q and returns the expression standing for it.
+	CursorValue(q *Query, v string, typ Type) string
+}
+
+// Postgres is the dialect of Postgres, which casts cursor values to the
+// type of their column in the query.
+var Postgres Dialect = postgres{}
+
+type postgres struct{}
+
+func (postgres) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (postgres) CursorValue(q *Query, v string, typ Type) string {
+	return q.Arg(v) + "::" + string(typ)
+}
+
+// Query accumulates the WHERE conditions and arguments of a list query.
+// Conditions reference arguments through the placeholders returned by Arg.
+// The zero Query is written for Postgres.
+type Query struct {
+	Dialect Dialect
+	conds   []string
+	args    []any
+}
+
+func (q *Query) Arg(v any) string {
+	q.args = append(q.args, v)
+	if q.Dialect == nil {
+		return Postgres.Placeholder(len(q.args))
+	}
+	return q.Dialect.Placeholder(len(q.args))
+}
+
+func (q *Query) Where(cond string) {
+	q.conds = append(q.conds, cond)
+}
+
+func (q *Query) Args() []any {
+	return q.args
+}
+
+// Build appends the filters, keyset condition, ordering and limit to base.
+// Rows are ordered by the sort column and then by id so that the cursor
+// always points at a single row.
+func (q *Query) Build(base string, p Params, sort, id Column) string {
+	if p.Cursor != nil {
+		op := ">"
+		if p.Descending {
+			op = "<"
+		}
+		dialect := q.Dialect
+		if dialect == nil {
+			dialect = Postgres
+		}
+		q.Where(fmt.Sprintf("(%s, %s) %s (%s, %s)",
+			sort.Expr, id.Expr, op,
+			dialect.CursorValue(q, p.Cursor.Value, sort.Type),
+			dialect.CursorValue(q, p.Cursor.ID, id.Type),
+		))
+	}
+
+	var b strings.Builder
+	b.WriteString(base)
+
+	if len(q.conds) > 0 {
+		b.WriteString("\nWHERE ")
+		b.WriteString(strings.Join(q.conds, "\nAND "))
+	}
+
+	dir := "ASC"
+	if p.Descending {
+		dir = "DESC"
+	}
+	fmt.Fprintf(&b, "\nORDER BY %s %s, %s %s", sort.Expr, dir, id.Expr, dir)
+	fmt.Fprintf(&b, "\nLIMIT %s\n", q.Arg(p.Limit+1))
+
+	return b.String()
+}
+
+// LikePrefix escapes prefix for use as the pattern of a LIKE prefix match.
+func LikePrefix(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(prefix) + "%"
+}
@@ -0,0 +1,88 @@
+package listing_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/sqlite"
+)
+
+const base = "SELECT id, level FROM player"
+
+func TestBuildPostgres(t *testing.T) {
+	tests := []struct {
+		name  string
+		p     listing.Params
+		where func(q *listing.Query)
+		want  string
+		args  []any
+	}{
+		{
+			name: "first page",
+			p:    listing.Params{Limit: 20, Sort: "level"},
+			want: base + "\nORDER BY level ASC, id ASC\nLIMIT $1\n",
+			args: []any{int32(21)},
+		},
+		{
+			name:  "filters",
+			p:     listing.Params{Limit: 5, Sort: "level", Descending: true},
+			where: func(q *listing.Query) { q.Where("class = " + q.Arg("Mage")); q.Where("deleted_at IS NULL") },
+			want:  base + "\nWHERE class = $1\nAND deleted_at IS NULL\nORDER BY level DESC, id DESC\nLIMIT $2\n",
+			args:  []any{"Mage", int32(6)},
+		},
+		{
+			name: "ascending cursor",
+			p:    listing.Params{Limit: 5, Sort: "level", Cursor: &listing.Cursor{Sort: "level", Value: "12", ID: "7"}},
+			want: base + "\nWHERE (level, id) > ($1::int, $2::int)\nORDER BY level ASC, id ASC\nLIMIT $3\n",
+			args: []any{"12", "7", int32(6)},
+		},
+		{
+			name:  "descending cursor after filters",
+			p:     listing.Params{Limit: 5, Sort: "level", Descending: true, Cursor: &listing.Cursor{Sort: "level", Descending: true, Value: "12", ID: "7"}},
+			where: func(q *listing.Query) { q.Where("class = " + q.Arg("Mage")) },
+			want:  base + "\nWHERE class = $1\nAND (level, id) < ($2::int, $3::int)\nORDER BY level DESC, id DESC\nLIMIT $4\n",
+			args:  []any{"Mage", "12", "7", int32(6)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var q listing.Query
+			if tt.where != nil {
+				tt.where(&q)
+			}
+
+			if got := q.Build(base, tt.p, levelColumn, idColumn); got != tt.want {
+				t.Errorf("Build =\n%s\nwant\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(q.Args(), tt.args) {
+				t.Errorf("Args = %#v, want %#v", q.Args(), tt.args)
+			}
+		})
+	}
+}
+
+func TestBuildSQLite(t *testing.T) {
+	q := listing.Query{Dialect: sqlite.Dialect}
+	q.Where("class = " + q.Arg("Mage"))
+
+	createdAt := listing.Column{Expr: "created_at", Type: listing.Timestamp}
+	p := listing.Params{
+		Limit:  10,
+		Sort:   "created_at",
+		Cursor: &listing.Cursor{Sort: "created_at", Value: "2026-01-02T05:04:05.5+02:00", ID: "7"},
+	}
+
+	want := base + "\nWHERE class = ?1\nAND (created_at, id) > (?2, ?3)\nORDER BY created_at ASC, id ASC\nLIMIT ?4\n"
+	if got := q.Build(base, p, createdAt, idColumn); got != want {
+		t.Errorf("Build =\n%s\nwant\n%s", got, want)
+	}
+
+	// SQLite has no casts, so cursor values are passed as stored: integers
+	// as integers and timestamps in UTC at the storage precision.
+	args := []any{"Mage", "2026-01-02T03:04:05.500000000Z", int64(7), int32(11)}
+	if !reflect.DeepEqual(q.Args(), args) {
+		t.Errorf("Args = %#v, want %#v", q.Args(), args)
+	}
+}
@@ -0,0 +1,122 @@
+// Package memdb keeps the game's tables in memory. It backs the in-memory
+// repositories, which share one DB so that they can check references between
+// tables the way the foreign keys of the Postgres schema do.
+package memdb
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// These errors stand in for the Postgres errors of the same situations, for
+// the cases the repositories do not map to a domain error.
+var (
+	ErrNoRows     = errors.New("memdb: no rows in result set")
+	ErrForeignKey = errors.New("memdb: foreign key violation")
+	ErrUnique     = errors.New("memdb: unique violation")
+)
+
+// DB guards a set of tables. Every repository call runs with the tables
+// locked, which makes it atomic and isolated like a transaction.
+type DB struct {
+	mu     sync.RWMutex
+	tables *Tables
+}
+
+func New() *DB {
+	return &DB{tables: newTables()}
+}
+
+type txKey struct{}
+
+func (db *DB) inTx(ctx context.Context) bool {
+	owner, _ := ctx.Value(txKey{}).(*DB)
+	return owner == db
+}
+
+// Read runs fn with the tables locked for reading.
+func (db *DB) Read(ctx context.Context, fn func(t *Tables) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !db.inTx(ctx) {
+		db.mu.RLock()
+		defer db.mu.RUnlock()
+	}
+
+	return fn(db.tables)
+}
+
+// Write runs fn with the tables locked for writing. If fn fails, every change
+// it made is undone.
+func (db *DB) Write(ctx context.Context, fn func(t *Tables) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !db.inTx(ctx) {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+	}
+
+	return db.apply(fn)
+}
+
+func (db *DB) apply(fn func(t *Tables) error) error {
+	saved := db.tables.clone()
+	if err := fn(db.tables); err != nil {
+		db.tables = saved
+		return err
+	}
+	return nil
+}
+
+// InTx runs fn as a single transaction: the tables stay locked until fn
+// returns, and are restored if it fails. Reads and writes made with the
+// context fn is given join the transaction; nested calls behave like
+// savepoints.
+func (db *DB) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !db.inTx(ctx) {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+		ctx = context.WithValue(ctx, txKey{}, db)
+	}
+
+	return db.apply(func(*Tables) error { return fn(ctx) })
+}
+
+// clone copies every table. Rows are stored by value and never modified in
+// place, so copying the maps is enough to keep a consistent snapshot.
+func (t *Tables) clone() *Tables {
+	c := *t
+	c.Players = maps.Clone(t.Players)
+	c.NameHistory = slices.Clone(t.NameHistory)
+	c.Items = maps.Clone(t.Items)
+	c.ItemPrices = maps.Clone(t.ItemPrices)
+	c.Inventory = maps.Clone(t.Inventory)
+	c.Effects = maps.Clone(t.Effects)
+	c.Ratings = maps.Clone(t.Ratings)
+	c.Matches = maps.Clone(t.Matches)
+	c.Zones = maps.Clone(t.Zones)
+	c.ZoneConnections = maps.Clone(t.ZoneConnections)
+	c.Locations = maps.Clone(t.Locations)
+	c.Currencies = maps.Clone(t.Currencies)
+	c.Balances = maps.Clone(t.Balances)
+	c.Bundles = maps.Clone(t.Bundles)
+	c.Receipts = maps.Clone(t.Receipts)
+	c.Grants = slices.Clone(t.Grants)
+	c.Accounts = maps.Clone(t.Accounts)
+	c.Storages = maps.Clone(t.Storages)
+	c.StorageItems = maps.Clone(t.StorageItems)
+	c.IdempotencyKeys = maps.Clone(t.IdempotencyKeys)
+	c.sequences = maps.Clone(t.sequences)
+	return &c
+}
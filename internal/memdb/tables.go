@@ -0,0 +1,294 @@
+package memdb
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tables mirrors the Postgres schema: one map per table, keyed by its primary
+// key. Rows hold the table's columns.
+type Tables struct {
+	Players         map[int32]Player
+	NameHistory     []NameChange
+	Items           map[uuid.UUID]Item
+	ItemPrices      map[ItemPriceKey]int32
+	Inventory       map[InventoryKey]InventoryRow
+	Effects         map[int32]Effect
+	Ratings         map[int32]Rating
+	Matches         map[int32]Match
+	Zones           map[int32]Zone
+	ZoneConnections map[ZoneConnection]bool
+	Locations       map[int32]Location
+	Currencies      map[string]Currency
+	Balances        map[BalanceKey]int32
+	Bundles         map[string]Bundle
+	Receipts        map[string]Receipt
+	Grants          []Grant
+	Accounts        map[int32]Account
+	Storages        map[StorageKey]Storage
+	StorageItems    map[StorageItemKey]bool
+	IdempotencyKeys map[IdempotencyKeyID]IdempotencyKey
+
+	sequences map[string]int32
+}
+
+func newTables() *Tables {
+	return &Tables{
+		Players:         make(map[int32]Player),
+		Items:           make(map[uuid.UUID]Item),
+		ItemPrices:      make(map[ItemPriceKey]int32),
+		Inventory:       make(map[InventoryKey]InventoryRow),
+		Effects:         make(map[int32]Effect),
+		Ratings:         make(map[int32]Rating),
+		Matches:         make(map[int32]Match),
+		Zones:           make(map[int32]Zone),
+		ZoneConnections: make(map[ZoneConnection]bool),
+		Locations:       make(map[int32]Location),
+		Currencies:      make(map[string]Currency),
+		Balances:        make(map[BalanceKey]int32),
+		Bundles:         make(map[string]Bundle),
+		Receipts:        make(map[string]Receipt),
+		Accounts:        make(map[int32]Account),
+		Storages:        make(map[StorageKey]Storage),
+		StorageItems:    make(map[StorageItemKey]bool),
+		IdempotencyKeys: make(map[IdempotencyKeyID]IdempotencyKey),
+		sequences:       make(map[string]int32),
+	}
+}
+
+// NextID returns the next value of the identity column of the named table.
+func (t *Tables) NextID(table string) int32 {
+	t.sequences[table]++
+	return t.sequences[table]
+}
+
+// GoldCap returns the most gold a player can hold: the cap of the gold
+// currency, or the largest int32 without one.
+func (t *Tables) GoldCap() int32 {
+	if c, ok := t.Currencies["gold"]; ok && c.Cap != nil {
+		return *c.Cap
+	}
+	return math.MaxInt32
+}
+
+// IncreaseGold and DecreaseGold change a player's gold like
+// player.IncreaseGoldSQL and player.DecreaseGoldSQL: credits are clamped to
+// the cap of the gold currency and debits never take gold below zero. They
+// return the new amount, and false for a missing or deleted player or, for
+// DecreaseGold, one with too little gold.
+func (t *Tables) IncreaseGold(playerID, amount int32) (int32, bool) {
+	p, ok := t.Players[playerID]
+	if !ok || p.DeletedAt != nil {
+		return 0, false
+	}
+
+	p.Gold = int32(min(int64(p.Gold)+int64(amount), int64(t.GoldCap())))
+	p.Version++
+	p.UpdatedAt = time.Now()
+	t.Players[playerID] = p
+
+	return p.Gold, true
+}
+
+func (t *Tables) DecreaseGold(playerID, amount int32) (int32, bool) {
+	p, ok := t.Players[playerID]
+	if !ok || p.DeletedAt != nil || p.Gold < amount {
+		return 0, false
+	}
+
+	p.Gold -= amount
+	p.Version++
+	p.UpdatedAt = time.Now()
+	t.Players[playerID] = p
+
+	return p.Gold, true
+}
+
+type Player struct {
+	ID          int32
+	Username    string
+	UsernameKey string
+	Class       string
+	Level       int32
+	Gold        int32
+	AccountID   *int32
+	Version     int32
+	RenamedAt   *time.Time
+	DeletedAt   *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type NameChange struct {
+	ID          int32
+	PlayerID    int32
+	Username    string
+	UsernameKey string
+	ChangedAt   time.Time
+	HeldUntil   time.Time
+}
+
+type Item struct {
+	ID        uuid.UUID
+	Name      string
+	Value     int32
+	Stats     map[string]int32
+	Version   int32
+	DeletedAt *time.Time
+}
+
+type ItemPriceKey struct {
+	ItemID   uuid.UUID
+	Currency string
+}
+
+type InventoryKey struct {
+	PlayerID int32
+	ItemID   uuid.UUID
+}
+
+type InventoryRow struct {
+	Equipped bool
+}
+
+type Effect struct {
+	ID        int32
+	PlayerID  int32
+	Type      string
+	Stat      string
+	Magnitude int32
+	Stacks    int32
+	MaxStacks int32
+	Source    string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Rating struct {
+	PlayerID  int32
+	Rating    int32
+	Wins      int32
+	Losses    int32
+	Draws     int32
+	UpdatedAt time.Time
+}
+
+type Match struct {
+	ID            int32
+	PlayerAID     int32
+	PlayerBID     int32
+	RatingA       int32
+	RatingB       int32
+	Status        string
+	WinnerID      *int32
+	RatingChangeA int32
+	RatingChangeB int32
+	CreatedAt     time.Time
+	CompletedAt   *time.Time
+}
+
+type Zone struct {
+	ID       int32
+	Name     string
+	MinLevel int32
+	MaxLevel int32
+	PvP      bool
+}
+
+type ZoneConnection struct {
+	FromZoneID int32
+	ToZoneID   int32
+}
+
+type Location struct {
+	PlayerID  int32
+	ZoneID    int32
+	X         float64
+	Y         float64
+	Z         float64
+	UpdatedAt time.Time
+}
+
+type Currency struct {
+	Code string
+	Name string
+	Cap  *int32
+}
+
+type BalanceKey struct {
+	PlayerID int32
+	Currency string
+}
+
+// Bundle holds a shop bundle together with the rows of shop_bundle_currency
+// and shop_bundle_item that belong to it.
+type Bundle struct {
+	ID         string
+	Name       string
+	PriceCents int32
+	Active     bool
+	Currencies map[string]int32
+	ItemIDs    []uuid.UUID
+}
+
+type Receipt struct {
+	TransactionID string
+	PlayerID      int32
+	BundleID      string
+	Status        string
+	CreatedAt     time.Time
+	GrantedAt     *time.Time
+	RefundedAt    *time.Time
+}
+
+type Grant struct {
+	ID            int32
+	TransactionID string
+	Currency      *string
+	ItemID        *uuid.UUID
+	Amount        int32
+}
+
+type Account struct {
+	ID        int32
+	Name      string
+	CreatedAt time.Time
+}
+
+// StorageKey identifies a bank (by player) or a shared storage (by account).
+// Kind takes the values of the bank package's storage kinds.
+type StorageKey struct {
+	Kind    string
+	OwnerID int32
+}
+
+type Storage struct {
+	Capacity int32
+	Gold     int32
+}
+
+type StorageItemKey struct {
+	Storage StorageKey
+	ItemID  uuid.UUID
+}
+
+type IdempotencyKeyID struct {
+	Scope string
+	Key   string
+}
+
+type IdempotencyKey struct {
+	Scope       string
+	Key         string
+	Fingerprint string
+	StatusCode  *int32
+	Headers     http.Header
+	Body        []byte
+	CreatedAt   time.Time
+	CompletedAt *time.Time
+	ExpiresAt   time.Time
+}
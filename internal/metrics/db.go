@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// other labels queries made outside of a repository, such as by migrations.
+const other = "other"
+
+// label returns the repository method ctx was labelled with, or other.
+func label(ctx context.Context) (repository, method string) {
+	l, ok := querylabel.From(ctx)
+	if !ok {
+		return other, other
+	}
+	return l.Repository, l.Method
+}
+
+func (m *Metrics) observeQuery(repository, method string, elapsed time.Duration, err error) {
+	m.queryDuration.WithLabelValues(repository, method).Observe(elapsed.Seconds())
+	if err != nil {
+		m.queryErrors.WithLabelValues(repository, method).Inc()
+	}
+}
+
+// ObserveSQLiteQuery records a query made on an SQLite database. It is an
+// sqlite.QueryObserver.
+func (m *Metrics) ObserveSQLiteQuery(ctx context.Context, elapsed time.Duration, err error) {
+	repository, method := label(ctx)
+	m.observeQuery(repository, method, elapsed, err)
+}
+
+// QueryTracer returns a pgx tracer that records every query made on the
+// connections it is set on.
+func (m *Metrics) QueryTracer() pgx.QueryTracer {
+	return &queryTracer{metrics: m}
+}
+
+type queryTracer struct {
+	metrics *Metrics
+}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	at                 time.Time
+	repository, method string
+}
+
+func (t *queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	repository, method := label(ctx)
+	return context.WithValue(ctx, queryStartKey{}, queryStart{at: time.Now(), repository: repository, method: method})
+}
+
+func (t *queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	t.metrics.observeQuery(start.repository, start.method, time.Since(start.at), data.Err)
+}
+
+// RegisterPool exports the statistics of a Postgres connection pool.
+func (m *Metrics) RegisterPool(pool *pgxpool.Pool) {
+	m.registry.MustRegister(&poolCollector{pool: pool})
+}
+
+// RegisterSQLDB exports the statistics of a database/sql connection pool.
+func (m *Metrics) RegisterSQLDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+var (
+	poolAcquiredConns = prometheus.NewDesc(namespace+"_db_pool_acquired_connections",
+		"Connections currently in use.", nil, nil)
+	poolIdleConns = prometheus.NewDesc(namespace+"_db_pool_idle_connections",
+		"Connections currently idle.", nil, nil)
+	poolTotalConns = prometheus.NewDesc(namespace+"_db_pool_connections",
+		"Connections currently open.", nil, nil)
+	poolMaxConns = prometheus.NewDesc(namespace+"_db_pool_max_connections",
+		"Maximum size of the pool.", nil, nil)
+	poolAcquires = prometheus.NewDesc(namespace+"_db_pool_acquires_total",
+		"Connections acquired from the pool.", nil, nil)
+	poolEmptyAcquires = prometheus.NewDesc(namespace+"_db_pool_empty_acquires_total",
+		"Acquires that had to wait for a connection because none was idle.", nil, nil)
+	poolCanceledAcquires = prometheus.NewDesc(namespace+"_db_pool_canceled_acquires_total",
+		"Acquires cancelled by their context before getting a connection.", nil, nil)
+	poolAcquireDuration = prometheus.NewDesc(namespace+"_db_pool_acquire_duration_seconds_total",
+		"Time spent acquiring connections.", nil, nil)
+)
+
+// poolCollector reads the pool statistics on every scrape.
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredConns
+	ch <- poolIdleConns
+	ch <- poolTotalConns
+	ch <- poolMaxConns
+	ch <- poolAcquires
+	ch <- poolEmptyAcquires
+	ch <- poolCanceledAcquires
+	ch <- poolAcquireDuration
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+)
+
+// GameSampler periodically updates the game gauges. They take queries over
+// whole tables to compute, so they are sampled on an interval rather than on
+// every scrape.
+type GameSampler struct {
+	metrics  *Metrics
+	players  *player.PlayerService
+	items    *item.ItemService
+	interval time.Duration
+}
+
+func NewGameSampler(metrics *Metrics, players *player.PlayerService, items *item.ItemService, interval time.Duration) *GameSampler {
+	return &GameSampler{metrics: metrics, players: players, items: items, interval: interval}
+}
+
+// Run samples right away and then on every tick until ctx is cancelled.
+func (s *GameSampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sample(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error sampling game metrics: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *GameSampler) sample(ctx context.Context) error {
+	totals, err := s.players.GetPlayerTotals(ctx)
+	if err != nil {
+		return err
+	}
+
+	items, err := s.items.CountItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Classes nobody plays any more are dropped rather than left at their
+	// last count.
+	s.metrics.players.Reset()
+	for class, n := range totals.ByClass {
+		s.metrics.players.WithLabelValues(class).Set(float64(n))
+	}
+	s.metrics.gold.Set(float64(totals.Gold))
+	s.metrics.items.Set(float64(items))
+
+	return nil
+}
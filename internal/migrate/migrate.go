@@ -0,0 +1,420 @@
+// Package migrate applies the numbered Postgres migrations embedded in the
+// binary and records them in the app_schema_migrations table, named so as not
+// to clash with the schema_migrations table of other migration tools.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migration is one numbered schema change. Down is empty if the migration
+// cannot be reverted.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads the migrations named <version>_<name>.up.sql and
+// <version>_<name>.down.sql from the root of fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("listing migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, file := range files {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(path.Base(file), ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration name '%v'", file)
+		}
+		num, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration name '%v'", file)
+		}
+
+		b, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %v: %w", file, err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %v has two names: '%v' and '%v'", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %v has no up file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+
+	return migrations, nil
+}
+
+// Status is the state of one migration in a database.
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator moves a database between schema versions. Every change holds an
+// advisory lock, so concurrent runs, such as several replicas migrating on
+// startup, wait for each other instead of applying the same migration twice.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+func New(db *database.DB, migrations []Migration) *Migrator {
+	return &Migrator{pool: db.Pool(), migrations: migrations}
+}
+
+// Latest returns the version of the newest known migration.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+const createSchemaMigrations = `
+CREATE TABLE IF NOT EXISTS app_schema_migrations (
+  version INT PRIMARY KEY,
+  name TEXT NOT NULL,
+  applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)
+`
+
+const lockMigrations = `
+SELECT pg_advisory_lock(hashtext('app_schema_migrations'))
+`
+
+const unlockMigrations = `
+SELECT pg_advisory_unlock(hashtext('app_schema_migrations'))
+`
+
+const listApplied = `
+SELECT version, applied_at FROM app_schema_migrations
+`
+
+// locked runs fn on a single connection holding the migration lock, with the
+// applied migrations read after the lock was taken.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn, applied map[int]time.Time) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, lockMigrations); err != nil {
+		return fmt.Errorf("locking migrations: %w", err)
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), unlockMigrations)
+
+	if _, err := conn.Exec(ctx, createSchemaMigrations); err != nil {
+		return fmt.Errorf("creating app_schema_migrations: %w", err)
+	}
+
+	applied, err := readApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// readApplied returns when each applied migration was applied. A database
+// app_schema_migrations was never created in has none applied.
+func readApplied(ctx context.Context, q querier) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
+
+	rows, err := q.Query(ctx, listApplied)
+	if err != nil {
+		if isUndefinedTable(err) {
+			return applied, nil
+		}
+		return nil, fmt.Errorf("querying for applied migrations: %w", err)
+	}
+
+	var version int
+	var appliedAt time.Time
+	_, err = pgx.ForEachRow(rows, []any{&version, &appliedAt}, func() error {
+		applied[version] = appliedAt
+		return nil
+	})
+	if err != nil {
+		if isUndefinedTable(err) {
+			return applied, nil
+		}
+		return nil, fmt.Errorf("scanning applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// Status lists every known migration and when it was applied, if it was. It
+// does not take the migration lock, so it answers while another run is in
+// progress, and sees the migrations that run has not finished as pending.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := readApplied(ctx, m.pool)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			s.AppliedAt = &at
+		}
+		statuses = append(statuses, s)
+	}
+
+	return statuses, nil
+}
+
+const newestApplied = `
+SELECT COALESCE(max(version), 0) FROM app_schema_migrations
+`
+
+// Version returns the newest applied migration, or 0 if none was. Unlike the
+// other methods it does not take the migration lock, so it is cheap enough
+// for health checks, and sees a migration in progress as not applied yet.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var version int
+
+	err := m.pool.QueryRow(ctx, newestApplied).Scan(&version)
+	if err != nil {
+		if isUndefinedTable(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("querying for schema version: %w", err)
+	}
+
+	return version, nil
+}
+
+// undefinedTable is the error code of a query on a table that does not
+// exist, which app_schema_migrations does not before the first run.
+const undefinedTable = "42P01"
+
+func isUndefinedTable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == undefinedTable
+}
+
+// Up applies every pending migration and returns how many it applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the newest applied migration. It returns false if there was
+// nothing to revert.
+func (m *Migrator) Down(ctx context.Context) (bool, error) {
+	var reverted bool
+
+	err := m.locked(ctx, func(conn *pgxpool.Conn, applied map[int]time.Time) error {
+		for _, mig := range slices.Backward(m.migrations) {
+			if _, ok := applied[mig.Version]; ok {
+				reverted = true
+				return revert(ctx, conn, mig)
+			}
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// To applies the pending migrations up to and including version, and reverts
+// the applied ones above it, newest first. Version 0 reverts everything. It
+// returns how many migrations it applied or reverted.
+func (m *Migrator) To(ctx context.Context, version int) (int, error) {
+	if version != 0 && !slices.ContainsFunc(m.migrations, func(mig Migration) bool { return mig.Version == version }) {
+		return 0, fmt.Errorf("unknown migration version %v", version)
+	}
+
+	var n int
+
+	err := m.locked(ctx, func(conn *pgxpool.Conn, applied map[int]time.Time) error {
+		if len(applied) == 0 && version > 0 {
+			if err := checkUntracked(ctx, conn); err != nil {
+				return err
+			}
+		}
+
+		for _, mig := range slices.Backward(m.migrations) {
+			if _, ok := applied[mig.Version]; ok && mig.Version > version {
+				if err := revert(ctx, conn, mig); err != nil {
+					return err
+				}
+				n++
+			}
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; !ok && mig.Version <= version {
+				if err := apply(ctx, conn, mig); err != nil {
+					return err
+				}
+				n++
+			}
+		}
+		return nil
+	})
+
+	return n, err
+}
+
+// ErrUntracked is returned when migrating a database that has tables but no
+// recorded migrations, such as one created by hand or by another tool, which
+// Baseline adopts instead.
+var ErrUntracked = errors.New("the database has a schema but no recorded migrations; adopt it with 'migrate baseline <version>'")
+
+const otherTables = `
+SELECT EXISTS (
+  SELECT 1 FROM information_schema.tables
+  WHERE table_schema = current_schema() AND table_name <> 'app_schema_migrations'
+)
+`
+
+// checkUntracked fails with ErrUntracked if the current schema holds tables
+// other than app_schema_migrations.
+func checkUntracked(ctx context.Context, conn *pgxpool.Conn) error {
+	var exists bool
+	if err := conn.QueryRow(ctx, otherTables).Scan(&exists); err != nil {
+		return fmt.Errorf("checking for an existing schema: %w", err)
+	}
+	if exists {
+		return ErrUntracked
+	}
+	return nil
+}
+
+// Baseline records the migrations up to and including version as applied
+// without running them, adopting a database whose schema is already at that
+// version. It only adopts a database that has no recorded migrations yet, and
+// returns how many it recorded.
+func (m *Migrator) Baseline(ctx context.Context, version int) (int, error) {
+	if !slices.ContainsFunc(m.migrations, func(mig Migration) bool { return mig.Version == version }) {
+		return 0, fmt.Errorf("unknown migration version %v", version)
+	}
+
+	var n int
+
+	err := m.locked(ctx, func(conn *pgxpool.Conn, applied map[int]time.Time) error {
+		if len(applied) > 0 {
+			return errors.New("the database already has recorded migrations")
+		}
+
+		return inTx(ctx, conn, func(tx pgx.Tx) error {
+			for _, mig := range m.migrations {
+				if mig.Version > version {
+					break
+				}
+				if _, err := tx.Exec(ctx, recordMigration, mig.Version, mig.Name); err != nil {
+					return fmt.Errorf("recording migration %v: %w", mig.Version, err)
+				}
+				n++
+			}
+			return nil
+		})
+	})
+
+	return n, err
+}
+
+const recordMigration = `
+INSERT INTO app_schema_migrations (version, name) VALUES ($1, $2)
+`
+
+const forgetMigration = `
+DELETE FROM app_schema_migrations WHERE version = $1
+`
+
+// apply runs a migration and records it in one transaction, so a failed
+// migration leaves nothing behind.
+func apply(ctx context.Context, conn *pgxpool.Conn, mig Migration) error {
+	return inTx(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, mig.Up); err != nil {
+			return fmt.Errorf("applying migration %v_%v: %w", mig.Version, mig.Name, err)
+		}
+		if _, err := tx.Exec(ctx, recordMigration, mig.Version, mig.Name); err != nil {
+			return fmt.Errorf("recording migration %v: %w", mig.Version, err)
+		}
+		return nil
+	})
+}
+
+func revert(ctx context.Context, conn *pgxpool.Conn, mig Migration) error {
+	if mig.Down == "" {
+		return fmt.Errorf("migration %v_%v cannot be reverted: %w", mig.Version, mig.Name, ErrIrreversible)
+	}
+
+	return inTx(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, mig.Down); err != nil {
+			return fmt.Errorf("reverting migration %v_%v: %w", mig.Version, mig.Name, err)
+		}
+		if _, err := tx.Exec(ctx, forgetMigration, mig.Version); err != nil {
+			return fmt.Errorf("forgetting migration %v: %w", mig.Version, err)
+		}
+		return nil
+	})
+}
+
+// ErrIrreversible is returned when reverting a migration that has no down
+// file.
+var ErrIrreversible = errors.New("no down migration")
+
+func inTx(ctx context.Context, conn *pgxpool.Conn, fn func(tx pgx.Tx) error) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
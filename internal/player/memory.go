@@ -0,0 +1,526 @@
+package player
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+)
+
+type memRepository struct {
+	db *memdb.DB
+}
+
+// NewMemoryRepository returns a PlayerRepository that keeps players in db. It
+// behaves like the Postgres repository, down to the errors it returns.
+func NewMemoryRepository(db *memdb.DB) PlayerRepository {
+	return &memRepository{db: db}
+}
+
+func toPlayer(row memdb.Player) *Player {
+	return &Player{
+		ID:        row.ID,
+		Username:  row.Username,
+		Class:     row.Class,
+		Level:     row.Level,
+		Gold:      row.Gold,
+		AccountID: row.AccountID,
+		Version:   row.Version,
+		RenamedAt: row.RenamedAt,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
+
+// livePlayer returns the player with the given id unless it does not exist or
+// was soft-deleted.
+func livePlayer(t *memdb.Tables, id int32) (memdb.Player, bool) {
+	row, ok := t.Players[id]
+	return row, ok && row.DeletedAt == nil
+}
+
+// checkUsername enforces what the unique indexes and claimUsername enforce in
+// Postgres: no two players share a username or username key, and names held
+// after a rename stay with their previous owner.
+func checkUsername(t *memdb.Tables, username, key string, playerID int32) error {
+	now := time.Now()
+	for _, c := range t.NameHistory {
+		if c.UsernameKey == key && c.HeldUntil.After(now) && c.PlayerID != playerID {
+			return ErrUsernameInUse
+		}
+	}
+	for _, row := range t.Players {
+		if row.ID != playerID && (row.UsernameKey == key || row.Username == username) {
+			return ErrUsernameInUse
+		}
+	}
+	return nil
+}
+
+func (r *memRepository) CreatePlayer(ctx context.Context, args CreatePlayerParams) (*Player, error) {
+	var p *Player
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		if err := checkUsername(t, args.Username, args.UsernameKey, 0); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		row := memdb.Player{
+			ID:          t.NextID("player"),
+			Username:    args.Username,
+			UsernameKey: args.UsernameKey,
+			Class:       args.Class,
+			Level:       1,
+			Gold:        args.Gold,
+			Version:     1,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		t.Players[row.ID] = row
+
+		p = toPlayer(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *memRepository) ListPlayers(ctx context.Context, args ListPlayersParams) (*listing.Page[*Player], error) {
+	sort, ok := playerSortColumns[args.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field '%v'", args.Sort)
+	}
+
+	var ps []*Player
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Players {
+			switch {
+			case row.DeletedAt != nil:
+			case args.Class != "" && row.Class != args.Class:
+			case args.MinLevel != nil && row.Level < *args.MinLevel:
+			case args.MaxLevel != nil && row.Level > *args.MaxLevel:
+			case args.NamePrefix != "" && !strings.HasPrefix(row.Username, args.NamePrefix):
+			default:
+				ps = append(ps, toPlayer(row))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := listing.Paginate(ps, args.Params, sort, playerSortColumns["id"], playerSortKey(args.Sort))
+	if err != nil {
+		return nil, fmt.Errorf("paginating players: %w", err)
+	}
+
+	return page, nil
+}
+
+func (r *memRepository) GetPlayerByID(ctx context.Context, id int32) (*Player, error) {
+	var p *Player
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		if row, ok := livePlayer(t, id); ok {
+			p = toPlayer(row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *memRepository) GetPlayerByUsername(ctx context.Context, username string) (*Player, error) {
+	var p *Player
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Players {
+			if row.DeletedAt == nil && strings.ToLower(row.Username) == strings.ToLower(username) {
+				p = toPlayer(row)
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// conditional looks up the live player a conditional write applies to. Like
+// missOrConflict, it reports a missing player as a nil error and a player at
+// another version as ErrVersionMismatch.
+func conditional(t *memdb.Tables, id int32, version *int32) (memdb.Player, bool, error) {
+	row, ok := livePlayer(t, id)
+	if !ok {
+		return row, false, nil
+	}
+	if version != nil && row.Version != *version {
+		return row, false, ErrVersionMismatch
+	}
+	return row, true, nil
+}
+
+func (r *memRepository) UpdatePlayer(ctx context.Context, args UpdatePlayerParams) (*Player, error) {
+	var p *Player
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		row, ok, err := conditional(t, args.ID, args.Version)
+		if !ok {
+			return err
+		}
+
+		if args.Class != nil {
+			row.Class = *args.Class
+		}
+		if args.Level != nil {
+			row.Level = *args.Level
+		}
+		if args.Gold != nil {
+			row.Gold = min(*args.Gold, t.GoldCap())
+		}
+		row.Version++
+		row.UpdatedAt = time.Now()
+		t.Players[row.ID] = row
+
+		p = toPlayer(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *memRepository) RenamePlayer(ctx context.Context, args RenamePlayerParams) (*Player, error) {
+	var p *Player
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		row, ok := livePlayer(t, args.ID)
+		if !ok {
+			return nil
+		}
+
+		if row.RenamedAt != nil && time.Since(*row.RenamedAt) < args.Cooldown {
+			return cooldownErr(*row.RenamedAt, args.Cooldown)
+		}
+
+		if err := checkUsername(t, args.Username, args.UsernameKey, args.ID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		t.NameHistory = append(t.NameHistory, memdb.NameChange{
+			ID:          t.NextID("player_name_history"),
+			PlayerID:    row.ID,
+			Username:    row.Username,
+			UsernameKey: row.UsernameKey,
+			ChangedAt:   now,
+			HeldUntil:   now.Add(args.HoldPeriod),
+		})
+
+		row.Username = args.Username
+		row.UsernameKey = args.UsernameKey
+		row.RenamedAt = &now
+		row.Version++
+		row.UpdatedAt = now
+		t.Players[row.ID] = row
+
+		p = toPlayer(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *memRepository) ListNameHistory(ctx context.Context, playerID int32) ([]*NameChange, error) {
+	var rows []memdb.NameChange
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, c := range t.NameHistory {
+			if c.PlayerID == playerID {
+				rows = append(rows, c)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(rows, func(a, b memdb.NameChange) int {
+		if c := b.ChangedAt.Compare(a.ChangedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+
+	history := make([]*NameChange, len(rows))
+	for i, c := range rows {
+		history[i] = &NameChange{Username: c.Username, ChangedAt: c.ChangedAt, HeldUntil: c.HeldUntil}
+	}
+
+	return history, nil
+}
+
+// updateLive applies fn to a live player and does nothing if there is none,
+// like an UPDATE matching no row.
+func (r *memRepository) updateLive(ctx context.Context, id int32, fn func(row *memdb.Player)) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		row, ok := livePlayer(t, id)
+		if !ok {
+			return nil
+		}
+
+		fn(&row)
+		row.Version++
+		row.UpdatedAt = time.Now()
+		t.Players[row.ID] = row
+
+		return nil
+	})
+}
+
+func (r *memRepository) UpdatePlayerLevel(ctx context.Context, args UpdatePlayerLevelParams) error {
+	return r.updateLive(ctx, args.ID, func(row *memdb.Player) {
+		row.Level = args.Level
+	})
+}
+
+func (r *memRepository) IncreasePlayerGold(ctx context.Context, args UpdatePlayerGoldParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		t.IncreaseGold(args.ID, args.Amount)
+		return nil
+	})
+}
+
+func (r *memRepository) DecreasePlayerGold(ctx context.Context, args UpdatePlayerGoldParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		if _, ok := t.DecreaseGold(args.ID, args.Amount); !ok {
+			return ErrInsufficientFunds
+		}
+		return nil
+	})
+}
+
+func (r *memRepository) DeletePlayerByID(ctx context.Context, args DeletePlayerParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		row, ok, err := conditional(t, args.ID, args.Version)
+		if !ok {
+			return err
+		}
+
+		now := time.Now()
+		row.DeletedAt = &now
+		row.Version++
+		row.UpdatedAt = now
+		t.Players[row.ID] = row
+
+		return nil
+	})
+}
+
+func (r *memRepository) RestorePlayer(ctx context.Context, args RestorePlayerParams) (*Player, error) {
+	var p *Player
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		row, ok := t.Players[args.ID]
+		if !ok || row.DeletedAt == nil {
+			return nil
+		}
+		if time.Since(*row.DeletedAt) > args.GracePeriod {
+			return ErrRestoreExpired
+		}
+
+		row.DeletedAt = nil
+		row.Version++
+		row.UpdatedAt = time.Now()
+		t.Players[row.ID] = row
+
+		p = toPlayer(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *memRepository) PurgePlayer(ctx context.Context, args PurgePlayerParams) (*DeletionReport, error) {
+	var report *DeletionReport
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		row, ok := t.Players[args.ID]
+		if !ok {
+			return nil
+		}
+		if args.Version != nil && *args.Version != row.Version {
+			return ErrVersionMismatch
+		}
+
+		report = purgeMemPlayers(t, map[int32]bool{args.ID: true})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (r *memRepository) PurgeDeletedPlayers(ctx context.Context, before time.Time) (*DeletionReport, error) {
+	var report *DeletionReport
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		ids := make(map[int32]bool)
+		for _, row := range t.Players {
+			if row.DeletedAt != nil && row.DeletedAt.Before(before) {
+				ids[row.ID] = true
+			}
+		}
+
+		report = purgeMemPlayers(t, ids)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// purgeMemPlayers removes the same records as purgePlayers does in Postgres
+// and keeps the same ledger.
+func purgeMemPlayers(t *memdb.Tables, ids map[int32]bool) *DeletionReport {
+	report := &DeletionReport{
+		Removed:  make(map[string]int64, len(ownedRecords)),
+		Retained: make(map[string]int64, len(ledgerRecords)),
+	}
+	if len(ids) == 0 {
+		return report
+	}
+	for _, rec := range ownedRecords {
+		report.Removed[rec.kind] = 0
+	}
+	for _, rec := range ledgerRecords {
+		report.Retained[rec.kind] = 0
+	}
+
+	for _, rc := range t.Receipts {
+		if ids[rc.PlayerID] {
+			report.Retained["purchase_receipts"]++
+		}
+	}
+	for id, m := range t.Matches {
+		if !ids[m.PlayerAID] && !ids[m.PlayerBID] {
+			continue
+		}
+		if m.Status == "pending" {
+			delete(t.Matches, id)
+			report.Removed["pending_pvp_matches"]++
+		} else {
+			report.Retained["pvp_matches"]++
+		}
+	}
+
+	for id := range ids {
+		if _, ok := t.Ratings[id]; ok {
+			delete(t.Ratings, id)
+			report.Removed["pvp_rating"]++
+		}
+		if _, ok := t.Locations[id]; ok {
+			delete(t.Locations, id)
+			report.Removed["location"]++
+		}
+	}
+
+	bank := func(key memdb.StorageKey) bool { return key.Kind == "bank" && ids[key.OwnerID] }
+	for key := range t.StorageItems {
+		if bank(key.Storage) {
+			delete(t.StorageItems, key)
+			report.Removed["bank_items"]++
+		}
+	}
+	for key := range t.Storages {
+		if bank(key) {
+			delete(t.Storages, key)
+			report.Removed["bank"]++
+		}
+	}
+
+	for key := range t.Balances {
+		if ids[key.PlayerID] {
+			delete(t.Balances, key)
+			report.Removed["currencies"]++
+		}
+	}
+	for id, e := range t.Effects {
+		if ids[e.PlayerID] {
+			delete(t.Effects, id)
+			report.Removed["effects"]++
+		}
+	}
+	for key := range t.Inventory {
+		if ids[key.PlayerID] {
+			delete(t.Inventory, key)
+			report.Removed["inventory"]++
+		}
+	}
+	t.NameHistory = slices.DeleteFunc(t.NameHistory, func(c memdb.NameChange) bool {
+		if ids[c.PlayerID] {
+			report.Removed["name_history"]++
+			return true
+		}
+		return false
+	})
+
+	for id := range ids {
+		if _, ok := t.Players[id]; ok {
+			delete(t.Players, id)
+			report.Players++
+		}
+	}
+
+	return report
+}
+
+func (r *memRepository) GetPlayerTotals(ctx context.Context) (*PlayerTotals, error) {
+	totals := &PlayerTotals{ByClass: make(map[string]int64)}
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Players {
+			if row.DeletedAt != nil {
+				continue
+			}
+			totals.ByClass[row.Class]++
+			totals.Gold += int64(row.Gold)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
@@ -0,0 +1,85 @@
+package player
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/validate"
+)
+
+var readOnlyFields = map[string]bool{
+	"id":         true,
+	"username":   true,
+	"account_id": true,
+	"version":    true,
+	"renamed_at": true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// ParsePatch reads a JSON Merge Patch (RFC 7396) document for a player into
+// the update it describes. Only class, level and gold can be patched; none of
+// them can be removed, so a null value is rejected like any other invalid one.
+func ParsePatch(id int32, data []byte) (UpdatePlayerParams, error) {
+	args := UpdatePlayerParams{ID: id}
+
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return args, apperr.Invalid("patch must be a JSON object")
+	}
+
+	fields := make([]string, 0, len(doc))
+	for field := range doc {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var verr apperr.Fields
+
+	for _, field := range fields {
+		raw := doc[field]
+		if string(raw) == "null" {
+			if readOnlyFields[field] {
+				verr.Add(field, "field is read-only")
+			} else {
+				verr.Add(field, "field cannot be removed")
+			}
+			continue
+		}
+
+		switch field {
+		case "class":
+			var class string
+			if err := json.Unmarshal(raw, &class); err != nil {
+				verr.Add(field, "must be a string")
+				continue
+			}
+			args.Class = &class
+		case "level":
+			var level int32
+			if err := json.Unmarshal(raw, &level); err != nil {
+				verr.Add(field, "must be an integer")
+				continue
+			}
+			args.Level = &level
+		case "gold":
+			var gold int32
+			if err := json.Unmarshal(raw, &gold); err != nil {
+				verr.Add(field, "must be an integer")
+				continue
+			}
+			args.Gold = &gold
+		default:
+			if readOnlyFields[field] {
+				verr.Add(field, "field is read-only")
+			} else {
+				verr.Add(field, "unknown field")
+			}
+		}
+	}
+
+	verr = append(verr, validate.Struct(args)...)
+
+	return args, verr.Err("player")
+}
@@ -3,11 +3,31 @@ package player
 import "time"
 
 type Player struct {
-	ID        int32     `json:"id"`
+	ID        int32      `json:"id"`
+	Username  string     `json:"username"`
+	Class     string     `json:"class"`
+	Level     int32      `json:"level"`
+	Gold      int32      `json:"gold"`
+	AccountID *int32     `json:"account_id"`
+	Version   int32      `json:"version"`
+	RenamedAt *time.Time `json:"renamed_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// NameChange records a username a player gave up. The name stays reserved for
+// that player until HeldUntil.
+type NameChange struct {
 	Username  string    `json:"username"`
-	Class     string    `json:"class"`
-	Level     int32     `json:"level"`
-	Gold      int32     `json:"gold"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ChangedAt time.Time `json:"changed_at"`
+	HeldUntil time.Time `json:"held_until"`
+}
+
+// DeletionReport says what permanently deleting players did. Removed counts
+// the deleted rows of each kind of owned record; Retained counts the ledger
+// records that were kept, still carrying the deleted players' ids.
+type DeletionReport struct {
+	Players  int64            `json:"players"`
+	Removed  map[string]int64 `json:"removed"`
+	Retained map[string]int64 `json:"retained"`
 }
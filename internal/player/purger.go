@@ -0,0 +1,40 @@
+package player
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Purger periodically hard-deletes players whose restore grace period has run
+// out.
+type Purger struct {
+	service  *PlayerService
+	interval time.Duration
+}
+
+func NewPurger(service *PlayerService, interval time.Duration) *Purger {
+	return &Purger{service: service, interval: interval}
+}
+
+// Run purges on every tick until ctx is cancelled.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := p.service.PurgeDeletedPlayers(ctx)
+			if err != nil {
+				log.Printf("Error purging deleted players: %s", err)
+				continue
+			}
+			if report.Players > 0 {
+				log.Printf("Purged %d deleted players, removed %v", report.Players, report.Removed)
+			}
+		}
+	}
+}
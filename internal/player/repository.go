@@ -2,61 +2,101 @@ package player
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// ErrVersionMismatch is returned by conditional writes when the player was
+// changed since the caller read it.
+var ErrVersionMismatch = apperr.New(apperr.KindPreconditionFailed, "player was modified by another request")
+
+// ErrInsufficientFunds is returned when taking more gold than a player has.
+var ErrInsufficientFunds = apperr.New(apperr.KindInsufficientFunds, "insufficient funds")
+
+// ErrRestoreExpired is returned when restoring a player whose grace period
+// has already run out.
+var ErrRestoreExpired = apperr.New(apperr.KindGone, "player was deleted too long ago to be restored")
+
 type PlayerRepository interface {
 	CreatePlayer(ctx context.Context, args CreatePlayerParams) (*Player, error)
-	GetAllPlayers(ctx context.Context) ([]*Player, error)
+	ListPlayers(ctx context.Context, args ListPlayersParams) (*listing.Page[*Player], error)
 	GetPlayerByID(ctx context.Context, id int32) (*Player, error)
 	GetPlayerByUsername(ctx context.Context, username string) (*Player, error)
+	UpdatePlayer(ctx context.Context, args UpdatePlayerParams) (*Player, error)
+	RenamePlayer(ctx context.Context, args RenamePlayerParams) (*Player, error)
+	ListNameHistory(ctx context.Context, playerID int32) ([]*NameChange, error)
 	UpdatePlayerLevel(ctx context.Context, args UpdatePlayerLevelParams) error
 	IncreasePlayerGold(ctx context.Context, args UpdatePlayerGoldParams) error
 	DecreasePlayerGold(ctx context.Context, args UpdatePlayerGoldParams) error
-	DeletePlayerByID(ctx context.Context, id int32) error
+	DeletePlayerByID(ctx context.Context, args DeletePlayerParams) error
+	RestorePlayer(ctx context.Context, args RestorePlayerParams) (*Player, error)
+	PurgePlayer(ctx context.Context, args PurgePlayerParams) (*DeletionReport, error)
+	PurgeDeletedPlayers(ctx context.Context, before time.Time) (*DeletionReport, error)
+	GetPlayerTotals(ctx context.Context) (*PlayerTotals, error)
 }
 
 type pgRepository struct {
-	db *pgx.Conn
+	db *database.DB
 }
 
-func NewPostgresRepository(db *pgx.Conn) PlayerRepository {
+func NewPostgresRepository(db *database.DB) PlayerRepository {
 	return &pgRepository{db: db}
 }
 
 const createPlayer = `
-INSERT INTO player (username, class, level, gold, created_at, updated_at)
-VALUES ($1, $2, 1, 0, now(), now())
-RETURNING id, username, class, level, gold, created_at, updated_at
+INSERT INTO player (username, username_key, class, level, gold, created_at, updated_at)
+VALUES ($1, $3, $2, 1, $4, now(), now())
+RETURNING id, username, class, level, gold, account_id, version, renamed_at, created_at, updated_at
 `
 
 type CreatePlayerParams struct {
-	Username string `json:"username"`
-	Class    string `json:"class"`
+	Username    string `json:"username" validate:"required,min=3,max=20,charset=username"`
+	Class       string `json:"class" validate:"required,oneof=Warrior Rogue Mage Sorcerer Druid"`
+	UsernameKey string `json:"-"`
+	Gold        int32  `json:"-"`
 }
 
 func (r *pgRepository) CreatePlayer(ctx context.Context, args CreatePlayerParams) (*Player, error) {
+	ctx = querylabel.With(ctx, "player", "CreatePlayer")
+
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("beginning transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
+	if err = claimUsername(ctx, tx, args.UsernameKey, 0); err != nil {
+		return nil, err
+	}
+
 	var p Player
 
-	row := tx.QueryRow(ctx, createPlayer, args.Username, args.Class)
+	row := tx.QueryRow(ctx, createPlayer, args.Username, args.Class, args.UsernameKey, args.Gold)
 	err = row.Scan(
 		&p.ID,
 		&p.Username,
 		&p.Class,
 		&p.Level,
 		&p.Gold,
+		&p.AccountID,
+		&p.Version,
+		&p.RenamedAt,
 		&p.CreatedAt,
 		&p.UpdatedAt,
 	)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrUsernameInUse
+		}
 		return nil, fmt.Errorf("scanning row into player struct: %w", err)
 	}
 
@@ -67,14 +107,113 @@ func (r *pgRepository) CreatePlayer(ctx context.Context, args CreatePlayerParams
 	return &p, nil
 }
 
-const getAllPlayers = `
-SELECT id, username, class, level, gold, created_at, updated_at FROM player
+const lockUsername = `
+SELECT pg_advisory_xact_lock(hashtext('player_username:' || $1))
+`
+
+const usernameHeld = `
+SELECT EXISTS (
+  SELECT 1 FROM player_name_history
+  WHERE username_key = $1 AND held_until > now() AND player_id <> $2
+)
 `
 
-func (r *pgRepository) GetAllPlayers(ctx context.Context) ([]*Player, error) {
-	rows, err := r.db.Query(ctx, getAllPlayers)
+// claimUsername serializes transactions that want the same username key and
+// fails with ErrUsernameInUse if another player is holding it after a rename.
+// Names taken by a current player are caught by the unique index instead.
+func claimUsername(ctx context.Context, tx pgx.Tx, key string, playerID int32) error {
+	if _, err := tx.Exec(ctx, lockUsername, key); err != nil {
+		return fmt.Errorf("locking username: %w", err)
+	}
+
+	var held bool
+	if err := tx.QueryRow(ctx, usernameHeld, key, playerID).Scan(&held); err != nil {
+		return fmt.Errorf("checking username holds: %w", err)
+	}
+	if held {
+		return ErrUsernameInUse
+	}
+
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+const listPlayers = `
+SELECT id, username, class, level, gold, account_id, version, renamed_at, created_at, updated_at FROM player`
+
+var playerSortColumns = map[string]listing.Column{
+	"id":         {Expr: "id", Type: listing.Int},
+	"username":   {Expr: "username", Type: listing.Text},
+	"level":      {Expr: "level", Type: listing.Int},
+	"gold":       {Expr: "gold", Type: listing.Int},
+	"created_at": {Expr: "created_at", Type: listing.Timestamp},
+}
+
+var PlayerSortFields = []listing.Field{
+	{Name: "id", Type: listing.Int},
+	{Name: "username", Type: listing.Text},
+	{Name: "level", Type: listing.Int},
+	{Name: "gold", Type: listing.Int},
+	{Name: "created_at", Type: listing.Timestamp},
+}
+
+type ListPlayersParams struct {
+	listing.Params
+	Class      string
+	MinLevel   *int32
+	MaxLevel   *int32
+	NamePrefix string
+}
+
+func playerSortKey(sort string) func(*Player) (string, string) {
+	return func(p *Player) (string, string) {
+		id := strconv.Itoa(int(p.ID))
+		switch sort {
+		case "username":
+			return p.Username, id
+		case "level":
+			return strconv.Itoa(int(p.Level)), id
+		case "gold":
+			return strconv.Itoa(int(p.Gold)), id
+		case "created_at":
+			return p.CreatedAt.Format(time.RFC3339Nano), id
+		default:
+			return id, id
+		}
+	}
+}
+
+func (r *pgRepository) ListPlayers(ctx context.Context, args ListPlayersParams) (*listing.Page[*Player], error) {
+	ctx = querylabel.With(ctx, "player", "ListPlayers")
+
+	sort, ok := playerSortColumns[args.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field '%v'", args.Sort)
+	}
+
+	var q listing.Query
+	q.Where("deleted_at IS NULL")
+	if args.Class != "" {
+		q.Where("class = " + q.Arg(args.Class))
+	}
+	if args.MinLevel != nil {
+		q.Where("level >= " + q.Arg(*args.MinLevel))
+	}
+	if args.MaxLevel != nil {
+		q.Where("level <= " + q.Arg(*args.MaxLevel))
+	}
+	if args.NamePrefix != "" {
+		q.Where("username LIKE " + q.Arg(listing.LikePrefix(args.NamePrefix)) + ` ESCAPE '\'`)
+	}
+	query := q.Build(listPlayers, args.Params, sort, playerSortColumns["id"])
+
+	rows, err := r.db.Query(ctx, query, q.Args()...)
 	if err != nil {
-		return nil, fmt.Errorf("querying for all players: %w", err)
+		return nil, fmt.Errorf("querying for players: %w", err)
 	}
 	defer rows.Close()
 
@@ -89,6 +228,9 @@ func (r *pgRepository) GetAllPlayers(ctx context.Context) ([]*Player, error) {
 			&p.Class,
 			&p.Level,
 			&p.Gold,
+			&p.AccountID,
+			&p.Version,
+			&p.RenamedAt,
 			&p.CreatedAt,
 			&p.UpdatedAt,
 		)
@@ -98,15 +240,20 @@ func (r *pgRepository) GetAllPlayers(ctx context.Context) ([]*Player, error) {
 
 		ps = append(ps, &p)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return ps, nil
+	return listing.NewPage(ps, args.Params, playerSortKey(args.Sort)), nil
 }
 
 const getPlayerByID = `
-SELECT id, username, class, level, gold, created_at, updated_at FROM player WHERE id = $1
+SELECT id, username, class, level, gold, account_id, version, renamed_at, created_at, updated_at FROM player WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (r *pgRepository) GetPlayerByID(ctx context.Context, id int32) (*Player, error) {
+	ctx = querylabel.With(ctx, "player", "GetPlayerByID")
+
 	var p Player
 
 	row := r.db.QueryRow(ctx, getPlayerByID, id)
@@ -116,6 +263,9 @@ func (r *pgRepository) GetPlayerByID(ctx context.Context, id int32) (*Player, er
 		&p.Class,
 		&p.Level,
 		&p.Gold,
+		&p.AccountID,
+		&p.Version,
+		&p.RenamedAt,
 		&p.CreatedAt,
 		&p.UpdatedAt,
 	)
@@ -130,10 +280,12 @@ func (r *pgRepository) GetPlayerByID(ctx context.Context, id int32) (*Player, er
 }
 
 const getPlayerByUsername = `
-SELECT id, username, class, level, gold, created_at, updated_at FROM player WHERE username = $1
+SELECT id, username, class, level, gold, account_id, version, renamed_at, created_at, updated_at FROM player WHERE lower(username) = lower($1) AND deleted_at IS NULL
 `
 
 func (r *pgRepository) GetPlayerByUsername(ctx context.Context, username string) (*Player, error) {
+	ctx = querylabel.With(ctx, "player", "GetPlayerByUsername")
+
 	var p Player
 
 	row := r.db.QueryRow(ctx, getPlayerByUsername, username)
@@ -143,6 +295,9 @@ func (r *pgRepository) GetPlayerByUsername(ctx context.Context, username string)
 		&p.Class,
 		&p.Level,
 		&p.Gold,
+		&p.AccountID,
+		&p.Version,
+		&p.RenamedAt,
 		&p.CreatedAt,
 		&p.UpdatedAt,
 	)
@@ -156,8 +311,189 @@ func (r *pgRepository) GetPlayerByUsername(ctx context.Context, username string)
 	return &p, nil
 }
 
+const updatePlayer = `
+UPDATE player SET
+  class = COALESCE($2, class),
+  level = COALESCE($3, level),
+  gold = CASE WHEN $4::int IS NULL THEN gold
+    ELSE LEAST($4::int, COALESCE((SELECT cap FROM currency WHERE code = 'gold'), 2147483647)) END,
+  version = version + 1,
+  updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL AND ($5::int IS NULL OR version = $5)
+RETURNING id, username, class, level, gold, account_id, version, renamed_at, created_at, updated_at
+`
+
+// UpdatePlayerParams describes a partial update: nil fields are left as they
+// are. When Version is set the update only applies if the player is still at
+// that version. Gold is clamped to the cap of the gold currency, as credits
+// are by IncreaseGoldSQL.
+type UpdatePlayerParams struct {
+	ID      int32   `json:"id"`
+	Class   *string `json:"class" validate:"oneof=Warrior Rogue Mage Sorcerer Druid"`
+	Level   *int32  `json:"level" validate:"min=1,max=100"`
+	Gold    *int32  `json:"gold" validate:"min=0"`
+	Version *int32  `json:"-"`
+}
+
+func (r *pgRepository) UpdatePlayer(ctx context.Context, args UpdatePlayerParams) (*Player, error) {
+	ctx = querylabel.With(ctx, "player", "UpdatePlayer")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var p Player
+
+	row := tx.QueryRow(ctx, updatePlayer, args.ID, args.Class, args.Level, args.Gold, args.Version)
+	err = row.Scan(
+		&p.ID,
+		&p.Username,
+		&p.Class,
+		&p.Level,
+		&p.Gold,
+		&p.AccountID,
+		&p.Version,
+		&p.RenamedAt,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, r.missOrConflict(ctx, tx, args.ID)
+		}
+		return nil, fmt.Errorf("scanning row into player struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return &p, nil
+}
+
+const getPlayerForRename = `
+SELECT username, username_key, renamed_at FROM player WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+`
+
+const renamePlayer = `
+UPDATE player SET
+  username = $2,
+  username_key = $3,
+  renamed_at = now(),
+  version = version + 1,
+  updated_at = now()
+WHERE id = $1
+RETURNING id, username, class, level, gold, account_id, version, renamed_at, created_at, updated_at
+`
+
+const addNameHistory = `
+INSERT INTO player_name_history (player_id, username, username_key, changed_at, held_until)
+VALUES ($1, $2, $3, now(), now() + $4 * interval '1 second')
+`
+
+type RenamePlayerParams struct {
+	ID          int32         `json:"id"`
+	Username    string        `json:"username" validate:"required,min=3,max=20,charset=username"`
+	UsernameKey string        `json:"-"`
+	Cooldown    time.Duration `json:"-"`
+	HoldPeriod  time.Duration `json:"-"`
+}
+
+// RenamePlayer changes a player's username and records the old one in the
+// name history, held for HoldPeriod. It fails if the player was renamed less
+// than Cooldown ago and returns nil if the player does not exist.
+func (r *pgRepository) RenamePlayer(ctx context.Context, args RenamePlayerParams) (*Player, error) {
+	ctx = querylabel.With(ctx, "player", "RenamePlayer")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var oldName, oldKey string
+	var renamedAt *time.Time
+
+	err = tx.QueryRow(ctx, getPlayerForRename, args.ID).Scan(&oldName, &oldKey, &renamedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("locking player for rename: %w", err)
+	}
+
+	if renamedAt != nil && time.Since(*renamedAt) < args.Cooldown {
+		return nil, cooldownErr(*renamedAt, args.Cooldown)
+	}
+
+	if err = claimUsername(ctx, tx, args.UsernameKey, args.ID); err != nil {
+		return nil, err
+	}
+
+	var p Player
+
+	row := tx.QueryRow(ctx, renamePlayer, args.ID, args.Username, args.UsernameKey)
+	err = row.Scan(
+		&p.ID,
+		&p.Username,
+		&p.Class,
+		&p.Level,
+		&p.Gold,
+		&p.AccountID,
+		&p.Version,
+		&p.RenamedAt,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrUsernameInUse
+		}
+		return nil, fmt.Errorf("scanning row into player struct: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, addNameHistory, args.ID, oldName, oldKey, args.HoldPeriod.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("recording name history: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return &p, nil
+}
+
+const listNameHistory = `
+SELECT username, changed_at, held_until FROM player_name_history
+WHERE player_id = $1
+ORDER BY changed_at DESC, id DESC
+`
+
+func (r *pgRepository) ListNameHistory(ctx context.Context, playerID int32) ([]*NameChange, error) {
+	ctx = querylabel.With(ctx, "player", "ListNameHistory")
+
+	rows, err := r.db.Query(ctx, listNameHistory, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("querying for name history: %w", err)
+	}
+
+	history, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*NameChange, error) {
+		var c NameChange
+		err := row.Scan(&c.Username, &c.ChangedAt, &c.HeldUntil)
+		return &c, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning rows into name change struct: %w", err)
+	}
+
+	return history, nil
+}
+
 const updatePlayerLevel = `
-UPDATE player SET level = $2 WHERE id = $1
+UPDATE player SET level = $2, version = version + 1, updated_at = now() WHERE id = $1 AND deleted_at IS NULL
 `
 
 type UpdatePlayerLevelParams struct {
@@ -166,6 +502,8 @@ type UpdatePlayerLevelParams struct {
 }
 
 func (r *pgRepository) UpdatePlayerLevel(ctx context.Context, args UpdatePlayerLevelParams) error {
+	ctx = querylabel.With(ctx, "player", "UpdatePlayerLevel")
+
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
@@ -189,19 +527,39 @@ type UpdatePlayerGoldParams struct {
 	Amount int32 `json:"amount"`
 }
 
-const increasePlayerGold = `
-UPDATE player SET gold = gold + $2 WHERE id = $1
+// IncreaseGoldSQL and DecreaseGoldSQL are the queries every repository that
+// credits or debits a player's gold runs, so that gold behaves the same
+// whether it is reached as a player field or as the gold currency. Credits
+// are clamped to the cap of the gold currency, anything above it being lost,
+// and debits that would take gold below zero match no row. Both return the
+// new amount, and no row for a missing or deleted player.
+const (
+	IncreaseGoldSQL = `
+UPDATE player
+SET gold = LEAST(gold::bigint + $2, COALESCE((SELECT cap FROM currency WHERE code = 'gold'), 2147483647))::int,
+  version = version + 1, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING gold
 `
+	DecreaseGoldSQL = `
+UPDATE player SET gold = gold - $2, version = version + 1, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL AND gold >= $2
+RETURNING gold
+`
+)
 
 func (r *pgRepository) IncreasePlayerGold(ctx context.Context, args UpdatePlayerGoldParams) error {
+	ctx = querylabel.With(ctx, "player", "IncreasePlayerGold")
+
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = r.db.Exec(ctx, increasePlayerGold, args.ID, args.Amount)
-	if err != nil {
+	var gold int32
+	err = tx.QueryRow(ctx, IncreaseGoldSQL, args.ID, args.Amount).Scan(&gold)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		return fmt.Errorf("increasing player gold: %w", err)
 	}
 
@@ -212,19 +570,21 @@ func (r *pgRepository) IncreasePlayerGold(ctx context.Context, args UpdatePlayer
 	return nil
 }
 
-const decreasePlayerGold = `
-UPDATE player SET gold = gold - $2 WHERE id = $1
-`
-
 func (r *pgRepository) DecreasePlayerGold(ctx context.Context, args UpdatePlayerGoldParams) error {
+	ctx = querylabel.With(ctx, "player", "DecreasePlayerGold")
+
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, decreasePlayerGold, args.ID, args.Amount)
+	var gold int32
+	err = tx.QueryRow(ctx, DecreaseGoldSQL, args.ID, args.Amount).Scan(&gold)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInsufficientFunds
+		}
 		return fmt.Errorf("decreasing player gold: %w", err)
 	}
 
@@ -236,20 +596,33 @@ func (r *pgRepository) DecreasePlayerGold(ctx context.Context, args UpdatePlayer
 }
 
 const deletePlayerByID = `
-DELETE FROM player WHERE id = $1
+UPDATE player SET deleted_at = now(), version = version + 1, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL AND ($2::int IS NULL OR version = $2)
 `
 
-func (r *pgRepository) DeletePlayerByID(ctx context.Context, id int32) error {
+type DeletePlayerParams struct {
+	ID      int32  `json:"id"`
+	Version *int32 `json:"-"`
+}
+
+// DeletePlayerByID soft-deletes a player: it is hidden from every other query
+// until it is restored or purged.
+func (r *pgRepository) DeletePlayerByID(ctx context.Context, args DeletePlayerParams) error {
+	ctx = querylabel.With(ctx, "player", "DeletePlayerByID")
+
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("beginning transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	_, err = tx.Exec(ctx, deletePlayerByID, id)
+	tag, err := tx.Exec(ctx, deletePlayerByID, args.ID, args.Version)
 	if err != nil {
 		return fmt.Errorf("deleting player: %w", err)
 	}
+	if tag.RowsAffected() == 0 {
+		return r.missOrConflict(ctx, tx, args.ID)
+	}
 
 	if err = tx.Commit(ctx); err != nil {
 		return fmt.Errorf("commiting transaction: %w", err)
@@ -257,3 +630,271 @@ func (r *pgRepository) DeletePlayerByID(ctx context.Context, id int32) error {
 
 	return nil
 }
+
+const getDeletedAt = `
+SELECT deleted_at FROM player WHERE id = $1 FOR UPDATE
+`
+
+const restorePlayer = `
+UPDATE player SET deleted_at = NULL, version = version + 1, updated_at = now()
+WHERE id = $1
+RETURNING id, username, class, level, gold, account_id, version, renamed_at, created_at, updated_at
+`
+
+type RestorePlayerParams struct {
+	ID          int32         `json:"id"`
+	GracePeriod time.Duration `json:"-"`
+}
+
+// RestorePlayer undoes a soft delete made less than GracePeriod ago. It
+// returns nil if there is no deleted player with the given id.
+func (r *pgRepository) RestorePlayer(ctx context.Context, args RestorePlayerParams) (*Player, error) {
+	ctx = querylabel.With(ctx, "player", "RestorePlayer")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var deletedAt *time.Time
+
+	err = tx.QueryRow(ctx, getDeletedAt, args.ID).Scan(&deletedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("locking player for restore: %w", err)
+	}
+	if deletedAt == nil {
+		return nil, nil
+	}
+	if time.Since(*deletedAt) > args.GracePeriod {
+		return nil, ErrRestoreExpired
+	}
+
+	var p Player
+
+	row := tx.QueryRow(ctx, restorePlayer, args.ID)
+	err = row.Scan(
+		&p.ID,
+		&p.Username,
+		&p.Class,
+		&p.Level,
+		&p.Gold,
+		&p.AccountID,
+		&p.Version,
+		&p.RenamedAt,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("scanning row into player struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return &p, nil
+}
+
+const lockPlayerForPurge = `
+SELECT version FROM player WHERE id = $1 FOR UPDATE
+`
+
+type PurgePlayerParams struct {
+	ID      int32  `json:"id"`
+	Version *int32 `json:"-"`
+}
+
+// PurgePlayer permanently deletes a player, live or soft-deleted, with
+// everything it owns. It returns nil if the player does not exist.
+func (r *pgRepository) PurgePlayer(ctx context.Context, args PurgePlayerParams) (*DeletionReport, error) {
+	ctx = querylabel.With(ctx, "player", "PurgePlayer")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var version int32
+
+	err = tx.QueryRow(ctx, lockPlayerForPurge, args.ID).Scan(&version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("locking player for purge: %w", err)
+	}
+	if args.Version != nil && *args.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	report, err := purgePlayers(ctx, tx, []int32{args.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return report, nil
+}
+
+const lockPurgeablePlayers = `
+SELECT id FROM player WHERE deleted_at < $1 FOR UPDATE
+`
+
+// PurgeDeletedPlayers permanently deletes the players soft-deleted before the
+// given time, with everything they own.
+func (r *pgRepository) PurgeDeletedPlayers(ctx context.Context, before time.Time) (*DeletionReport, error) {
+	ctx = querylabel.With(ctx, "player", "PurgeDeletedPlayers")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, lockPurgeablePlayers, before)
+	if err != nil {
+		return nil, fmt.Errorf("locking deleted players: %w", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+	if err != nil {
+		return nil, fmt.Errorf("scanning deleted player ids: %w", err)
+	}
+
+	report, err := purgePlayers(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return report, nil
+}
+
+// ownedRecords lists, in dependency order, the statements that delete what
+// the players in $1 own, keyed by the name used in the DeletionReport.
+var ownedRecords = []struct {
+	kind string
+	stmt string
+}{
+	{"pending_pvp_matches", `DELETE FROM pvp_match WHERE status = 'pending' AND (player_a_id = ANY($1) OR player_b_id = ANY($1))`},
+	{"pvp_rating", `DELETE FROM pvp_rating WHERE player_id = ANY($1)`},
+	{"bank_items", `DELETE FROM bank_item WHERE player_id = ANY($1)`},
+	{"bank", `DELETE FROM bank WHERE player_id = ANY($1)`},
+	{"currencies", `DELETE FROM player_currency WHERE player_id = ANY($1)`},
+	{"location", `DELETE FROM player_location WHERE player_id = ANY($1)`},
+	{"effects", `DELETE FROM player_effect WHERE player_id = ANY($1)`},
+	{"inventory", `DELETE FROM inventory WHERE player_id = ANY($1)`},
+	{"name_history", `DELETE FROM player_name_history WHERE player_id = ANY($1)`},
+}
+
+// ledgerRecords counts the records that are kept when their players are
+// deleted: purchases and finished matches are part of the game's history and
+// stay under the deleted player's id. Matches still pending are removed.
+var ledgerRecords = []struct {
+	kind  string
+	query string
+}{
+	{"purchase_receipts", `SELECT count(*) FROM purchase_receipt WHERE player_id = ANY($1)`},
+	{"pvp_matches", `SELECT count(*) FROM pvp_match WHERE status <> 'pending' AND (player_a_id = ANY($1) OR player_b_id = ANY($1))`},
+}
+
+const deletePlayers = `
+DELETE FROM player WHERE id = ANY($1)
+`
+
+func purgePlayers(ctx context.Context, tx pgx.Tx, ids []int32) (*DeletionReport, error) {
+	report := &DeletionReport{
+		Removed:  make(map[string]int64, len(ownedRecords)),
+		Retained: make(map[string]int64, len(ledgerRecords)),
+	}
+	if len(ids) == 0 {
+		return report, nil
+	}
+
+	for _, rec := range ledgerRecords {
+		var n int64
+		if err := tx.QueryRow(ctx, rec.query, ids).Scan(&n); err != nil {
+			return nil, fmt.Errorf("counting player %v: %w", rec.kind, err)
+		}
+		report.Retained[rec.kind] = n
+	}
+
+	for _, rec := range ownedRecords {
+		tag, err := tx.Exec(ctx, rec.stmt, ids)
+		if err != nil {
+			return nil, fmt.Errorf("deleting player %v: %w", rec.kind, err)
+		}
+		report.Removed[rec.kind] = tag.RowsAffected()
+	}
+
+	tag, err := tx.Exec(ctx, deletePlayers, ids)
+	if err != nil {
+		return nil, fmt.Errorf("deleting players: %w", err)
+	}
+	report.Players = tag.RowsAffected()
+
+	return report, nil
+}
+
+// PlayerTotals sums up the players that are not deleted.
+type PlayerTotals struct {
+	ByClass map[string]int64
+	Gold    int64
+}
+
+const getPlayerTotals = `
+SELECT class, count(*), COALESCE(sum(gold), 0) FROM player
+WHERE deleted_at IS NULL
+GROUP BY class
+`
+
+func (r *pgRepository) GetPlayerTotals(ctx context.Context) (*PlayerTotals, error) {
+	ctx = querylabel.With(ctx, "player", "GetPlayerTotals")
+
+	rows, err := r.db.Query(ctx, getPlayerTotals)
+	if err != nil {
+		return nil, fmt.Errorf("querying for player totals: %w", err)
+	}
+
+	totals := &PlayerTotals{ByClass: make(map[string]int64)}
+	var class string
+	var count, gold int64
+	_, err = pgx.ForEachRow(rows, []any{&class, &count, &gold}, func() error {
+		totals.ByClass[class] = count
+		totals.Gold += gold
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning player totals: %w", err)
+	}
+
+	return totals, nil
+}
+
+const playerExists = `
+SELECT EXISTS (SELECT 1 FROM player WHERE id = $1 AND deleted_at IS NULL)
+`
+
+// missOrConflict explains why a conditional write matched no row: nil if the
+// player does not exist, ErrVersionMismatch if it exists at another version.
+func (r *pgRepository) missOrConflict(ctx context.Context, tx pgx.Tx, id int32) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, playerExists, id).Scan(&exists); err != nil {
+		return fmt.Errorf("checking if player exists: %w", err)
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return nil
+}
@@ -2,38 +2,39 @@ package player
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/validate"
 )
 
 type PlayerService struct {
-	repo PlayerRepository
-}
-
-func NewPlayerService(repo PlayerRepository) *PlayerService {
-	return &PlayerService{repo: repo}
+	repo         PlayerRepository
+	policy       UsernamePolicy
+	gracePeriod  time.Duration
+	startingGold int32
 }
 
-type NotFoundErr struct {
-	resource  string
-	attribute string
-	value     any
+// NewPlayerService returns a PlayerService. New players start with
+// startingGold. Deleted players can be restored for gracePeriod, after which
+// they are left to PurgeDeletedPlayers.
+func NewPlayerService(repo PlayerRepository, policy UsernamePolicy, gracePeriod time.Duration, startingGold int32) *PlayerService {
+	return &PlayerService{repo: repo, policy: policy, gracePeriod: gracePeriod, startingGold: startingGold}
 }
 
-func (e *NotFoundErr) Error() string {
-	return fmt.Sprintf("%s with %s '%v' not found", e.resource, e.attribute, e.value)
-}
+var ErrUsernameInUse = apperr.Conflict("username already in use")
 
 func (s *PlayerService) CreatePlayer(ctx context.Context, username, class string) (*Player, error) {
-	p, err := s.repo.GetPlayerByUsername(ctx, username)
-	if err == nil && p != nil {
-		return nil, errors.New("username already in use")
-	}
-	if err != nil {
-		return nil, fmt.Errorf("creating player: %w", err)
+	username = NormalizeUsername(username)
+
+	args := CreatePlayerParams{Username: username, Class: class, UsernameKey: UsernameKey(username), Gold: s.startingGold}
+	if err := s.checkUsername(args, username); err != nil {
+		return nil, err
 	}
 
-	newPlayer, err := s.repo.CreatePlayer(ctx, CreatePlayerParams{Username: username, Class: class})
+	newPlayer, err := s.repo.CreatePlayer(ctx, args)
 	if err != nil {
 		return nil, fmt.Errorf("creating new player: %w", err)
 	}
@@ -41,8 +42,22 @@ func (s *PlayerService) CreatePlayer(ctx context.Context, username, class string
 	return newPlayer, nil
 }
 
-func (s *PlayerService) GetAllPlayers(ctx context.Context) ([]*Player, error) {
-	return s.repo.GetAllPlayers(ctx)
+// checkUsername validates args and checks username against the policy,
+// reporting every violation together.
+func (s *PlayerService) checkUsername(args any, username string) error {
+	fields := validate.Struct(args)
+	fields = append(fields, s.policy.Check(username)...)
+
+	return fields.Err("player")
+}
+
+func (s *PlayerService) ListPlayers(ctx context.Context, args ListPlayersParams) (*listing.Page[*Player], error) {
+	page, err := s.repo.ListPlayers(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("listing players: %w", err)
+	}
+
+	return page, nil
 }
 
 func (s *PlayerService) GetPlayerByID(ctx context.Context, id int32) (*Player, error) {
@@ -51,24 +66,88 @@ func (s *PlayerService) GetPlayerByID(ctx context.Context, id int32) (*Player, e
 		return nil, fmt.Errorf("getting player with id %v: %w", id, err)
 	}
 	if player == nil {
-		return nil, &NotFoundErr{resource: "player", attribute: "id", value: id}
+		return nil, apperr.NotFound("player", "id", id)
 	}
 
 	return player, nil
 }
 
+// GetPlayerByUsername looks a player up by username, ignoring case.
 func (s *PlayerService) GetPlayerByUsername(ctx context.Context, username string) (*Player, error) {
+	username = NormalizeUsername(username)
+
 	player, err := s.repo.GetPlayerByUsername(ctx, username)
 	if err != nil {
 		return nil, fmt.Errorf("getting player with username '%v': %w", username, err)
 	}
 	if player == nil {
-		return nil, &NotFoundErr{resource: "player", attribute: "username", value: username}
+		return nil, apperr.NotFound("player", "username", username)
 	}
 
 	return player, nil
 }
 
+// UpdatePlayer applies a partial update to a player and returns the updated
+// player.
+func (s *PlayerService) UpdatePlayer(ctx context.Context, args UpdatePlayerParams) (*Player, error) {
+	if err := validate.Struct(args).Err("player"); err != nil {
+		return nil, err
+	}
+
+	p, err := s.repo.UpdatePlayer(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("updating player with id %v: %w", args.ID, err)
+	}
+	if p == nil {
+		return nil, apperr.NotFound("player", "id", args.ID)
+	}
+
+	return p, nil
+}
+
+// RenamePlayer changes a player's username, subject to the username policy
+// and its rename cooldown.
+func (s *PlayerService) RenamePlayer(ctx context.Context, id int32, username string) (*Player, error) {
+	username = NormalizeUsername(username)
+
+	args := RenamePlayerParams{
+		ID:          id,
+		Username:    username,
+		UsernameKey: UsernameKey(username),
+		Cooldown:    s.policy.RenameCooldown,
+		HoldPeriod:  s.policy.HoldPeriod,
+	}
+	if err := s.checkUsername(args, username); err != nil {
+		return nil, err
+	}
+
+	p, err := s.repo.RenamePlayer(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("renaming player with id %v: %w", id, err)
+	}
+	if p == nil {
+		return nil, apperr.NotFound("player", "id", id)
+	}
+
+	return p, nil
+}
+
+func (s *PlayerService) ListNameHistory(ctx context.Context, id int32) ([]*NameChange, error) {
+	if _, err := s.GetPlayerByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	history, err := s.repo.ListNameHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting name history for player with id %v: %w", id, err)
+	}
+	if history == nil {
+		history = []*NameChange{}
+	}
+
+	return history, nil
+}
+
 func (s *PlayerService) UpdatePlayerLevel(ctx context.Context, id, level int32) error {
 	err := s.repo.UpdatePlayerLevel(ctx, UpdatePlayerLevelParams{ID: id, Level: level})
 	if err != nil {
@@ -95,11 +174,65 @@ func (s *PlayerService) DecreasePlayerGold(ctx context.Context, id, amount int32
 	return nil
 }
 
-func (s *PlayerService) DeletePlayerByID(ctx context.Context, id int32) error {
-	err := s.repo.DeletePlayerByID(ctx, id)
+// DeletePlayerByID soft-deletes a player. A non-nil version makes the delete
+// conditional on the player still being at that version.
+func (s *PlayerService) DeletePlayerByID(ctx context.Context, id int32, version *int32) error {
+	err := s.repo.DeletePlayerByID(ctx, DeletePlayerParams{ID: id, Version: version})
 	if err != nil {
 		return fmt.Errorf("deleting player with id %v: %w", id, err)
 	}
 
 	return nil
 }
+
+// RestorePlayer brings back a deleted player if it is still within the grace
+// period.
+func (s *PlayerService) RestorePlayer(ctx context.Context, id int32) (*Player, error) {
+	p, err := s.repo.RestorePlayer(ctx, RestorePlayerParams{ID: id, GracePeriod: s.gracePeriod})
+	if err != nil {
+		return nil, fmt.Errorf("restoring player with id %v: %w", id, err)
+	}
+	if p == nil {
+		return nil, apperr.NotFound("deleted player", "id", id)
+	}
+
+	return p, nil
+}
+
+// PurgePlayer permanently deletes a player, skipping the grace period of a
+// soft delete. Everything the player owns is removed in the same transaction,
+// while purchase receipts and match results are kept for the record; the
+// report says what was done. A non-nil version makes the purge conditional on
+// the player still being at that version.
+func (s *PlayerService) PurgePlayer(ctx context.Context, id int32, version *int32) (*DeletionReport, error) {
+	report, err := s.repo.PurgePlayer(ctx, PurgePlayerParams{ID: id, Version: version})
+	if err != nil {
+		return nil, fmt.Errorf("purging player with id %v: %w", id, err)
+	}
+	if report == nil {
+		return nil, apperr.NotFound("player", "id", id)
+	}
+
+	return report, nil
+}
+
+// PurgeDeletedPlayers permanently deletes the players whose grace period has
+// run out, in the same way as PurgePlayer.
+func (s *PlayerService) PurgeDeletedPlayers(ctx context.Context) (*DeletionReport, error) {
+	report, err := s.repo.PurgeDeletedPlayers(ctx, time.Now().Add(-s.gracePeriod))
+	if err != nil {
+		return nil, fmt.Errorf("purging deleted players: %w", err)
+	}
+
+	return report, nil
+}
+
+// GetPlayerTotals counts the players by class and sums up their gold.
+func (s *PlayerService) GetPlayerTotals(ctx context.Context) (*PlayerTotals, error) {
+	totals, err := s.repo.GetPlayerTotals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting player totals: %w", err)
+	}
+
+	return totals, nil
+}
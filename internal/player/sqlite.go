@@ -0,0 +1,683 @@
+package player
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+	"github.com/hossokawa/go-nethttp-example/internal/sqlite"
+)
+
+type sqliteRepository struct {
+	db *sqlite.DB
+}
+
+// NewSQLiteRepository returns a PlayerRepository that keeps players in an
+// SQLite database. It behaves like the Postgres repository, down to the
+// errors it returns.
+func NewSQLiteRepository(db *sqlite.DB) PlayerRepository {
+	return &sqliteRepository{db: db}
+}
+
+const sqlitePlayerColumns = `id, username, class, level, gold, account_id, version, renamed_at, created_at, updated_at`
+
+func scanSQLitePlayer(row interface{ Scan(...any) error }) (*Player, error) {
+	var p Player
+
+	err := row.Scan(
+		&p.ID,
+		&p.Username,
+		&p.Class,
+		&p.Level,
+		&p.Gold,
+		&p.AccountID,
+		&p.Version,
+		sqlite.NullTime(&p.RenamedAt),
+		sqlite.Time(&p.CreatedAt),
+		sqlite.Time(&p.UpdatedAt),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+const sqliteCreatePlayer = `
+INSERT INTO player (username, username_key, class, level, gold, created_at, updated_at)
+VALUES (?1, ?3, ?2, 1, ?5, ?4, ?4)
+RETURNING ` + sqlitePlayerColumns
+
+func (r *sqliteRepository) CreatePlayer(ctx context.Context, args CreatePlayerParams) (*Player, error) {
+	ctx = querylabel.With(ctx, "player", "CreatePlayer")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = claimSQLiteUsername(ctx, tx, args.UsernameKey, 0); err != nil {
+		return nil, err
+	}
+
+	now := sqlite.Timestamp(time.Now())
+
+	p, err := scanSQLitePlayer(tx.QueryRow(ctx, sqliteCreatePlayer, args.Username, args.Class, args.UsernameKey, now, args.Gold))
+	if err != nil {
+		if sqlite.IsUniqueViolation(err) {
+			return nil, ErrUsernameInUse
+		}
+		return nil, fmt.Errorf("scanning row into player struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return p, nil
+}
+
+const sqliteUsernameHeld = `
+SELECT EXISTS (
+  SELECT 1 FROM player_name_history
+  WHERE username_key = ?1 AND held_until > ?3 AND player_id <> ?2
+)
+`
+
+// claimSQLiteUsername fails with ErrUsernameInUse if another player is
+// holding the username key after a rename. Unlike Postgres, no lock is
+// needed: write transactions already run one at a time.
+func claimSQLiteUsername(ctx context.Context, tx *sqlite.Tx, key string, playerID int32) error {
+	var held bool
+	err := tx.QueryRow(ctx, sqliteUsernameHeld, key, playerID, sqlite.Timestamp(time.Now())).Scan(&held)
+	if err != nil {
+		return fmt.Errorf("checking username holds: %w", err)
+	}
+	if held {
+		return ErrUsernameInUse
+	}
+
+	return nil
+}
+
+const sqliteListPlayers = `
+SELECT ` + sqlitePlayerColumns + ` FROM player`
+
+func (r *sqliteRepository) ListPlayers(ctx context.Context, args ListPlayersParams) (*listing.Page[*Player], error) {
+	ctx = querylabel.With(ctx, "player", "ListPlayers")
+
+	sort, ok := playerSortColumns[args.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field '%v'", args.Sort)
+	}
+
+	q := listing.Query{Dialect: sqlite.Dialect}
+	q.Where("deleted_at IS NULL")
+	if args.Class != "" {
+		q.Where("class = " + q.Arg(args.Class))
+	}
+	if args.MinLevel != nil {
+		q.Where("level >= " + q.Arg(*args.MinLevel))
+	}
+	if args.MaxLevel != nil {
+		q.Where("level <= " + q.Arg(*args.MaxLevel))
+	}
+	if args.NamePrefix != "" {
+		q.Where("username LIKE " + q.Arg(listing.LikePrefix(args.NamePrefix)) + ` ESCAPE '\'`)
+	}
+	query := q.Build(sqliteListPlayers, args.Params, sort, playerSortColumns["id"])
+
+	rows, err := r.db.Query(ctx, query, q.Args()...)
+	if err != nil {
+		return nil, fmt.Errorf("querying for players: %w", err)
+	}
+	defer rows.Close()
+
+	var ps []*Player
+
+	for rows.Next() {
+		p, err := scanSQLitePlayer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning rows into player struct: %w", err)
+		}
+
+		ps = append(ps, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return listing.NewPage(ps, args.Params, playerSortKey(args.Sort)), nil
+}
+
+const sqliteGetPlayerByID = `
+SELECT ` + sqlitePlayerColumns + ` FROM player WHERE id = ?1 AND deleted_at IS NULL
+`
+
+func (r *sqliteRepository) GetPlayerByID(ctx context.Context, id int32) (*Player, error) {
+	ctx = querylabel.With(ctx, "player", "GetPlayerByID")
+
+	p, err := scanSQLitePlayer(r.db.QueryRow(ctx, sqliteGetPlayerByID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into player struct: %w", err)
+	}
+
+	return p, nil
+}
+
+const sqliteGetPlayerByUsername = `
+SELECT ` + sqlitePlayerColumns + ` FROM player WHERE lower(username) = lower(?1) AND deleted_at IS NULL
+`
+
+func (r *sqliteRepository) GetPlayerByUsername(ctx context.Context, username string) (*Player, error) {
+	ctx = querylabel.With(ctx, "player", "GetPlayerByUsername")
+
+	p, err := scanSQLitePlayer(r.db.QueryRow(ctx, sqliteGetPlayerByUsername, username))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into player struct: %w", err)
+	}
+
+	return p, nil
+}
+
+const sqliteUpdatePlayer = `
+UPDATE player SET
+  class = COALESCE(?2, class),
+  level = COALESCE(?3, level),
+  gold = CASE WHEN ?4 IS NULL THEN gold
+    ELSE MIN(?4, COALESCE((SELECT cap FROM currency WHERE code = 'gold'), 2147483647)) END,
+  version = version + 1,
+  updated_at = ?6
+WHERE id = ?1 AND deleted_at IS NULL AND (?5 IS NULL OR version = ?5)
+RETURNING ` + sqlitePlayerColumns
+
+func (r *sqliteRepository) UpdatePlayer(ctx context.Context, args UpdatePlayerParams) (*Player, error) {
+	ctx = querylabel.With(ctx, "player", "UpdatePlayer")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := sqlite.Timestamp(time.Now())
+
+	p, err := scanSQLitePlayer(tx.QueryRow(ctx, sqliteUpdatePlayer, args.ID, args.Class, args.Level, args.Gold, args.Version, now))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, r.missOrConflict(ctx, tx, args.ID)
+		}
+		return nil, fmt.Errorf("scanning row into player struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return p, nil
+}
+
+const sqliteGetPlayerForRename = `
+SELECT username, username_key, renamed_at FROM player WHERE id = ?1 AND deleted_at IS NULL
+`
+
+const sqliteRenamePlayer = `
+UPDATE player SET
+  username = ?2,
+  username_key = ?3,
+  renamed_at = ?4,
+  version = version + 1,
+  updated_at = ?4
+WHERE id = ?1
+RETURNING ` + sqlitePlayerColumns
+
+const sqliteAddNameHistory = `
+INSERT INTO player_name_history (player_id, username, username_key, changed_at, held_until)
+VALUES (?1, ?2, ?3, ?4, ?5)
+`
+
+// RenamePlayer changes a player's username and records the old one in the
+// name history, held for HoldPeriod. It fails if the player was renamed less
+// than Cooldown ago and returns nil if the player does not exist.
+func (r *sqliteRepository) RenamePlayer(ctx context.Context, args RenamePlayerParams) (*Player, error) {
+	ctx = querylabel.With(ctx, "player", "RenamePlayer")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var oldName, oldKey string
+	var renamedAt *time.Time
+
+	err = tx.QueryRow(ctx, sqliteGetPlayerForRename, args.ID).Scan(&oldName, &oldKey, sqlite.NullTime(&renamedAt))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading player for rename: %w", err)
+	}
+
+	if renamedAt != nil && time.Since(*renamedAt) < args.Cooldown {
+		return nil, cooldownErr(*renamedAt, args.Cooldown)
+	}
+
+	if err = claimSQLiteUsername(ctx, tx, args.UsernameKey, args.ID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	p, err := scanSQLitePlayer(tx.QueryRow(ctx, sqliteRenamePlayer, args.ID, args.Username, args.UsernameKey, sqlite.Timestamp(now)))
+	if err != nil {
+		if sqlite.IsUniqueViolation(err) {
+			return nil, ErrUsernameInUse
+		}
+		return nil, fmt.Errorf("scanning row into player struct: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, sqliteAddNameHistory, args.ID, oldName, oldKey, sqlite.Timestamp(now), sqlite.Timestamp(now.Add(args.HoldPeriod)))
+	if err != nil {
+		return nil, fmt.Errorf("recording name history: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return p, nil
+}
+
+const sqliteListNameHistory = `
+SELECT username, changed_at, held_until FROM player_name_history
+WHERE player_id = ?1
+ORDER BY changed_at DESC, id DESC
+`
+
+func (r *sqliteRepository) ListNameHistory(ctx context.Context, playerID int32) ([]*NameChange, error) {
+	ctx = querylabel.With(ctx, "player", "ListNameHistory")
+
+	rows, err := r.db.Query(ctx, sqliteListNameHistory, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("querying for name history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*NameChange
+
+	for rows.Next() {
+		var c NameChange
+
+		if err := rows.Scan(&c.Username, sqlite.Time(&c.ChangedAt), sqlite.Time(&c.HeldUntil)); err != nil {
+			return nil, fmt.Errorf("scanning rows into name change struct: %w", err)
+		}
+
+		history = append(history, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+const sqliteUpdatePlayerLevel = `
+UPDATE player SET level = ?2, version = version + 1, updated_at = ?3 WHERE id = ?1 AND deleted_at IS NULL
+`
+
+func (r *sqliteRepository) UpdatePlayerLevel(ctx context.Context, args UpdatePlayerLevelParams) error {
+	ctx = querylabel.With(ctx, "player", "UpdatePlayerLevel")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, sqliteUpdatePlayerLevel, args.ID, args.Level, sqlite.Timestamp(time.Now()))
+	if err != nil {
+		return fmt.Errorf("updating player level: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+// sqliteIncreaseGold and sqliteDecreaseGold follow IncreaseGoldSQL and
+// DecreaseGoldSQL.
+const (
+	sqliteIncreaseGold = `
+UPDATE player
+SET gold = MIN(gold + ?2, COALESCE((SELECT cap FROM currency WHERE code = 'gold'), 2147483647)),
+  version = version + 1, updated_at = ?3
+WHERE id = ?1 AND deleted_at IS NULL
+RETURNING gold
+`
+	sqliteDecreaseGold = `
+UPDATE player SET gold = gold - ?2, version = version + 1, updated_at = ?3
+WHERE id = ?1 AND deleted_at IS NULL AND gold >= ?2
+RETURNING gold
+`
+)
+
+func (r *sqliteRepository) IncreasePlayerGold(ctx context.Context, args UpdatePlayerGoldParams) error {
+	ctx = querylabel.With(ctx, "player", "IncreasePlayerGold")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var gold int32
+	err = tx.QueryRow(ctx, sqliteIncreaseGold, args.ID, args.Amount, sqlite.Timestamp(time.Now())).Scan(&gold)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("increasing player gold: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *sqliteRepository) DecreasePlayerGold(ctx context.Context, args UpdatePlayerGoldParams) error {
+	ctx = querylabel.With(ctx, "player", "DecreasePlayerGold")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var gold int32
+	err = tx.QueryRow(ctx, sqliteDecreaseGold, args.ID, args.Amount, sqlite.Timestamp(time.Now())).Scan(&gold)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInsufficientFunds
+		}
+		return fmt.Errorf("decreasing player gold: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+const sqliteDeletePlayerByID = `
+UPDATE player SET deleted_at = ?3, version = version + 1, updated_at = ?3
+WHERE id = ?1 AND deleted_at IS NULL AND (?2 IS NULL OR version = ?2)
+`
+
+// DeletePlayerByID soft-deletes a player: it is hidden from every other query
+// until it is restored or purged.
+func (r *sqliteRepository) DeletePlayerByID(ctx context.Context, args DeletePlayerParams) error {
+	ctx = querylabel.With(ctx, "player", "DeletePlayerByID")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	res, err := tx.Exec(ctx, sqliteDeletePlayerByID, args.ID, args.Version, sqlite.Timestamp(time.Now()))
+	if err != nil {
+		return fmt.Errorf("deleting player: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return r.missOrConflict(ctx, tx, args.ID)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+const sqliteGetDeletedAt = `
+SELECT deleted_at FROM player WHERE id = ?1
+`
+
+const sqliteRestorePlayer = `
+UPDATE player SET deleted_at = NULL, version = version + 1, updated_at = ?2
+WHERE id = ?1
+RETURNING ` + sqlitePlayerColumns
+
+// RestorePlayer undoes a soft delete made less than GracePeriod ago. It
+// returns nil if there is no deleted player with the given id.
+func (r *sqliteRepository) RestorePlayer(ctx context.Context, args RestorePlayerParams) (*Player, error) {
+	ctx = querylabel.With(ctx, "player", "RestorePlayer")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var deletedAt *time.Time
+
+	err = tx.QueryRow(ctx, sqliteGetDeletedAt, args.ID).Scan(sqlite.NullTime(&deletedAt))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading player for restore: %w", err)
+	}
+	if deletedAt == nil {
+		return nil, nil
+	}
+	if time.Since(*deletedAt) > args.GracePeriod {
+		return nil, ErrRestoreExpired
+	}
+
+	p, err := scanSQLitePlayer(tx.QueryRow(ctx, sqliteRestorePlayer, args.ID, sqlite.Timestamp(time.Now())))
+	if err != nil {
+		return nil, fmt.Errorf("scanning row into player struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return p, nil
+}
+
+const sqliteGetPlayerVersion = `
+SELECT version FROM player WHERE id = ?1
+`
+
+// PurgePlayer permanently deletes a player, live or soft-deleted, with
+// everything it owns. It returns nil if the player does not exist.
+func (r *sqliteRepository) PurgePlayer(ctx context.Context, args PurgePlayerParams) (*DeletionReport, error) {
+	ctx = querylabel.With(ctx, "player", "PurgePlayer")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var version int32
+
+	err = tx.QueryRow(ctx, sqliteGetPlayerVersion, args.ID).Scan(&version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading player for purge: %w", err)
+	}
+	if args.Version != nil && *args.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	report, err := purgeSQLitePlayers(ctx, tx, []int32{args.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return report, nil
+}
+
+const sqliteListPurgeablePlayers = `
+SELECT id FROM player WHERE deleted_at < ?1
+`
+
+// PurgeDeletedPlayers permanently deletes the players soft-deleted before the
+// given time, with everything they own.
+func (r *sqliteRepository) PurgeDeletedPlayers(ctx context.Context, before time.Time) (*DeletionReport, error) {
+	ctx = querylabel.With(ctx, "player", "PurgeDeletedPlayers")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, sqliteListPurgeablePlayers, sqlite.Timestamp(before))
+	if err != nil {
+		return nil, fmt.Errorf("listing deleted players: %w", err)
+	}
+
+	var ids []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning deleted player ids: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report, err := purgeSQLitePlayers(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return report, nil
+}
+
+// sqliteOwnedRecords lists the statements that delete what the player in ?1
+// owns. The SQLite schema only has inventories and name history; it keeps no
+// ledger records.
+var sqliteOwnedRecords = []struct {
+	kind string
+	stmt string
+}{
+	{"inventory", `DELETE FROM inventory WHERE player_id = ?1`},
+	{"name_history", `DELETE FROM player_name_history WHERE player_id = ?1`},
+}
+
+const sqliteDeletePlayer = `
+DELETE FROM player WHERE id = ?1
+`
+
+func purgeSQLitePlayers(ctx context.Context, tx *sqlite.Tx, ids []int32) (*DeletionReport, error) {
+	report := &DeletionReport{
+		Removed:  make(map[string]int64, len(sqliteOwnedRecords)),
+		Retained: make(map[string]int64),
+	}
+	for _, rec := range sqliteOwnedRecords {
+		report.Removed[rec.kind] = 0
+	}
+
+	for _, id := range ids {
+		for _, rec := range sqliteOwnedRecords {
+			res, err := tx.Exec(ctx, rec.stmt, id)
+			if err != nil {
+				return nil, fmt.Errorf("deleting player %v: %w", rec.kind, err)
+			}
+			n, _ := res.RowsAffected()
+			report.Removed[rec.kind] += n
+		}
+
+		res, err := tx.Exec(ctx, sqliteDeletePlayer, id)
+		if err != nil {
+			return nil, fmt.Errorf("deleting players: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		report.Players += n
+	}
+
+	return report, nil
+}
+
+const sqlitePlayerExists = `
+SELECT EXISTS (SELECT 1 FROM player WHERE id = ?1 AND deleted_at IS NULL)
+`
+
+// missOrConflict explains why a conditional write matched no row: nil if the
+// player does not exist, ErrVersionMismatch if it exists at another version.
+func (r *sqliteRepository) missOrConflict(ctx context.Context, tx *sqlite.Tx, id int32) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, sqlitePlayerExists, id).Scan(&exists); err != nil {
+		return fmt.Errorf("checking if player exists: %w", err)
+	}
+	if exists {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+const sqliteGetPlayerTotals = `
+SELECT class, count(*), COALESCE(sum(gold), 0) FROM player
+WHERE deleted_at IS NULL
+GROUP BY class
+`
+
+func (r *sqliteRepository) GetPlayerTotals(ctx context.Context) (*PlayerTotals, error) {
+	ctx = querylabel.With(ctx, "player", "GetPlayerTotals")
+
+	rows, err := r.db.Query(ctx, sqliteGetPlayerTotals)
+	if err != nil {
+		return nil, fmt.Errorf("querying for player totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := &PlayerTotals{ByClass: make(map[string]int64)}
+
+	for rows.Next() {
+		var class string
+		var count, gold int64
+		if err := rows.Scan(&class, &count, &gold); err != nil {
+			return nil, fmt.Errorf("scanning player totals: %w", err)
+		}
+		totals.ByClass[class] = count
+		totals.Gold += gold
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
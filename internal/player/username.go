@@ -0,0 +1,198 @@
+package player
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	//go:embed wordlists/reserved.txt
+	defaultReserved string
+	//go:embed wordlists/profanity.txt
+	defaultProfanity string
+)
+
+// UsernamePolicy decides which usernames can be taken and how often a player
+// may change theirs.
+type UsernamePolicy struct {
+	// Reserved names cannot be used at all. Profane words cannot appear
+	// anywhere in a name. Both are compared by username key, so case and
+	// lookalike characters do not get around them.
+	Reserved  []string
+	Profanity []string
+	// RenameCooldown is how long a player has to wait between renames.
+	RenameCooldown time.Duration
+	// HoldPeriod is how long a name given up in a rename stays reserved for
+	// the player who gave it up.
+	HoldPeriod time.Duration
+}
+
+func DefaultUsernamePolicy() UsernamePolicy {
+	return UsernamePolicy{
+		Reserved:       parseWordList(strings.NewReader(defaultReserved)),
+		Profanity:      parseWordList(strings.NewReader(defaultProfanity)),
+		RenameCooldown: 30 * 24 * time.Hour,
+		HoldPeriod:     90 * 24 * time.Hour,
+	}
+}
+
+// LoadWordList reads a word list with one word per line. Blank lines and
+// lines starting with '#' are ignored.
+func LoadWordList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening word list: %w", err)
+	}
+	defer f.Close()
+
+	return parseWordList(f), nil
+}
+
+func parseWordList(r io.Reader) []string {
+	var words []string
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+
+	return words
+}
+
+// NormalizeUsername returns the form a username is stored and displayed in.
+// NFKC folds compatibility characters such as fullwidth letters and
+// ligatures into their plain equivalents.
+func NormalizeUsername(username string) string {
+	return strings.TrimSpace(norm.NFKC.String(username))
+}
+
+var fold = cases.Fold()
+
+// confusables maps characters that are easily mistaken for a Latin letter to
+// that letter. It is applied after case folding, which is why i and l share a
+// key: a capital I looks like a lowercase l.
+var confusables = map[rune]rune{
+	'i': 'l', 'ı': 'l',
+	// Digits
+	'0': 'o', '1': 'l',
+	// Cyrillic
+	'а': 'a', 'е': 'e', 'к': 'k', 'о': 'o', 'р': 'p', 'с': 'c', 'у': 'y',
+	'х': 'x', 'ѕ': 's', 'і': 'l', 'ј': 'j', 'ԁ': 'd', 'һ': 'h', 'ӏ': 'l',
+	'ԛ': 'q', 'ԝ': 'w',
+	// Greek
+	'α': 'a', 'ι': 'l', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'υ': 'u',
+	'χ': 'x',
+}
+
+// sequences are runs of Latin letters that read as a single other letter.
+var sequences = strings.NewReplacer("rn", "m", "vv", "w")
+
+// UsernameKey returns the key two usernames collide on. It ignores case and
+// maps lookalike characters onto one another, so "Sephiro", "sephiro" and
+// "Sephir0" all share a key.
+func UsernameKey(username string) string {
+	s := fold.String(NormalizeUsername(username))
+
+	s = strings.Map(func(r rune) rune {
+		if c, ok := confusables[r]; ok {
+			return c
+		}
+		return r
+	}, s)
+
+	return sequences.Replace(s)
+}
+
+// scripts are the writing systems a username's letters are checked against.
+// Han, Hiragana and Katakana are written together in Japanese and count as
+// one script.
+var scripts = []struct {
+	name   string
+	tables []*unicode.RangeTable
+}{
+	{"Latin", []*unicode.RangeTable{unicode.Latin}},
+	{"Cyrillic", []*unicode.RangeTable{unicode.Cyrillic}},
+	{"Greek", []*unicode.RangeTable{unicode.Greek}},
+	{"Armenian", []*unicode.RangeTable{unicode.Armenian}},
+	{"Arabic", []*unicode.RangeTable{unicode.Arabic}},
+	{"Hebrew", []*unicode.RangeTable{unicode.Hebrew}},
+	{"Devanagari", []*unicode.RangeTable{unicode.Devanagari}},
+	{"Thai", []*unicode.RangeTable{unicode.Thai}},
+	{"Hangul", []*unicode.RangeTable{unicode.Hangul}},
+	{"Japanese", []*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana}},
+}
+
+func scriptOf(r rune) string {
+	for _, s := range scripts {
+		if unicode.In(r, s.tables...) {
+			return s.name
+		}
+	}
+	return ""
+}
+
+// mixesScripts reports whether username has letters from more than one
+// script, the usual way of spoofing a name with lookalike characters.
+func mixesScripts(username string) bool {
+	seen := ""
+	for _, r := range username {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		script := scriptOf(r)
+		if script == "" {
+			continue
+		}
+		if seen != "" && script != seen {
+			return true
+		}
+		seen = script
+	}
+	return false
+}
+
+// Check reports why a normalized username breaks the policy, if it does.
+func (p UsernamePolicy) Check(username string) apperr.Fields {
+	var fields apperr.Fields
+
+	if mixesScripts(username) {
+		fields.Add("username", "must not mix letters from different alphabets")
+	}
+
+	key := UsernameKey(username)
+
+	for _, word := range p.Reserved {
+		if key == UsernameKey(word) {
+			fields.Add("username", "is reserved")
+			break
+		}
+	}
+
+	for _, word := range p.Profanity {
+		if strings.Contains(key, UsernameKey(word)) {
+			fields.Add("username", "contains a word that is not allowed")
+			break
+		}
+	}
+
+	return fields
+}
+
+// cooldownErr reports when a player may rename again.
+func cooldownErr(renamedAt time.Time, cooldown time.Duration) error {
+	return apperr.Conflict(fmt.Sprintf("username can be changed again after %s", renamedAt.Add(cooldown).UTC().Format(time.RFC3339)))
+}
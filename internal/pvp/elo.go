@@ -0,0 +1,47 @@
+package pvp
+
+import "math"
+
+const (
+	DefaultRating = 1500
+
+	// Players start with a larger K factor so their rating converges quickly,
+	// then settle on the regular one once they have played enough games.
+	provisionalKFactor = 40
+	kFactor            = 24
+	provisionalGames   = 20
+)
+
+// Outcome is the score of player A in a match: 1 for a win, 0.5 for a draw
+// and 0 for a loss.
+type Outcome float64
+
+const (
+	OutcomeWinA Outcome = 1
+	OutcomeDraw Outcome = 0.5
+	OutcomeWinB Outcome = 0
+)
+
+// ExpectedScore is the probability of a player rated a beating a player
+// rated b.
+func ExpectedScore(a, b int32) float64 {
+	return 1 / (1 + math.Pow(10, float64(b-a)/400))
+}
+
+func kFactorFor(r *Rating) float64 {
+	if r.Games() < provisionalGames {
+		return provisionalKFactor
+	}
+	return kFactor
+}
+
+// RatingChanges returns the rating delta of each player for the given outcome.
+func RatingChanges(a, b *Rating, outcome Outcome) (int32, int32) {
+	expectedA := ExpectedScore(a.Rating, b.Rating)
+	expectedB := 1 - expectedA
+
+	changeA := kFactorFor(a) * (float64(outcome) - expectedA)
+	changeB := kFactorFor(b) * ((1 - float64(outcome)) - expectedB)
+
+	return int32(math.Round(changeA)), int32(math.Round(changeB))
+}
@@ -0,0 +1,52 @@
+package pvp_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hossokawa/go-nethttp-example/internal/pvp"
+)
+
+func TestExpectedScore(t *testing.T) {
+	if got := pvp.ExpectedScore(1500, 1500); got != 0.5 {
+		t.Errorf("ExpectedScore of equal ratings = %v, want 0.5", got)
+	}
+
+	// A 400 point lead makes a win ten times as likely as a loss.
+	if got := pvp.ExpectedScore(1900, 1500); math.Abs(got-10.0/11) > 1e-9 {
+		t.Errorf("ExpectedScore(1900, 1500) = %v, want %v", got, 10.0/11)
+	}
+
+	if a, b := pvp.ExpectedScore(1620, 1480), pvp.ExpectedScore(1480, 1620); math.Abs(a+b-1) > 1e-9 {
+		t.Errorf("expected scores %v and %v do not add up to 1", a, b)
+	}
+}
+
+func TestRatingChanges(t *testing.T) {
+	newPlayer := &pvp.Rating{Rating: 1500}
+	veteran := &pvp.Rating{Rating: 1500, Wins: 30, Losses: 30}
+
+	tests := []struct {
+		name             string
+		a, b             *pvp.Rating
+		outcome          pvp.Outcome
+		changeA, changeB int32
+	}{
+		{"provisional win", newPlayer, newPlayer, pvp.OutcomeWinA, 20, -20},
+		{"provisional loss", newPlayer, newPlayer, pvp.OutcomeWinB, -20, 20},
+		{"draw between equals", newPlayer, newPlayer, pvp.OutcomeDraw, 0, 0},
+		{"established win", veteran, veteran, pvp.OutcomeWinA, 12, -12},
+		{"provisional beats established", newPlayer, veteran, pvp.OutcomeWinA, 20, -12},
+		{"favourite wins", &pvp.Rating{Rating: 1900}, &pvp.Rating{Rating: 1500}, pvp.OutcomeWinA, 4, -4},
+		{"underdog wins", &pvp.Rating{Rating: 1500}, &pvp.Rating{Rating: 1900}, pvp.OutcomeWinA, 36, -36},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changeA, changeB := pvp.RatingChanges(tt.a, tt.b, tt.outcome)
+			if changeA != tt.changeA || changeB != tt.changeB {
+				t.Errorf("RatingChanges = %d, %d, want %d, %d", changeA, changeB, tt.changeA, tt.changeB)
+			}
+		})
+	}
+}
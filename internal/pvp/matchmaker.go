@@ -0,0 +1,38 @@
+package pvp
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Matchmaker runs the matching pass of a PvPService on a fixed interval.
+type Matchmaker struct {
+	service  *PvPService
+	interval time.Duration
+}
+
+func NewMatchmaker(service *PvPService, interval time.Duration) *Matchmaker {
+	return &Matchmaker{service: service, interval: interval}
+}
+
+// Run matches queued players on every tick until ctx is cancelled.
+func (m *Matchmaker) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			matches, err := m.service.MatchQueuedPlayers(ctx)
+			if err != nil {
+				log.Printf("Error matching queued players: %s", err)
+			}
+			if len(matches) > 0 {
+				log.Printf("Created %d pvp matches", len(matches))
+			}
+		}
+	}
+}
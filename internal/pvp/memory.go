@@ -0,0 +1,232 @@
+package pvp
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+)
+
+type memRepository struct {
+	db *memdb.DB
+}
+
+// NewMemoryRepository returns a PvPRepository that keeps ratings and matches
+// in db. It behaves like the Postgres repository, down to the errors it
+// returns.
+func NewMemoryRepository(db *memdb.DB) PvPRepository {
+	return &memRepository{db: db}
+}
+
+func toMatch(row memdb.Match) *Match {
+	return &Match{
+		ID:            row.ID,
+		PlayerAID:     row.PlayerAID,
+		PlayerBID:     row.PlayerBID,
+		RatingA:       row.RatingA,
+		RatingB:       row.RatingB,
+		Status:        row.Status,
+		WinnerID:      row.WinnerID,
+		RatingChangeA: row.RatingChangeA,
+		RatingChangeB: row.RatingChangeB,
+		CreatedAt:     row.CreatedAt,
+		CompletedAt:   row.CompletedAt,
+	}
+}
+
+func (r *memRepository) GetRating(ctx context.Context, playerID int32) (*Rating, error) {
+	var rt *Rating
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		if row, ok := t.Ratings[playerID]; ok {
+			rt = &Rating{
+				PlayerID:  row.PlayerID,
+				Rating:    row.Rating,
+				Wins:      row.Wins,
+				Losses:    row.Losses,
+				Draws:     row.Draws,
+				UpdatedAt: row.UpdatedAt,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rt, nil
+}
+
+func (r *memRepository) LockRating(ctx context.Context, playerID int32) (*Rating, error) {
+	var rt *Rating
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		if _, ok := t.Players[playerID]; !ok {
+			return fmt.Errorf("creating rating for player with id %v: %w", playerID, memdb.ErrForeignKey)
+		}
+
+		row, ok := t.Ratings[playerID]
+		if !ok {
+			row = memdb.Rating{PlayerID: playerID, Rating: DefaultRating, UpdatedAt: time.Now()}
+			t.Ratings[playerID] = row
+		}
+
+		rt = &Rating{
+			PlayerID:  row.PlayerID,
+			Rating:    row.Rating,
+			Wins:      row.Wins,
+			Losses:    row.Losses,
+			Draws:     row.Draws,
+			UpdatedAt: row.UpdatedAt,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rt, nil
+}
+
+func (r *memRepository) HasPendingMatch(ctx context.Context, playerID int32) (bool, error) {
+	var pending bool
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Matches {
+			if row.Status == MatchPending && (row.PlayerAID == playerID || row.PlayerBID == playerID) {
+				pending = true
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return pending, nil
+}
+
+func (r *memRepository) CreateMatch(ctx context.Context, args CreateMatchParams) (*Match, error) {
+	var m *Match
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		row := memdb.Match{
+			ID:        t.NextID("pvp_match"),
+			PlayerAID: args.PlayerAID,
+			PlayerBID: args.PlayerBID,
+			RatingA:   args.RatingA,
+			RatingB:   args.RatingB,
+			Status:    MatchPending,
+			CreatedAt: time.Now(),
+		}
+		t.Matches[row.ID] = row
+
+		m = toMatch(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (r *memRepository) GetMatchByID(ctx context.Context, id int32) (*Match, error) {
+	var m *Match
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		if row, ok := t.Matches[id]; ok {
+			m = toMatch(row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (r *memRepository) ListPlayerMatches(ctx context.Context, playerID int32) ([]*Match, error) {
+	var matches []*Match
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Matches {
+			if row.PlayerAID == playerID || row.PlayerBID == playerID {
+				matches = append(matches, toMatch(row))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(matches, func(a, b *Match) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+
+	return matches, nil
+}
+
+func (r *memRepository) CompleteMatch(ctx context.Context, args CompleteMatchParams) (*Match, error) {
+	var m *Match
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		row, ok := t.Matches[args.ID]
+		if !ok || row.Status != MatchPending {
+			return ErrMatchNotPending
+		}
+
+		now := time.Now()
+		row.Status = MatchCompleted
+		row.WinnerID = args.WinnerID
+		row.RatingChangeA = args.RatingChangeA
+		row.RatingChangeB = args.RatingChangeB
+		row.CompletedAt = &now
+		t.Matches[row.ID] = row
+
+		for _, p := range []struct {
+			id     int32
+			change int32
+		}{
+			{row.PlayerAID, args.RatingChangeA},
+			{row.PlayerBID, args.RatingChangeB},
+		} {
+			if _, ok := t.Players[p.id]; !ok {
+				return fmt.Errorf("updating rating for player with id %v: %w", p.id, memdb.ErrForeignKey)
+			}
+
+			rt, ok := t.Ratings[p.id]
+			if !ok {
+				rt = memdb.Rating{PlayerID: p.id, Rating: DefaultRating}
+			}
+			rt.Rating += p.change
+			switch {
+			case args.WinnerID == nil:
+				rt.Draws++
+			case *args.WinnerID == p.id:
+				rt.Wins++
+			default:
+				rt.Losses++
+			}
+			rt.UpdatedAt = now
+			t.Ratings[p.id] = rt
+		}
+
+		m = toMatch(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
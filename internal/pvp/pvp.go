@@ -0,0 +1,39 @@
+package pvp
+
+import "time"
+
+const (
+	MatchPending   = "pending"
+	MatchCompleted = "completed"
+)
+
+type Rating struct {
+	PlayerID  int32     `json:"player_id"`
+	Rating    int32     `json:"rating"`
+	Wins      int32     `json:"wins"`
+	Losses    int32     `json:"losses"`
+	Draws     int32     `json:"draws"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r *Rating) Games() int32 {
+	return r.Wins + r.Losses + r.Draws
+}
+
+type Match struct {
+	ID            int32      `json:"id"`
+	PlayerAID     int32      `json:"player_a_id"`
+	PlayerBID     int32      `json:"player_b_id"`
+	RatingA       int32      `json:"rating_a"`
+	RatingB       int32      `json:"rating_b"`
+	Status        string     `json:"status"`
+	WinnerID      *int32     `json:"winner_id"`
+	RatingChangeA int32      `json:"rating_change_a"`
+	RatingChangeB int32      `json:"rating_change_b"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CompletedAt   *time.Time `json:"completed_at"`
+}
+
+func (m *Match) HasPlayer(playerID int32) bool {
+	return m.PlayerAID == playerID || m.PlayerBID == playerID
+}
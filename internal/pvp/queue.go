@@ -0,0 +1,216 @@
+package pvp
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+)
+
+var (
+	ErrAlreadyQueued = apperr.Conflict("player is already queued")
+	ErrNotQueued     = apperr.New(apperr.KindNotFound, "player is not queued")
+	ErrInMatch       = apperr.Conflict("player is in a pending match")
+)
+
+type QueueEntry struct {
+	PlayerID int32     `json:"player_id"`
+	Rating   int32     `json:"rating"`
+	Level    int32     `json:"level"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// MatchRules controls how far apart two queued players may be to be paired.
+// Both windows start at their base value and widen the longer a player
+// waits, up to their maximum.
+type MatchRules struct {
+	BaseRatingWindow   int32
+	RatingWindowGrowth int32
+	MaxRatingWindow    int32
+	BaseLevelBand      int32
+	LevelBandGrowth    int32
+	MaxLevelBand       int32
+	GrowthInterval     time.Duration
+}
+
+var DefaultMatchRules = MatchRules{
+	BaseRatingWindow:   50,
+	RatingWindowGrowth: 25,
+	MaxRatingWindow:    400,
+	BaseLevelBand:      3,
+	LevelBandGrowth:    1,
+	MaxLevelBand:       10,
+	GrowthInterval:     10 * time.Second,
+}
+
+func (r MatchRules) windows(e *QueueEntry, now time.Time) (int32, int32) {
+	var steps int32
+	if r.GrowthInterval > 0 {
+		steps = int32(now.Sub(e.JoinedAt) / r.GrowthInterval)
+	}
+
+	rating := min(r.BaseRatingWindow+r.RatingWindowGrowth*steps, r.MaxRatingWindow)
+	level := min(r.BaseLevelBand+r.LevelBandGrowth*steps, r.MaxLevelBand)
+
+	return rating, level
+}
+
+// compatible reports whether a and b fall within each other's windows, so a
+// player who just joined is not pulled into a match far outside their range
+// because their opponent has been waiting a long time.
+func (r MatchRules) compatible(a, b *QueueEntry, now time.Time) bool {
+	ratingA, levelA := r.windows(a, now)
+	ratingB, levelB := r.windows(b, now)
+
+	ratingDiff := abs(a.Rating - b.Rating)
+	levelDiff := abs(a.Level - b.Level)
+
+	return ratingDiff <= min(ratingA, ratingB) && levelDiff <= min(levelA, levelB)
+}
+
+type Pairing struct {
+	A QueueEntry
+	B QueueEntry
+}
+
+// Queue holds the players waiting for a match. Paired players are held back
+// from joining again until their match is finished, or requeued if it could
+// not be created. It is safe for concurrent use.
+type Queue struct {
+	mu      sync.Mutex
+	rules   MatchRules
+	entries map[int32]*QueueEntry
+	paired  map[int32]bool
+}
+
+func NewQueue(rules MatchRules) *Queue {
+	return &Queue{rules: rules, entries: make(map[int32]*QueueEntry), paired: make(map[int32]bool)}
+}
+
+func (q *Queue) Join(e QueueEntry) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.entries[e.PlayerID]; ok {
+		return ErrAlreadyQueued
+	}
+	if q.paired[e.PlayerID] {
+		return ErrInMatch
+	}
+	q.entries[e.PlayerID] = &e
+
+	return nil
+}
+
+func (q *Queue) Leave(playerID int32) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.entries[playerID]; !ok {
+		return ErrNotQueued
+	}
+	delete(q.entries, playerID)
+
+	return nil
+}
+
+func (q *Queue) Get(playerID int32) (QueueEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.entries[playerID]
+	if !ok {
+		return QueueEntry{}, false
+	}
+	return *e, true
+}
+
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.entries)
+}
+
+// Pair removes and returns every pair of compatible players, who cannot join
+// again until Finish or Requeue is called for them. Players who have
+// waited the longest are matched first, each with the closest rated
+// compatible opponent.
+func (q *Queue) Pair(now time.Time) []Pairing {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	waiting := make([]*QueueEntry, 0, len(q.entries))
+	for _, e := range q.entries {
+		waiting = append(waiting, e)
+	}
+	sort.Slice(waiting, func(i, j int) bool {
+		if waiting[i].JoinedAt.Equal(waiting[j].JoinedAt) {
+			return waiting[i].PlayerID < waiting[j].PlayerID
+		}
+		return waiting[i].JoinedAt.Before(waiting[j].JoinedAt)
+	})
+
+	var pairings []Pairing
+	matched := make(map[int32]bool)
+
+	for i, a := range waiting {
+		if matched[a.PlayerID] {
+			continue
+		}
+
+		var best *QueueEntry
+		for _, b := range waiting[i+1:] {
+			if matched[b.PlayerID] || !q.rules.compatible(a, b, now) {
+				continue
+			}
+			if best == nil || abs(a.Rating-b.Rating) < abs(a.Rating-best.Rating) {
+				best = b
+			}
+		}
+		if best == nil {
+			continue
+		}
+
+		matched[a.PlayerID] = true
+		matched[best.PlayerID] = true
+		pairings = append(pairings, Pairing{A: *a, B: *best})
+	}
+
+	for id := range matched {
+		delete(q.entries, id)
+		q.paired[id] = true
+	}
+
+	return pairings
+}
+
+// Requeue puts back players whose match could not be created, keeping their
+// original join time so they do not lose their widened windows.
+func (q *Queue) Requeue(e QueueEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.paired, e.PlayerID)
+	if _, ok := q.entries[e.PlayerID]; !ok {
+		q.entries[e.PlayerID] = &e
+	}
+}
+
+// Finish lets players whose match is over join again.
+func (q *Queue) Finish(playerIDs ...int32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, id := range playerIDs {
+		delete(q.paired, id)
+	}
+}
+
+func abs(n int32) int32 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,93 @@
+package pvp_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/pvp"
+)
+
+var start = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func entry(id, rating, level int32) pvp.QueueEntry {
+	return pvp.QueueEntry{PlayerID: id, Rating: rating, Level: level, JoinedAt: start}
+}
+
+func join(t *testing.T, q *pvp.Queue, entries ...pvp.QueueEntry) {
+	t.Helper()
+	for _, e := range entries {
+		if err := q.Join(e); err != nil {
+			t.Fatalf("joining player %d: %v", e.PlayerID, err)
+		}
+	}
+}
+
+func TestQueuePairsClosestCompatible(t *testing.T) {
+	q := pvp.NewQueue(pvp.DefaultMatchRules)
+	join(t, q, entry(1, 1500, 10), entry(2, 1540, 10), entry(3, 1510, 11), entry(4, 1800, 10))
+
+	pairs := q.Pair(start)
+	if len(pairs) != 1 {
+		t.Fatalf("got %d pairings, want 1: %+v", len(pairs), pairs)
+	}
+	if p := pairs[0]; p.A.PlayerID != 1 || p.B.PlayerID != 3 {
+		t.Errorf("paired %d with %d, want 1 with 3", p.A.PlayerID, p.B.PlayerID)
+	}
+	if q.Len() != 2 {
+		t.Errorf("%d players left queued, want 2", q.Len())
+	}
+}
+
+func TestQueueWindowsWiden(t *testing.T) {
+	q := pvp.NewQueue(pvp.DefaultMatchRules)
+	join(t, q, entry(1, 1500, 10), entry(2, 1700, 15))
+
+	if pairs := q.Pair(start); len(pairs) != 0 {
+		t.Fatalf("players 200 points and 5 levels apart paired right away: %+v", pairs)
+	}
+
+	// Both windows are at their widest after waiting long enough.
+	if pairs := q.Pair(start.Add(10 * time.Minute)); len(pairs) != 1 {
+		t.Fatalf("got %d pairings after waiting, want 1", len(pairs))
+	}
+}
+
+func TestQueueChecksBothWindows(t *testing.T) {
+	q := pvp.NewQueue(pvp.DefaultMatchRules)
+	join(t, q, entry(1, 1500, 10))
+
+	// A player who just joined is not pulled far outside their own window
+	// by one who has waited long.
+	late := entry(2, 1700, 10)
+	late.JoinedAt = start.Add(10 * time.Minute)
+	join(t, q, late)
+
+	if pairs := q.Pair(late.JoinedAt); len(pairs) != 0 {
+		t.Fatalf("paired a player outside their own window: %+v", pairs)
+	}
+}
+
+func TestPairedPlayersCannotRejoin(t *testing.T) {
+	q := pvp.NewQueue(pvp.DefaultMatchRules)
+	a, b := entry(1, 1500, 10), entry(2, 1500, 10)
+	join(t, q, a, b)
+
+	if pairs := q.Pair(start); len(pairs) != 1 {
+		t.Fatalf("got %d pairings, want 1", len(pairs))
+	}
+
+	if err := q.Join(a); !errors.Is(err, pvp.ErrInMatch) {
+		t.Fatalf("joining while paired: got %v, want %v", err, pvp.ErrInMatch)
+	}
+
+	q.Requeue(a)
+	if _, ok := q.Get(a.PlayerID); !ok {
+		t.Errorf("requeued player is not queued")
+	}
+
+	q.Finish(b.PlayerID)
+	if err := q.Join(b); err != nil {
+		t.Errorf("joining after the match finished: %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+package pvp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrMatchNotPending = apperr.Conflict("match is not pending")
+
+type PvPRepository interface {
+	GetRating(ctx context.Context, playerID int32) (*Rating, error)
+	LockRating(ctx context.Context, playerID int32) (*Rating, error)
+	HasPendingMatch(ctx context.Context, playerID int32) (bool, error)
+	CreateMatch(ctx context.Context, args CreateMatchParams) (*Match, error)
+	GetMatchByID(ctx context.Context, id int32) (*Match, error)
+	ListPlayerMatches(ctx context.Context, playerID int32) ([]*Match, error)
+	CompleteMatch(ctx context.Context, args CompleteMatchParams) (*Match, error)
+}
+
+type pgRepository struct {
+	db *database.DB
+}
+
+func NewPostgresRepository(db *database.DB) PvPRepository {
+	return &pgRepository{db: db}
+}
+
+const getRating = `
+SELECT player_id, rating, wins, losses, draws, updated_at FROM pvp_rating WHERE player_id = $1
+`
+
+func (r *pgRepository) GetRating(ctx context.Context, playerID int32) (*Rating, error) {
+	ctx = querylabel.With(ctx, "pvp", "GetRating")
+
+	var rt Rating
+
+	row := r.db.QueryRow(ctx, getRating, playerID)
+	err := row.Scan(
+		&rt.PlayerID,
+		&rt.Rating,
+		&rt.Wins,
+		&rt.Losses,
+		&rt.Draws,
+		&rt.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into rating struct: %w", err)
+	}
+
+	return &rt, nil
+}
+
+const createDefaultRating = `
+INSERT INTO pvp_rating (player_id, rating, wins, losses, draws, updated_at)
+VALUES ($1, $2, 0, 0, 0, now())
+ON CONFLICT (player_id) DO NOTHING
+`
+
+const getRatingForUpdate = `
+SELECT player_id, rating, wins, losses, draws, updated_at FROM pvp_rating WHERE player_id = $1
+FOR UPDATE
+`
+
+// LockRating returns the player's rating, starting them at the default
+// rating if they have none, and locks it until the transaction of ctx ends,
+// so that results reported concurrently are computed one after the other.
+func (r *pgRepository) LockRating(ctx context.Context, playerID int32) (*Rating, error) {
+	ctx = querylabel.With(ctx, "pvp", "LockRating")
+
+	if _, err := r.db.Exec(ctx, createDefaultRating, playerID, DefaultRating); err != nil {
+		return nil, fmt.Errorf("creating rating for player with id %v: %w", playerID, err)
+	}
+
+	var rt Rating
+
+	row := r.db.QueryRow(ctx, getRatingForUpdate, playerID)
+	err := row.Scan(
+		&rt.PlayerID,
+		&rt.Rating,
+		&rt.Wins,
+		&rt.Losses,
+		&rt.Draws,
+		&rt.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("scanning row into rating struct: %w", err)
+	}
+
+	return &rt, nil
+}
+
+const hasPendingMatch = `
+SELECT EXISTS (
+  SELECT 1 FROM pvp_match
+  WHERE status = 'pending' AND (player_a_id = $1 OR player_b_id = $1)
+)
+`
+
+func (r *pgRepository) HasPendingMatch(ctx context.Context, playerID int32) (bool, error) {
+	ctx = querylabel.With(ctx, "pvp", "HasPendingMatch")
+
+	var pending bool
+	if err := r.db.QueryRow(ctx, hasPendingMatch, playerID).Scan(&pending); err != nil {
+		return false, fmt.Errorf("querying for pending matches: %w", err)
+	}
+
+	return pending, nil
+}
+
+const matchColumns = `id, player_a_id, player_b_id, rating_a, rating_b, status, winner_id, rating_change_a, rating_change_b, created_at, completed_at`
+
+func scanMatch(row pgx.Row) (*Match, error) {
+	var m Match
+
+	err := row.Scan(
+		&m.ID,
+		&m.PlayerAID,
+		&m.PlayerBID,
+		&m.RatingA,
+		&m.RatingB,
+		&m.Status,
+		&m.WinnerID,
+		&m.RatingChangeA,
+		&m.RatingChangeB,
+		&m.CreatedAt,
+		&m.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+const createMatch = `
+INSERT INTO pvp_match (player_a_id, player_b_id, rating_a, rating_b, status, created_at)
+VALUES ($1, $2, $3, $4, 'pending', now())
+RETURNING ` + matchColumns
+
+type CreateMatchParams struct {
+	PlayerAID int32 `json:"player_a_id"`
+	PlayerBID int32 `json:"player_b_id"`
+	RatingA   int32 `json:"rating_a"`
+	RatingB   int32 `json:"rating_b"`
+}
+
+func (r *pgRepository) CreateMatch(ctx context.Context, args CreateMatchParams) (*Match, error) {
+	ctx = querylabel.With(ctx, "pvp", "CreateMatch")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	m, err := scanMatch(tx.QueryRow(ctx, createMatch, args.PlayerAID, args.PlayerBID, args.RatingA, args.RatingB))
+	if err != nil {
+		return nil, fmt.Errorf("scanning row into match struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return m, nil
+}
+
+const getMatchByID = `
+SELECT ` + matchColumns + ` FROM pvp_match WHERE id = $1
+`
+
+func (r *pgRepository) GetMatchByID(ctx context.Context, id int32) (*Match, error) {
+	ctx = querylabel.With(ctx, "pvp", "GetMatchByID")
+
+	m, err := scanMatch(r.db.QueryRow(ctx, getMatchByID, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into match struct: %w", err)
+	}
+
+	return m, nil
+}
+
+const listPlayerMatches = `
+SELECT ` + matchColumns + ` FROM pvp_match
+WHERE player_a_id = $1 OR player_b_id = $1
+ORDER BY created_at DESC
+`
+
+func (r *pgRepository) ListPlayerMatches(ctx context.Context, playerID int32) ([]*Match, error) {
+	ctx = querylabel.With(ctx, "pvp", "ListPlayerMatches")
+
+	rows, err := r.db.Query(ctx, listPlayerMatches, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("querying for player matches: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []*Match
+
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning rows into match struct: %w", err)
+		}
+
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+const completeMatch = `
+UPDATE pvp_match
+SET status = 'completed', winner_id = $2, rating_change_a = $3, rating_change_b = $4, completed_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING ` + matchColumns
+
+const applyRatingChange = `
+INSERT INTO pvp_rating (player_id, rating, wins, losses, draws, updated_at)
+VALUES ($1, $2 + $3, $4, $5, $6, now())
+ON CONFLICT (player_id) DO UPDATE SET
+  rating = pvp_rating.rating + $3,
+  wins = pvp_rating.wins + $4,
+  losses = pvp_rating.losses + $5,
+  draws = pvp_rating.draws + $6,
+  updated_at = now()
+`
+
+type CompleteMatchParams struct {
+	ID            int32  `json:"id"`
+	WinnerID      *int32 `json:"winner_id"`
+	RatingChangeA int32  `json:"rating_change_a"`
+	RatingChangeB int32  `json:"rating_change_b"`
+}
+
+// CompleteMatch records the result of a pending match and applies the rating
+// changes to both players in the same transaction. Changes are applied as
+// deltas so results of concurrent matches involving the same player add up.
+func (r *pgRepository) CompleteMatch(ctx context.Context, args CompleteMatchParams) (*Match, error) {
+	ctx = querylabel.With(ctx, "pvp", "CompleteMatch")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	m, err := scanMatch(tx.QueryRow(ctx, completeMatch, args.ID, args.WinnerID, args.RatingChangeA, args.RatingChangeB))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrMatchNotPending
+		}
+		return nil, fmt.Errorf("scanning row into match struct: %w", err)
+	}
+
+	for _, p := range []struct {
+		id     int32
+		change int32
+	}{
+		{m.PlayerAID, args.RatingChangeA},
+		{m.PlayerBID, args.RatingChangeB},
+	} {
+		var win, loss, draw int32
+		switch {
+		case args.WinnerID == nil:
+			draw = 1
+		case *args.WinnerID == p.id:
+			win = 1
+		default:
+			loss = 1
+		}
+
+		_, err = tx.Exec(ctx, applyRatingChange, p.id, DefaultRating, p.change, win, loss, draw)
+		if err != nil {
+			return nil, fmt.Errorf("updating rating for player with id %v: %w", p.id, err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return m, nil
+}
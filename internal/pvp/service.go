@@ -0,0 +1,210 @@
+package pvp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+)
+
+// PlayerLookup is the part of the player service matchmaking depends on.
+type PlayerLookup interface {
+	GetPlayerByID(ctx context.Context, id int32) (*player.Player, error)
+}
+
+// Transactor runs fn as a single unit of work that the repositories join
+// through ctx.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type PvPService struct {
+	repo    PvPRepository
+	tx      Transactor
+	players PlayerLookup
+	queue   *Queue
+	now     func() time.Time
+}
+
+func NewPvPService(repo PvPRepository, tx Transactor, players PlayerLookup, queue *Queue) *PvPService {
+	return &PvPService{repo: repo, tx: tx, players: players, queue: queue, now: time.Now}
+}
+
+var ErrInvalidWinner = apperr.Invalid("winner did not take part in the match")
+
+// GetRating returns the player's rating, or the default rating if they have
+// not played a ranked match yet.
+func (s *PvPService) GetRating(ctx context.Context, playerID int32) (*Rating, error) {
+	r, err := s.repo.GetRating(ctx, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("getting rating for player with id %v: %w", playerID, err)
+	}
+	if r == nil {
+		return &Rating{PlayerID: playerID, Rating: DefaultRating}, nil
+	}
+
+	return r, nil
+}
+
+// JoinQueue queues a player for a match, unless they already have a pending
+// one.
+func (s *PvPService) JoinQueue(ctx context.Context, playerID int32) (*QueueEntry, error) {
+	p, err := s.players.GetPlayerByID(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := s.repo.HasPendingMatch(ctx, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("checking for pending matches of player with id %v: %w", playerID, err)
+	}
+	if pending {
+		return nil, ErrInMatch
+	}
+
+	r, err := s.GetRating(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	e := QueueEntry{PlayerID: p.ID, Rating: r.Rating, Level: p.Level, JoinedAt: s.now()}
+	if err := s.queue.Join(e); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+func (s *PvPService) LeaveQueue(ctx context.Context, playerID int32) error {
+	return s.queue.Leave(playerID)
+}
+
+func (s *PvPService) GetQueueEntry(ctx context.Context, playerID int32) (*QueueEntry, error) {
+	e, ok := s.queue.Get(playerID)
+	if !ok {
+		return nil, ErrNotQueued
+	}
+	return &e, nil
+}
+
+// MatchQueuedPlayers pairs compatible queued players and creates a pending
+// match for each pair. Players whose match could not be stored are put back
+// in the queue.
+func (s *PvPService) MatchQueuedPlayers(ctx context.Context) ([]*Match, error) {
+	var matches []*Match
+	var errs []error
+
+	for _, p := range s.queue.Pair(s.now()) {
+		m, err := s.repo.CreateMatch(ctx, CreateMatchParams{
+			PlayerAID: p.A.PlayerID,
+			PlayerBID: p.B.PlayerID,
+			RatingA:   p.A.Rating,
+			RatingB:   p.B.Rating,
+		})
+		if err != nil {
+			s.queue.Requeue(p.A)
+			s.queue.Requeue(p.B)
+			errs = append(errs, fmt.Errorf("creating match for players %v and %v: %w", p.A.PlayerID, p.B.PlayerID, err))
+			continue
+		}
+
+		matches = append(matches, m)
+	}
+
+	return matches, errors.Join(errs...)
+}
+
+func (s *PvPService) GetMatchByID(ctx context.Context, id int32) (*Match, error) {
+	m, err := s.repo.GetMatchByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting match with id %v: %w", id, err)
+	}
+	if m == nil {
+		return nil, apperr.NotFound("match", "id", id)
+	}
+
+	return m, nil
+}
+
+func (s *PvPService) ListPlayerMatches(ctx context.Context, playerID int32) ([]*Match, error) {
+	matches, err := s.repo.ListPlayerMatches(ctx, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("getting matches for player with id %v: %w", playerID, err)
+	}
+
+	return matches, nil
+}
+
+// ReportResult completes a pending match. A nil winnerID records a draw. The
+// ratings are read and updated in one transaction holding them locked, so
+// results reported concurrently for the same player are computed from the
+// rating the other left.
+func (s *PvPService) ReportResult(ctx context.Context, matchID int32, winnerID *int32) (*Match, error) {
+	m, err := s.GetMatchByID(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Status != MatchPending {
+		return nil, ErrMatchNotPending
+	}
+	if winnerID != nil && !m.HasPlayer(*winnerID) {
+		return nil, ErrInvalidWinner
+	}
+
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		a, b, err := s.lockRatings(ctx, m.PlayerAID, m.PlayerBID)
+		if err != nil {
+			return err
+		}
+
+		outcome := OutcomeDraw
+		if winnerID != nil {
+			outcome = OutcomeWinB
+			if *winnerID == m.PlayerAID {
+				outcome = OutcomeWinA
+			}
+		}
+		changeA, changeB := RatingChanges(a, b, outcome)
+
+		m, err = s.repo.CompleteMatch(ctx, CompleteMatchParams{
+			ID:            matchID,
+			WinnerID:      winnerID,
+			RatingChangeA: changeA,
+			RatingChangeB: changeB,
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrMatchNotPending) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("completing match with id %v: %w", matchID, err)
+	}
+
+	s.queue.Finish(m.PlayerAID, m.PlayerBID)
+
+	return m, nil
+}
+
+// lockRatings locks the ratings of players a and b, lowest id first, and
+// returns them in argument order.
+func (s *PvPService) lockRatings(ctx context.Context, a, b int32) (*Rating, *Rating, error) {
+	first, second := a, b
+	if second < first {
+		first, second = second, first
+	}
+
+	ratings := make(map[int32]*Rating, 2)
+	for _, id := range []int32{first, second} {
+		r, err := s.repo.LockRating(ctx, id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("locking rating for player with id %v: %w", id, err)
+		}
+		ratings[id] = r
+	}
+
+	return ratings[a], ratings[b], nil
+}
@@ -0,0 +1,163 @@
+package pvp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+	"github.com/hossokawa/go-nethttp-example/internal/pvp"
+)
+
+// players looks players up in the memory tables, without the rest of the
+// player service.
+type players struct {
+	db *memdb.DB
+}
+
+func (p players) GetPlayerByID(ctx context.Context, id int32) (*player.Player, error) {
+	var found *player.Player
+	err := p.db.Read(ctx, func(t *memdb.Tables) error {
+		if row, ok := t.Players[id]; ok {
+			found = &player.Player{ID: row.ID, Username: row.Username, Level: row.Level}
+		}
+		return nil
+	})
+	if err == nil && found == nil {
+		err = apperr.NotFound("player", "id", id)
+	}
+	return found, err
+}
+
+// newStore returns memory tables holding players of level 10 with the given
+// ids.
+func newStore(t *testing.T, ids ...int32) *memdb.DB {
+	t.Helper()
+
+	db := memdb.New()
+	err := db.Write(t.Context(), func(t *memdb.Tables) error {
+		for _, id := range ids {
+			t.Players[id] = memdb.Player{ID: id, Level: 10}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("adding players: %v", err)
+	}
+
+	return db
+}
+
+func newService(t *testing.T, ids ...int32) *pvp.PvPService {
+	t.Helper()
+
+	db := newStore(t, ids...)
+	return pvp.NewPvPService(pvp.NewMemoryRepository(db), db, players{db}, pvp.NewQueue(pvp.DefaultMatchRules))
+}
+
+func matchPair(t *testing.T, s *pvp.PvPService, a, b int32) *pvp.Match {
+	t.Helper()
+
+	for _, id := range []int32{a, b} {
+		if _, err := s.JoinQueue(t.Context(), id); err != nil {
+			t.Fatalf("queueing player %d: %v", id, err)
+		}
+	}
+
+	matches, err := s.MatchQueuedPlayers(t.Context())
+	if err != nil {
+		t.Fatalf("matching: %v", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+	return matches[0]
+}
+
+func rating(t *testing.T, s *pvp.PvPService, id int32) int32 {
+	t.Helper()
+
+	r, err := s.GetRating(t.Context(), id)
+	if err != nil {
+		t.Fatalf("getting rating of player %d: %v", id, err)
+	}
+	return r.Rating
+}
+
+func TestPendingMatchBlocksQueue(t *testing.T) {
+	s := newService(t, 1, 2)
+	m := matchPair(t, s, 1, 2)
+
+	if _, err := s.JoinQueue(t.Context(), 1); !errors.Is(err, pvp.ErrInMatch) {
+		t.Fatalf("queueing with a pending match: got %v, want %v", err, pvp.ErrInMatch)
+	}
+
+	if _, err := s.ReportResult(t.Context(), m.ID, &m.PlayerAID); err != nil {
+		t.Fatalf("reporting result: %v", err)
+	}
+
+	if _, err := s.JoinQueue(t.Context(), 1); err != nil {
+		t.Fatalf("queueing after the match: %v", err)
+	}
+}
+
+// TestPendingMatchBlocksQueueAfterRestart checks that a match left pending
+// blocks queueing even for a queue that never paired the players.
+func TestPendingMatchBlocksQueueAfterRestart(t *testing.T) {
+	db := newStore(t, 1, 2)
+	repo := pvp.NewMemoryRepository(db)
+	if _, err := repo.CreateMatch(t.Context(), pvp.CreateMatchParams{PlayerAID: 1, PlayerBID: 2, RatingA: 1500, RatingB: 1500}); err != nil {
+		t.Fatalf("creating match: %v", err)
+	}
+
+	s := pvp.NewPvPService(repo, db, players{db}, pvp.NewQueue(pvp.DefaultMatchRules))
+	if _, err := s.JoinQueue(t.Context(), 2); !errors.Is(err, pvp.ErrInMatch) {
+		t.Fatalf("queueing with a pending match: got %v, want %v", err, pvp.ErrInMatch)
+	}
+}
+
+func TestReportResult(t *testing.T) {
+	s := newService(t, 1, 2, 3)
+
+	m := matchPair(t, s, 1, 2)
+	got, err := s.ReportResult(t.Context(), m.ID, &m.PlayerAID)
+	if err != nil {
+		t.Fatalf("reporting result: %v", err)
+	}
+	if got.Status != pvp.MatchCompleted || got.RatingChangeA != 20 || got.RatingChangeB != -20 {
+		t.Fatalf("completed match = %+v, want a 20 point swing", got)
+	}
+	if a, b := rating(t, s, m.PlayerAID), rating(t, s, m.PlayerBID); a != 1520 || b != 1480 {
+		t.Fatalf("ratings = %d, %d, want 1520, 1480", a, b)
+	}
+
+	if _, err := s.ReportResult(t.Context(), m.ID, nil); !errors.Is(err, pvp.ErrMatchNotPending) {
+		t.Fatalf("reporting twice: got %v, want %v", err, pvp.ErrMatchNotPending)
+	}
+
+	// The next match of the winner is rated from their new rating.
+	winner := m.PlayerAID
+	next := matchPair(t, s, winner, 3)
+	if next, err = s.ReportResult(t.Context(), next.ID, nil); err != nil {
+		t.Fatalf("reporting draw: %v", err)
+	}
+	changeW, change3 := pvp.RatingChanges(&pvp.Rating{Rating: 1520, Wins: 1}, &pvp.Rating{Rating: 1500}, pvp.OutcomeDraw)
+	if w := rating(t, s, winner); w != 1520+changeW {
+		t.Errorf("winner's rating after the draw = %d, want %d", w, 1520+changeW)
+	}
+	if r := rating(t, s, 3); r != 1500+change3 {
+		t.Errorf("player 3's rating after the draw = %d, want %d", r, 1500+change3)
+	}
+}
+
+func TestReportResultRejectsOutsider(t *testing.T) {
+	s := newService(t, 1, 2, 3)
+	m := matchPair(t, s, 1, 2)
+
+	outsider := int32(3)
+	if _, err := s.ReportResult(t.Context(), m.ID, &outsider); !errors.Is(err, pvp.ErrInvalidWinner) {
+		t.Fatalf("got %v, want %v", err, pvp.ErrInvalidWinner)
+	}
+}
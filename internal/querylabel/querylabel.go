@@ -0,0 +1,28 @@
+// Package querylabel names the repository method queries are made for. Each
+// repository method labels its context on entry, and the query metrics read
+// the label back, so queries are broken down by method without the metrics
+// knowing about the repositories.
+package querylabel
+
+import "context"
+
+// Label names the repository method a query is made for.
+type Label struct {
+	Repository string
+	Method     string
+}
+
+type labelKey struct{}
+
+// With returns a copy of ctx labelling the queries made with it as made by
+// method of repository. A label set further in, such as by a repository
+// method calling another, takes precedence.
+func With(ctx context.Context, repository, method string) context.Context {
+	return context.WithValue(ctx, labelKey{}, Label{Repository: repository, Method: method})
+}
+
+// From returns the label of ctx, if it has one.
+func From(ctx context.Context) (Label, bool) {
+	l, ok := ctx.Value(labelKey{}).(Label)
+	return l, ok
+}
@@ -0,0 +1,140 @@
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/inventory"
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+)
+
+// Cancellation checks that every repository gives up on a call whose context
+// is done, so that a request that was cancelled or ran out of time stops
+// waiting on the store, and that a write given up on is not applied.
+func Cancellation(t *testing.T, newRepos Factory) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	for _, c := range []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{"Cancelled", cancelled, context.Canceled},
+		{"DeadlineExceeded", expired, context.DeadlineExceeded},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			repos := newRepos(t)
+			p := createPlayer(t, repos.Players)
+			i := createItem(t, repos.Items)
+
+			check := func(call string, err error) {
+				t.Helper()
+				if !errors.Is(err, c.want) {
+					t.Errorf("%s = %v, want %v", call, err, c.want)
+				}
+			}
+
+			_, err := repos.Players.GetPlayerByID(c.ctx, p.ID)
+			check("GetPlayerByID", err)
+			_, err = repos.Players.ListPlayers(c.ctx, player.ListPlayersParams{Params: listing.Params{Sort: "id", Limit: 10}})
+			check("ListPlayers", err)
+			_, err = repos.Items.GetItemByID(c.ctx, i.ID)
+			check("GetItemByID", err)
+			_, err = repos.Inventory.ListPlayerItems(c.ctx, p.ID)
+			check("ListPlayerItems", err)
+
+			username := randomName("Late")
+			_, err = repos.Players.CreatePlayer(c.ctx, player.CreatePlayerParams{
+				Username:    username,
+				Class:       "Druid",
+				UsernameKey: player.UsernameKey(username),
+			})
+			check("CreatePlayer", err)
+			if got, err := repos.Players.GetPlayerByUsername(t.Context(), username); got != nil || err != nil {
+				t.Errorf("player created with a done context: %v, %v", got, err)
+			}
+
+			err = repos.Inventory.AddItem(c.ctx, inventory.AddItemParams{PlayerID: p.ID, ItemID: i.ID})
+			check("AddItem", err)
+			items, err := repos.Inventory.ListPlayerItems(t.Context(), p.ID)
+			if err != nil {
+				t.Fatalf("ListPlayerItems: %v", err)
+			}
+			if len(items) != 0 {
+				t.Errorf("item added with a done context: %v", items)
+			}
+
+			name := randomName("Renamed")
+			_, err = repos.Items.UpdateItem(c.ctx, item.UpdateItemParams{ID: i.ID, Name: &name})
+			check("UpdateItem", err)
+		})
+	}
+}
+
+// Slow queries keep a database busy for far longer than the tests wait, so
+// that they are still running when the tests cancel them.
+const (
+	SlowPostgresQuery = `SELECT pg_sleep(30)`
+	SlowSQLiteQuery   = `WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 1000000000) SELECT count(*) FROM n`
+)
+
+// Backend is a database handle QueryCancelledInFlight runs against.
+type Backend struct {
+	// Slow runs one of the slow queries and returns the error it stopped
+	// with.
+	Slow func(ctx context.Context) error
+	// InUse returns how many connections are taken out of the pool.
+	InUse func() int
+	Ping  func(ctx context.Context) error
+}
+
+// QueryCancelledInFlight checks that cancelling a query while it runs
+// interrupts it, reports why, and gives its connection back to the pool.
+func QueryCancelledInFlight(t *testing.T, db Backend) {
+	const after = 100 * time.Millisecond
+
+	for _, c := range []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		want error
+	}{
+		{"Cancelled", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(t.Context())
+			time.AfterFunc(after, cancel)
+			return ctx, cancel
+		}, context.Canceled},
+		{"DeadlineExceeded", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(t.Context(), after)
+		}, context.DeadlineExceeded},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, cancel := c.ctx()
+			defer cancel()
+
+			start := time.Now()
+			if err := db.Slow(ctx); !errors.Is(err, c.want) {
+				t.Fatalf("slow query = %v, want %v", err, c.want)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("query took %v to give up", elapsed)
+			}
+
+			if inUse := db.InUse(); inUse != 0 {
+				t.Errorf("%d connections still in use", inUse)
+			}
+			pingCtx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+			defer cancel()
+			if err := db.Ping(pingCtx); err != nil {
+				t.Errorf("Ping after the cancelled query: %v", err)
+			}
+		})
+	}
+}
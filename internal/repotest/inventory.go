@@ -0,0 +1,217 @@
+package repotest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/inventory"
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+)
+
+// InventoryRepository checks an inventory.InventoryRepository, using the
+// player and item repositories to set up the rows it refers to.
+func InventoryRepository(t *testing.T, newRepos Factory) {
+	t.Run("AddAndList", func(t *testing.T) {
+		repos := newRepos(t)
+		p := createPlayer(t, repos.Players)
+		sword, shield := createItem(t, repos.Items), createItem(t, repos.Items)
+
+		for _, i := range []*item.Item{sword, shield} {
+			if err := repos.Inventory.AddItem(t.Context(), inventory.AddItemParams{PlayerID: p.ID, ItemID: i.ID}); err != nil {
+				t.Fatalf("AddItem: %v", err)
+			}
+		}
+
+		items, err := repos.Inventory.ListPlayerItems(t.Context(), p.ID)
+		if err != nil {
+			t.Fatalf("ListPlayerItems: %v", err)
+		}
+		if !sameItems(items, sword.ID, shield.ID) {
+			t.Errorf("ListPlayerItems = %+v, want items %v and %v", items, sword.ID, shield.ID)
+		}
+
+		page, err := repos.Inventory.ListInventory(t.Context(), inventory.ListInventoryParams{
+			Params:   listing.Params{Sort: "id", Limit: 1},
+			PlayerID: p.ID,
+		})
+		if err != nil {
+			t.Fatalf("ListInventory: %v", err)
+		}
+		if len(page.Items) != 1 || page.NextCursor == "" {
+			t.Errorf("ListInventory with limit 1 = %+v, want one item and a next cursor", page)
+		}
+	})
+
+	t.Run("AlreadyOwned", func(t *testing.T) {
+		repos := newRepos(t)
+		p := createPlayer(t, repos.Players)
+		i := createItem(t, repos.Items)
+
+		args := inventory.AddItemParams{PlayerID: p.ID, ItemID: i.ID}
+		if err := repos.Inventory.AddItem(t.Context(), args); err != nil {
+			t.Fatalf("AddItem: %v", err)
+		}
+		if err := repos.Inventory.AddItem(t.Context(), args); !errors.Is(err, inventory.ErrItemAlreadyOwned) {
+			t.Errorf("AddItem twice = %v, want ErrItemAlreadyOwned", err)
+		}
+	})
+
+	t.Run("MissingReferences", func(t *testing.T) {
+		repos := newRepos(t)
+		p := createPlayer(t, repos.Players)
+		i := createItem(t, repos.Items)
+
+		if err := repos.Inventory.AddItem(t.Context(), inventory.AddItemParams{PlayerID: missingPlayerID, ItemID: i.ID}); err == nil {
+			t.Error("AddItem for a missing player succeeded")
+		}
+		if err := repos.Inventory.AddItem(t.Context(), inventory.AddItemParams{PlayerID: p.ID, ItemID: uuid.New()}); err == nil {
+			t.Error("AddItem of a missing item succeeded")
+		}
+	})
+
+	t.Run("Equip", func(t *testing.T) {
+		repos := newRepos(t)
+		p := createPlayer(t, repos.Players)
+		sword, shield := createItem(t, repos.Items), createItem(t, repos.Items)
+
+		err := repos.Inventory.SetItemEquipped(t.Context(), inventory.SetItemEquippedParams{PlayerID: p.ID, ItemID: sword.ID, Equipped: true})
+		if !errors.Is(err, inventory.ErrItemNotInInventory) {
+			t.Errorf("SetItemEquipped of an item not in the inventory = %v, want ErrItemNotInInventory", err)
+		}
+
+		for _, i := range []*item.Item{sword, shield} {
+			if err := repos.Inventory.AddItem(t.Context(), inventory.AddItemParams{PlayerID: p.ID, ItemID: i.ID}); err != nil {
+				t.Fatalf("AddItem: %v", err)
+			}
+		}
+		err = repos.Inventory.SetItemEquipped(t.Context(), inventory.SetItemEquippedParams{PlayerID: p.ID, ItemID: sword.ID, Equipped: true})
+		if err != nil {
+			t.Fatalf("SetItemEquipped: %v", err)
+		}
+
+		equipped, err := repos.Inventory.ListEquippedItems(t.Context(), p.ID)
+		if err != nil {
+			t.Fatalf("ListEquippedItems: %v", err)
+		}
+		if !sameItems(equipped, sword.ID) {
+			t.Errorf("ListEquippedItems = %+v, want only item %v", equipped, sword.ID)
+		}
+
+		yes := true
+		page, err := repos.Inventory.ListInventory(t.Context(), inventory.ListInventoryParams{
+			Params:   listing.Params{Sort: "id", Limit: 10},
+			PlayerID: p.ID,
+			Equipped: &yes,
+		})
+		if err != nil {
+			t.Fatalf("ListInventory: %v", err)
+		}
+		if len(page.Items) != 1 || page.Items[0].ID != sword.ID {
+			t.Errorf("ListInventory of equipped items = %+v, want only item %v", page.Items, sword.ID)
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		repos := newRepos(t)
+		p := createPlayer(t, repos.Players)
+		i := createItem(t, repos.Items)
+
+		args := inventory.RemoveItemParams{PlayerID: p.ID, ItemID: i.ID}
+		if err := repos.Inventory.RemoveItem(t.Context(), args); err != nil {
+			t.Errorf("RemoveItem of an item not in the inventory = %v, want nil", err)
+		}
+
+		if err := repos.Inventory.AddItem(t.Context(), inventory.AddItemParams{PlayerID: p.ID, ItemID: i.ID}); err != nil {
+			t.Fatalf("AddItem: %v", err)
+		}
+		if err := repos.Inventory.RemoveItem(t.Context(), args); err != nil {
+			t.Fatalf("RemoveItem: %v", err)
+		}
+
+		items, err := repos.Inventory.ListPlayerItems(t.Context(), p.ID)
+		if err != nil {
+			t.Fatalf("ListPlayerItems: %v", err)
+		}
+		if len(items) != 0 {
+			t.Errorf("ListPlayerItems after remove = %+v, want no items", items)
+		}
+	})
+
+	t.Run("DeletedItemsHidden", func(t *testing.T) {
+		repos := newRepos(t)
+		p := createPlayer(t, repos.Players)
+		i := createItem(t, repos.Items)
+
+		if err := repos.Inventory.AddItem(t.Context(), inventory.AddItemParams{PlayerID: p.ID, ItemID: i.ID}); err != nil {
+			t.Fatalf("AddItem: %v", err)
+		}
+		err := repos.Inventory.SetItemEquipped(t.Context(), inventory.SetItemEquippedParams{PlayerID: p.ID, ItemID: i.ID, Equipped: true})
+		if err != nil {
+			t.Fatalf("SetItemEquipped: %v", err)
+		}
+		if err := repos.Items.DeleteItemByID(t.Context(), item.DeleteItemParams{ID: i.ID}); err != nil {
+			t.Fatalf("DeleteItemByID: %v", err)
+		}
+
+		items, err := repos.Inventory.ListPlayerItems(t.Context(), p.ID)
+		if err != nil {
+			t.Fatalf("ListPlayerItems: %v", err)
+		}
+		if len(items) != 0 {
+			t.Errorf("ListPlayerItems = %+v, want the deleted item hidden", items)
+		}
+
+		equipped, err := repos.Inventory.ListEquippedItems(t.Context(), p.ID)
+		if err != nil {
+			t.Fatalf("ListEquippedItems: %v", err)
+		}
+		if len(equipped) != 0 {
+			t.Errorf("ListEquippedItems = %+v, want the deleted item hidden", equipped)
+		}
+	})
+
+	t.Run("ConcurrentAdd", func(t *testing.T) {
+		repos := newRepos(t)
+		p := createPlayer(t, repos.Players)
+		i := createItem(t, repos.Items)
+
+		errs := parallel(concurrency, func(int) error {
+			return repos.Inventory.AddItem(t.Context(), inventory.AddItemParams{PlayerID: p.ID, ItemID: i.ID})
+		})
+
+		var won int
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				won++
+			case !errors.Is(err, inventory.ErrItemAlreadyOwned):
+				t.Errorf("AddItem: %v", err)
+			}
+		}
+		if won != 1 {
+			t.Errorf("%v of %v adds of the same item succeeded, want 1", won, concurrency)
+		}
+	})
+}
+
+// sameItems reports whether items holds exactly the items with the given ids,
+// in any order.
+func sameItems(items []item.Item, ids ...uuid.UUID) bool {
+	if len(items) != len(ids) {
+		return false
+	}
+
+	want := make(map[uuid.UUID]bool)
+	for _, id := range ids {
+		want[id] = true
+	}
+	for _, i := range items {
+		if !want[i.ID] {
+			return false
+		}
+	}
+
+	return true
+}
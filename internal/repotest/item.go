@@ -0,0 +1,276 @@
+package repotest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+)
+
+// ItemRepository checks an item.ItemRepository.
+func ItemRepository(t *testing.T, newRepos Factory) {
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repo := newRepos(t).Items
+		created := createItem(t, repo)
+
+		if created.Version != 1 || created.Stats == nil {
+			t.Errorf("new item has version %v and stats %v; want 1 and an empty map", created.Version, created.Stats)
+		}
+
+		got, err := repo.GetItemByID(t.Context(), created.ID)
+		if err != nil {
+			t.Fatalf("GetItemByID: %v", err)
+		}
+		if got == nil || got.Name != created.Name || got.Value != created.Value {
+			t.Errorf("GetItemByID = %+v, want %+v", got, created)
+		}
+
+		got, err = repo.GetItemByName(t.Context(), created.Name)
+		if err != nil {
+			t.Fatalf("GetItemByName: %v", err)
+		}
+		if got == nil || got.ID != created.ID {
+			t.Errorf("GetItemByName = %+v, want item %v", got, created.ID)
+		}
+	})
+
+	t.Run("CreateAssignsIDs", func(t *testing.T) {
+		repo := newRepos(t).Items
+		a := createItem(t, repo)
+		b := createItem(t, repo)
+
+		if a.ID == uuid.Nil || b.ID == uuid.Nil || a.ID == b.ID {
+			t.Fatalf("CreateItem returned ids %v and %v; want two distinct ids", a.ID, b.ID)
+		}
+
+		for _, created := range []*item.Item{a, b} {
+			got, err := repo.GetItemByID(t.Context(), created.ID)
+			if err != nil {
+				t.Fatalf("GetItemByID: %v", err)
+			}
+			if got == nil || got.ID != created.ID || got.Name != created.Name {
+				t.Errorf("GetItemByID(%v) = %+v, want %+v", created.ID, got, created)
+			}
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepos(t).Items
+		missing := uuid.New()
+
+		if i, err := repo.GetItemByID(t.Context(), missing); i != nil || err != nil {
+			t.Errorf("GetItemByID = %v, %v; want nil, nil", i, err)
+		}
+		if i, err := repo.GetItemByName(t.Context(), randomName("missing")); i != nil || err != nil {
+			t.Errorf("GetItemByName = %v, %v; want nil, nil", i, err)
+		}
+
+		value := int32(1)
+		if i, err := repo.UpdateItem(t.Context(), item.UpdateItemParams{ID: missing, Value: &value}); i != nil || err != nil {
+			t.Errorf("UpdateItem = %v, %v; want nil, nil", i, err)
+		}
+		if err := repo.DeleteItemByID(t.Context(), item.DeleteItemParams{ID: missing}); err != nil {
+			t.Errorf("DeleteItemByID = %v, want nil", err)
+		}
+		if i, err := repo.RestoreItem(t.Context(), item.RestoreItemParams{ID: missing, GracePeriod: time.Hour}); i != nil || err != nil {
+			t.Errorf("RestoreItem = %v, %v; want nil, nil", i, err)
+		}
+	})
+
+	t.Run("UniqueName", func(t *testing.T) {
+		repo := newRepos(t).Items
+		a := createItem(t, repo)
+		b := createItem(t, repo)
+
+		_, err := repo.CreateItem(t.Context(), item.CreateItemParams{Name: a.Name, Value: 1})
+		if !errors.Is(err, item.ErrItemNameInUse) {
+			t.Errorf("CreateItem with a taken name = %v, want ErrItemNameInUse", err)
+		}
+
+		_, err = repo.UpdateItem(t.Context(), item.UpdateItemParams{ID: b.ID, Name: &a.Name})
+		if !errors.Is(err, item.ErrItemNameInUse) {
+			t.Errorf("UpdateItem to a taken name = %v, want ErrItemNameInUse", err)
+		}
+	})
+
+	t.Run("VersionMismatch", func(t *testing.T) {
+		repo := newRepos(t).Items
+		created := createItem(t, repo)
+
+		stale := created.Version - 1
+		value := int32(99)
+		_, err := repo.UpdateItem(t.Context(), item.UpdateItemParams{ID: created.ID, Value: &value, Version: &stale})
+		if !errors.Is(err, item.ErrVersionMismatch) {
+			t.Errorf("UpdateItem at a stale version = %v, want ErrVersionMismatch", err)
+		}
+
+		err = repo.DeleteItemByID(t.Context(), item.DeleteItemParams{ID: created.ID, Version: &stale})
+		if !errors.Is(err, item.ErrVersionMismatch) {
+			t.Errorf("DeleteItemByID at a stale version = %v, want ErrVersionMismatch", err)
+		}
+
+		updated, err := repo.UpdateItem(t.Context(), item.UpdateItemParams{ID: created.ID, Value: &value, Version: &created.Version})
+		if err != nil {
+			t.Fatalf("UpdateItem at the current version: %v", err)
+		}
+		if updated.Value != value || updated.Version != created.Version+1 {
+			t.Errorf("UpdateItem = %+v, want value %v at version %v", updated, value, created.Version+1)
+		}
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		repo := newRepos(t).Items
+
+		before, err := repo.CountItems(t.Context())
+		if err != nil {
+			t.Fatalf("CountItems: %v", err)
+		}
+
+		createItem(t, repo)
+		deleted := createItem(t, repo)
+		if err := repo.DeleteItemByID(t.Context(), item.DeleteItemParams{ID: deleted.ID}); err != nil {
+			t.Fatalf("DeleteItemByID: %v", err)
+		}
+
+		after, err := repo.CountItems(t.Context())
+		if err != nil {
+			t.Fatalf("CountItems: %v", err)
+		}
+		if after-before != 1 {
+			t.Errorf("CountItems grew by %v, want 1", after-before)
+		}
+	})
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		repo := newRepos(t).Items
+		created := createItem(t, repo)
+
+		if err := repo.DeleteItemByID(t.Context(), item.DeleteItemParams{ID: created.ID}); err != nil {
+			t.Fatalf("DeleteItemByID: %v", err)
+		}
+		if i, err := repo.GetItemByID(t.Context(), created.ID); i != nil || err != nil {
+			t.Errorf("GetItemByID after delete = %v, %v; want nil, nil", i, err)
+		}
+
+		page, err := repo.ListItems(t.Context(), item.ListItemsParams{
+			Params:     listing.Params{Sort: "id", Limit: 10},
+			NamePrefix: created.Name,
+		})
+		if err != nil {
+			t.Fatalf("ListItems: %v", err)
+		}
+		if len(page.Items) != 0 {
+			t.Errorf("ListItems after delete = %+v, want no items", page.Items)
+		}
+
+		restored, err := repo.RestoreItem(t.Context(), item.RestoreItemParams{ID: created.ID, GracePeriod: time.Hour})
+		if err != nil {
+			t.Fatalf("RestoreItem: %v", err)
+		}
+		if restored == nil || restored.ID != created.ID {
+			t.Errorf("RestoreItem = %+v, want item %v", restored, created.ID)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		repo := newRepos(t).Items
+		prefix := randomName("List")
+		for _, value := range []int32{30, 10, 20} {
+			if _, err := repo.CreateItem(t.Context(), item.CreateItemParams{Name: randomName(prefix), Value: value}); err != nil {
+				t.Fatalf("CreateItem: %v", err)
+			}
+		}
+
+		params := item.ListItemsParams{Params: listing.Params{Sort: "value", Limit: 2}, NamePrefix: prefix}
+		page, err := repo.ListItems(t.Context(), params)
+		if err != nil {
+			t.Fatalf("ListItems: %v", err)
+		}
+		if len(page.Items) != 2 || page.Items[0].Value != 10 || page.Items[1].Value != 20 || page.NextCursor == "" {
+			t.Fatalf("first page = %+v, want values 10 and 20 and a next cursor", page)
+		}
+
+		params.Cursor, err = listing.DecodeCursor(page.NextCursor)
+		if err != nil {
+			t.Fatalf("DecodeCursor: %v", err)
+		}
+		page, err = repo.ListItems(t.Context(), params)
+		if err != nil {
+			t.Fatalf("ListItems: %v", err)
+		}
+		if len(page.Items) != 1 || page.Items[0].Value != 30 || page.NextCursor != "" {
+			t.Errorf("second page = %+v, want only value 30", page)
+		}
+	})
+
+	t.Run("UnknownCurrency", func(t *testing.T) {
+		repo := newRepos(t).Items
+		created := createItem(t, repo)
+
+		err := repo.SetItemPrice(t.Context(), item.SetItemPriceParams{ID: created.ID, Currency: randomName("currency"), Amount: 5})
+		if !errors.Is(err, item.ErrUnknownCurrency) {
+			t.Errorf("SetItemPrice in an unknown currency = %v, want ErrUnknownCurrency", err)
+		}
+	})
+
+	t.Run("ConcurrentConditionalUpdates", func(t *testing.T) {
+		repo := newRepos(t).Items
+		created := createItem(t, repo)
+
+		errs := parallel(concurrency, func(i int) error {
+			value := int32(i)
+			_, err := repo.UpdateItem(t.Context(), item.UpdateItemParams{ID: created.ID, Value: &value, Version: &created.Version})
+			return err
+		})
+
+		var won int
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				won++
+			case !errors.Is(err, item.ErrVersionMismatch):
+				t.Errorf("UpdateItem: %v", err)
+			}
+		}
+		if won != 1 {
+			t.Errorf("%v of %v updates at the same version succeeded, want 1", won, concurrency)
+		}
+	})
+
+	t.Run("ConcurrentCreateSameName", func(t *testing.T) {
+		repo := newRepos(t).Items
+		name := randomName("Racer")
+
+		errs := parallel(concurrency, func(int) error {
+			_, err := repo.CreateItem(t.Context(), item.CreateItemParams{Name: name, Value: 1})
+			return err
+		})
+
+		var won int
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				won++
+			case !errors.Is(err, item.ErrItemNameInUse):
+				t.Errorf("CreateItem: %v", err)
+			}
+		}
+		if won != 1 {
+			t.Errorf("%v of %v items with the same name were created, want 1", won, concurrency)
+		}
+	})
+}
+
+func createItem(t *testing.T, repo item.ItemRepository) *item.Item {
+	t.Helper()
+
+	i, err := repo.CreateItem(t.Context(), item.CreateItemParams{Name: randomName("Item"), Value: 10})
+	if err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+
+	return i
+}
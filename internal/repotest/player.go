@@ -0,0 +1,462 @@
+package repotest
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+)
+
+// missingPlayerID is an id no test player is ever given.
+const missingPlayerID = math.MaxInt32
+
+// PlayerRepository checks a player.PlayerRepository.
+func PlayerRepository(t *testing.T, newRepos Factory) {
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repo := newRepos(t).Players
+		created := createPlayer(t, repo)
+
+		if created.Level != 1 || created.Gold != 0 || created.Version != 1 {
+			t.Errorf("new player has level %v, gold %v, version %v; want 1, 0, 1", created.Level, created.Gold, created.Version)
+		}
+
+		got, err := repo.GetPlayerByID(t.Context(), created.ID)
+		if err != nil {
+			t.Fatalf("GetPlayerByID: %v", err)
+		}
+		if got == nil || got.Username != created.Username || got.Class != created.Class {
+			t.Errorf("GetPlayerByID = %+v, want %+v", got, created)
+		}
+
+		got, err = repo.GetPlayerByUsername(t.Context(), strings.ToUpper(created.Username))
+		if err != nil {
+			t.Fatalf("GetPlayerByUsername: %v", err)
+		}
+		if got == nil || got.ID != created.ID {
+			t.Errorf("GetPlayerByUsername ignoring case = %+v, want player %v", got, created.ID)
+		}
+	})
+
+	t.Run("StartingGold", func(t *testing.T) {
+		repo := newRepos(t).Players
+		username := randomName("Rich")
+
+		created, err := repo.CreatePlayer(t.Context(), player.CreatePlayerParams{
+			Username:    username,
+			Class:       "Rogue",
+			UsernameKey: player.UsernameKey(username),
+			Gold:        250,
+		})
+		if err != nil {
+			t.Fatalf("CreatePlayer: %v", err)
+		}
+		if created.Gold != 250 {
+			t.Errorf("new player has gold %v, want 250", created.Gold)
+		}
+	})
+
+	t.Run("Totals", func(t *testing.T) {
+		repo := newRepos(t).Players
+
+		before, err := repo.GetPlayerTotals(t.Context())
+		if err != nil {
+			t.Fatalf("GetPlayerTotals: %v", err)
+		}
+
+		username := randomName("Counted")
+		created, err := repo.CreatePlayer(t.Context(), player.CreatePlayerParams{
+			Username:    username,
+			Class:       "Sorcerer",
+			UsernameKey: player.UsernameKey(username),
+			Gold:        40,
+		})
+		if err != nil {
+			t.Fatalf("CreatePlayer: %v", err)
+		}
+		deleted := createPlayer(t, repo)
+		if err := repo.DeletePlayerByID(t.Context(), player.DeletePlayerParams{ID: deleted.ID}); err != nil {
+			t.Fatalf("DeletePlayerByID: %v", err)
+		}
+
+		after, err := repo.GetPlayerTotals(t.Context())
+		if err != nil {
+			t.Fatalf("GetPlayerTotals: %v", err)
+		}
+		if got := after.ByClass[created.Class] - before.ByClass[created.Class]; got != 1 {
+			t.Errorf("%v count grew by %v, want 1", created.Class, got)
+		}
+		if got := after.ByClass[deleted.Class] - before.ByClass[deleted.Class]; got != 0 {
+			t.Errorf("%v count grew by %v, want 0 for a deleted player", deleted.Class, got)
+		}
+		if got := after.Gold - before.Gold; got != 40 {
+			t.Errorf("gold grew by %v, want 40", got)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepos(t).Players
+
+		if p, err := repo.GetPlayerByID(t.Context(), missingPlayerID); p != nil || err != nil {
+			t.Errorf("GetPlayerByID = %v, %v; want nil, nil", p, err)
+		}
+		if p, err := repo.GetPlayerByUsername(t.Context(), randomName("missing")); p != nil || err != nil {
+			t.Errorf("GetPlayerByUsername = %v, %v; want nil, nil", p, err)
+		}
+
+		level := int32(2)
+		if p, err := repo.UpdatePlayer(t.Context(), player.UpdatePlayerParams{ID: missingPlayerID, Level: &level}); p != nil || err != nil {
+			t.Errorf("UpdatePlayer = %v, %v; want nil, nil", p, err)
+		}
+		if err := repo.DeletePlayerByID(t.Context(), player.DeletePlayerParams{ID: missingPlayerID}); err != nil {
+			t.Errorf("DeletePlayerByID = %v, want nil", err)
+		}
+		if p, err := repo.RestorePlayer(t.Context(), player.RestorePlayerParams{ID: missingPlayerID, GracePeriod: time.Hour}); p != nil || err != nil {
+			t.Errorf("RestorePlayer = %v, %v; want nil, nil", p, err)
+		}
+		if err := repo.IncreasePlayerGold(t.Context(), player.UpdatePlayerGoldParams{ID: missingPlayerID, Amount: 10}); err != nil {
+			t.Errorf("IncreasePlayerGold = %v, want nil", err)
+		}
+	})
+
+	t.Run("UniqueUsername", func(t *testing.T) {
+		repo := newRepos(t).Players
+		created := createPlayer(t, repo)
+
+		for _, username := range []string{created.Username, strings.ToLower(created.Username)} {
+			_, err := repo.CreatePlayer(t.Context(), player.CreatePlayerParams{
+				Username:    username,
+				Class:       "Mage",
+				UsernameKey: player.UsernameKey(username),
+			})
+			if !errors.Is(err, player.ErrUsernameInUse) {
+				t.Errorf("CreatePlayer(%q) = %v, want ErrUsernameInUse", username, err)
+			}
+		}
+	})
+
+	t.Run("UsernameOfDeletedPlayer", func(t *testing.T) {
+		repo := newRepos(t).Players
+		created := createPlayer(t, repo)
+
+		if err := repo.DeletePlayerByID(t.Context(), player.DeletePlayerParams{ID: created.ID}); err != nil {
+			t.Fatalf("DeletePlayerByID: %v", err)
+		}
+
+		_, err := repo.CreatePlayer(t.Context(), player.CreatePlayerParams{
+			Username:    created.Username,
+			Class:       "Mage",
+			UsernameKey: player.UsernameKey(created.Username),
+		})
+		if !errors.Is(err, player.ErrUsernameInUse) {
+			t.Errorf("CreatePlayer with a deleted player's name = %v, want ErrUsernameInUse", err)
+		}
+	})
+
+	t.Run("Rename", func(t *testing.T) {
+		repo := newRepos(t).Players
+		a := createPlayer(t, repo)
+		b := createPlayer(t, repo)
+
+		newName := randomName("Renamed")
+		renamed, err := repo.RenamePlayer(t.Context(), player.RenamePlayerParams{
+			ID:          a.ID,
+			Username:    newName,
+			UsernameKey: player.UsernameKey(newName),
+			Cooldown:    time.Hour,
+			HoldPeriod:  time.Hour,
+		})
+		if err != nil {
+			t.Fatalf("RenamePlayer: %v", err)
+		}
+		if renamed.Username != newName || renamed.Version != a.Version+1 || renamed.RenamedAt == nil {
+			t.Errorf("RenamePlayer = %+v, want username %v at version %v", renamed, newName, a.Version+1)
+		}
+
+		_, err = repo.RenamePlayer(t.Context(), player.RenamePlayerParams{
+			ID:          b.ID,
+			Username:    a.Username,
+			UsernameKey: player.UsernameKey(a.Username),
+			Cooldown:    time.Hour,
+			HoldPeriod:  time.Hour,
+		})
+		if !errors.Is(err, player.ErrUsernameInUse) {
+			t.Errorf("renaming to a held name = %v, want ErrUsernameInUse", err)
+		}
+
+		again := randomName("Again")
+		_, err = repo.RenamePlayer(t.Context(), player.RenamePlayerParams{
+			ID:          a.ID,
+			Username:    again,
+			UsernameKey: player.UsernameKey(again),
+			Cooldown:    time.Hour,
+			HoldPeriod:  time.Hour,
+		})
+		if err == nil {
+			t.Error("renaming again within the cooldown succeeded")
+		}
+
+		history, err := repo.ListNameHistory(t.Context(), a.ID)
+		if err != nil {
+			t.Fatalf("ListNameHistory: %v", err)
+		}
+		if len(history) != 1 || history[0].Username != a.Username {
+			t.Errorf("ListNameHistory = %+v, want only %v", history, a.Username)
+		}
+	})
+
+	t.Run("GoldArithmetic", func(t *testing.T) {
+		repo := newRepos(t).Players
+		created := createPlayer(t, repo)
+
+		if err := repo.IncreasePlayerGold(t.Context(), player.UpdatePlayerGoldParams{ID: created.ID, Amount: 100}); err != nil {
+			t.Fatalf("IncreasePlayerGold: %v", err)
+		}
+		if err := repo.DecreasePlayerGold(t.Context(), player.UpdatePlayerGoldParams{ID: created.ID, Amount: 30}); err != nil {
+			t.Fatalf("DecreasePlayerGold: %v", err)
+		}
+
+		got := getPlayer(t, repo, created.ID)
+		if got.Gold != 70 {
+			t.Errorf("gold = %v, want 70", got.Gold)
+		}
+		if got.Version != created.Version+2 {
+			t.Errorf("version = %v, want %v", got.Version, created.Version+2)
+		}
+	})
+
+	t.Run("UpdateGold", func(t *testing.T) {
+		repo := newRepos(t).Players
+		created := createPlayer(t, repo)
+
+		gold := int32(250)
+		if _, err := repo.UpdatePlayer(t.Context(), player.UpdatePlayerParams{ID: created.ID, Gold: &gold}); err != nil {
+			t.Fatalf("UpdatePlayer: %v", err)
+		}
+		if got := getPlayer(t, repo, created.ID); got.Gold != gold {
+			t.Errorf("gold = %v, want %v", got.Gold, gold)
+		}
+
+		// An update that leaves gold out keeps it as it is.
+		class := "Mage"
+		updated, err := repo.UpdatePlayer(t.Context(), player.UpdatePlayerParams{ID: created.ID, Class: &class})
+		if err != nil {
+			t.Fatalf("UpdatePlayer: %v", err)
+		}
+		if updated.Gold != gold || updated.Class != class {
+			t.Errorf("UpdatePlayer = %+v, want class %v and gold %v", updated, class, gold)
+		}
+	})
+
+	t.Run("GoldNeverNegative", func(t *testing.T) {
+		repo := newRepos(t).Players
+		created := createPlayer(t, repo)
+
+		if err := repo.IncreasePlayerGold(t.Context(), player.UpdatePlayerGoldParams{ID: created.ID, Amount: 10}); err != nil {
+			t.Fatalf("IncreasePlayerGold: %v", err)
+		}
+		err := repo.DecreasePlayerGold(t.Context(), player.UpdatePlayerGoldParams{ID: created.ID, Amount: created.Gold + 11})
+		if !errors.Is(err, player.ErrInsufficientFunds) {
+			t.Errorf("DecreasePlayerGold = %v, want %v", err, player.ErrInsufficientFunds)
+		}
+
+		got := getPlayer(t, repo, created.ID)
+		if got.Gold != created.Gold+10 {
+			t.Errorf("gold = %v, want %v", got.Gold, created.Gold+10)
+		}
+		if got.Version != created.Version+1 {
+			t.Errorf("version = %v, want %v", got.Version, created.Version+1)
+		}
+	})
+
+	t.Run("VersionMismatch", func(t *testing.T) {
+		repo := newRepos(t).Players
+		created := createPlayer(t, repo)
+
+		stale := created.Version - 1
+		level := int32(5)
+		_, err := repo.UpdatePlayer(t.Context(), player.UpdatePlayerParams{ID: created.ID, Level: &level, Version: &stale})
+		if !errors.Is(err, player.ErrVersionMismatch) {
+			t.Errorf("UpdatePlayer at a stale version = %v, want ErrVersionMismatch", err)
+		}
+
+		err = repo.DeletePlayerByID(t.Context(), player.DeletePlayerParams{ID: created.ID, Version: &stale})
+		if !errors.Is(err, player.ErrVersionMismatch) {
+			t.Errorf("DeletePlayerByID at a stale version = %v, want ErrVersionMismatch", err)
+		}
+
+		updated, err := repo.UpdatePlayer(t.Context(), player.UpdatePlayerParams{ID: created.ID, Level: &level, Version: &created.Version})
+		if err != nil {
+			t.Fatalf("UpdatePlayer at the current version: %v", err)
+		}
+		if updated.Level != level || updated.Version != created.Version+1 {
+			t.Errorf("UpdatePlayer = %+v, want level %v at version %v", updated, level, created.Version+1)
+		}
+	})
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		repo := newRepos(t).Players
+		created := createPlayer(t, repo)
+
+		if err := repo.DeletePlayerByID(t.Context(), player.DeletePlayerParams{ID: created.ID}); err != nil {
+			t.Fatalf("DeletePlayerByID: %v", err)
+		}
+		if p, err := repo.GetPlayerByID(t.Context(), created.ID); p != nil || err != nil {
+			t.Errorf("GetPlayerByID after delete = %v, %v; want nil, nil", p, err)
+		}
+
+		restored, err := repo.RestorePlayer(t.Context(), player.RestorePlayerParams{ID: created.ID, GracePeriod: time.Hour})
+		if err != nil {
+			t.Fatalf("RestorePlayer: %v", err)
+		}
+		if restored == nil || restored.ID != created.ID {
+			t.Fatalf("RestorePlayer = %+v, want player %v", restored, created.ID)
+		}
+		getPlayer(t, repo, created.ID)
+
+		if err := repo.DeletePlayerByID(t.Context(), player.DeletePlayerParams{ID: created.ID}); err != nil {
+			t.Fatalf("DeletePlayerByID: %v", err)
+		}
+		_, err = repo.RestorePlayer(t.Context(), player.RestorePlayerParams{ID: created.ID, GracePeriod: 0})
+		if !errors.Is(err, player.ErrRestoreExpired) {
+			t.Errorf("RestorePlayer after the grace period = %v, want ErrRestoreExpired", err)
+		}
+	})
+
+	t.Run("ConcurrentGold", func(t *testing.T) {
+		repo := newRepos(t).Players
+		created := createPlayer(t, repo)
+
+		errs := parallel(concurrency, func(int) error {
+			return repo.IncreasePlayerGold(t.Context(), player.UpdatePlayerGoldParams{ID: created.ID, Amount: 1})
+		})
+		for _, err := range errs {
+			if err != nil {
+				t.Fatalf("IncreasePlayerGold: %v", err)
+			}
+		}
+
+		if got := getPlayer(t, repo, created.ID); got.Gold != concurrency {
+			t.Errorf("gold after %v concurrent increases = %v", concurrency, got.Gold)
+		}
+	})
+
+	t.Run("ConcurrentConditionalUpdates", func(t *testing.T) {
+		repo := newRepos(t).Players
+		created := createPlayer(t, repo)
+
+		errs := parallel(concurrency, func(i int) error {
+			level := int32(i + 2)
+			_, err := repo.UpdatePlayer(t.Context(), player.UpdatePlayerParams{ID: created.ID, Level: &level, Version: &created.Version})
+			return err
+		})
+
+		var won int
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				won++
+			case !errors.Is(err, player.ErrVersionMismatch):
+				t.Errorf("UpdatePlayer: %v", err)
+			}
+		}
+		if won != 1 {
+			t.Errorf("%v of %v updates at the same version succeeded, want 1", won, concurrency)
+		}
+	})
+
+	t.Run("ConcurrentCreateSameUsername", func(t *testing.T) {
+		repo := newRepos(t).Players
+		username := randomName("Racer")
+
+		errs := parallel(concurrency, func(int) error {
+			_, err := repo.CreatePlayer(t.Context(), player.CreatePlayerParams{
+				Username:    username,
+				Class:       "Rogue",
+				UsernameKey: player.UsernameKey(username),
+			})
+			return err
+		})
+
+		var won int
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				won++
+			case !errors.Is(err, player.ErrUsernameInUse):
+				t.Errorf("CreatePlayer: %v", err)
+			}
+		}
+		if won != 1 {
+			t.Errorf("%v of %v players with the same username were created, want 1", won, concurrency)
+		}
+	})
+}
+
+// GoldCap checks that gold set on a player, whether credited or updated, is
+// clamped to the cap of the gold currency. setCap sets that cap in the store
+// behind repos. It is not part of Run, since it changes a currency every
+// other test relies on.
+func GoldCap(t *testing.T, repos Repositories, setCap func(cap int32)) {
+	const limit = 500
+	setCap(limit)
+
+	created := createPlayer(t, repos.Players)
+
+	if err := repos.Players.IncreasePlayerGold(t.Context(), player.UpdatePlayerGoldParams{ID: created.ID, Amount: limit + 1}); err != nil {
+		t.Fatalf("IncreasePlayerGold: %v", err)
+	}
+	if got := getPlayer(t, repos.Players, created.ID); got.Gold != limit {
+		t.Errorf("gold after a credit past the cap = %v, want %v", got.Gold, limit)
+	}
+
+	gold := int32(limit * 2)
+	updated, err := repos.Players.UpdatePlayer(t.Context(), player.UpdatePlayerParams{ID: created.ID, Gold: &gold})
+	if err != nil {
+		t.Fatalf("UpdatePlayer: %v", err)
+	}
+	if updated.Gold != limit {
+		t.Errorf("gold after an update past the cap = %v, want %v", updated.Gold, limit)
+	}
+
+	gold = limit - 1
+	updated, err = repos.Players.UpdatePlayer(t.Context(), player.UpdatePlayerParams{ID: created.ID, Gold: &gold})
+	if err != nil {
+		t.Fatalf("UpdatePlayer: %v", err)
+	}
+	if updated.Gold != gold {
+		t.Errorf("gold after an update below the cap = %v, want %v", updated.Gold, gold)
+	}
+}
+
+func createPlayer(t *testing.T, repo player.PlayerRepository) *player.Player {
+	t.Helper()
+
+	username := randomName("Player")
+	p, err := repo.CreatePlayer(t.Context(), player.CreatePlayerParams{
+		Username:    username,
+		Class:       "Warrior",
+		UsernameKey: player.UsernameKey(username),
+	})
+	if err != nil {
+		t.Fatalf("CreatePlayer: %v", err)
+	}
+
+	return p
+}
+
+func getPlayer(t *testing.T, repo player.PlayerRepository, id int32) *player.Player {
+	t.Helper()
+
+	p, err := repo.GetPlayerByID(t.Context(), id)
+	if err != nil {
+		t.Fatalf("GetPlayerByID: %v", err)
+	}
+	if p == nil {
+		t.Fatalf("player %v not found", id)
+	}
+
+	return p
+}
@@ -0,0 +1,91 @@
+// Package repotest is a conformance suite for the player, item and inventory
+// repositories. Every implementation must pass it, so that the services built
+// on top behave the same whatever the storage backend. An implementation runs
+// it from its own tests, as the storage package does for every backend:
+//
+//	func TestMemoryRepositories(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) repotest.Repositories {
+//			return repotest.FromStorage(storage.NewMemoryRepositories(memdb.New()))
+//		})
+//	}
+//
+// The suite creates its own players and items under random names and never
+// relies on the store being empty, so it can run against a database that
+// holds other data, as long as nothing else writes to it meanwhile.
+//
+// QueryCancelledInFlight is the counterpart for the database packages: it
+// checks that a handle gives up on a running query when its context is done.
+package repotest
+
+import (
+	"math/rand/v2"
+	"sync"
+	"testing"
+
+	"github.com/hossokawa/go-nethttp-example/internal/inventory"
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+	"github.com/hossokawa/go-nethttp-example/internal/storage"
+)
+
+// Repositories are the implementations under test. They must share one
+// store, since inventories refer to players and items.
+type Repositories struct {
+	Players   player.PlayerRepository
+	Items     item.ItemRepository
+	Inventory inventory.InventoryRepository
+}
+
+// Factory returns the repositories to run a test against. It is called once
+// per test.
+type Factory func(t *testing.T) Repositories
+
+// FromStorage picks the repositories the suite covers out of repos.
+func FromStorage(repos *storage.Repositories) Repositories {
+	return Repositories{Players: repos.Players, Items: repos.Items, Inventory: repos.Inventory}
+}
+
+// Run runs the whole suite.
+func Run(t *testing.T, newRepos Factory) {
+	t.Run("Player", func(t *testing.T) { PlayerRepository(t, newRepos) })
+	t.Run("Item", func(t *testing.T) { ItemRepository(t, newRepos) })
+	t.Run("Inventory", func(t *testing.T) { InventoryRepository(t, newRepos) })
+	t.Run("Cancellation", func(t *testing.T) { Cancellation(t, newRepos) })
+}
+
+// concurrency is how many goroutines the concurrency tests start at once.
+const concurrency = 20
+
+// nameLetters leaves out the letters UsernameKey maps onto one another, so
+// that two random names never collide on their key.
+const nameLetters = "abcdefghjkpqstuxyz"
+
+// randomName returns prefix followed by random letters.
+func randomName(prefix string) string {
+	b := []byte(prefix)
+	for range 12 {
+		b = append(b, nameLetters[rand.IntN(len(nameLetters))])
+	}
+	return string(b)
+}
+
+// parallel runs fn concurrently n times and returns the errors it returned,
+// in no particular order.
+func parallel(n int, fn func(i int) error) []error {
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	start := make(chan struct{})
+
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs[i] = fn(i)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	return errs
+}
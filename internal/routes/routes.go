@@ -2,19 +2,207 @@ package routes
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/hossokawa/go-nethttp-example/internal/account"
+	"github.com/hossokawa/go-nethttp-example/internal/api"
+	"github.com/hossokawa/go-nethttp-example/internal/bank"
+	"github.com/hossokawa/go-nethttp-example/internal/currency"
+	"github.com/hossokawa/go-nethttp-example/internal/effect"
 	"github.com/hossokawa/go-nethttp-example/internal/handler"
+	"github.com/hossokawa/go-nethttp-example/internal/health"
+	"github.com/hossokawa/go-nethttp-example/internal/idempotency"
+	"github.com/hossokawa/go-nethttp-example/internal/inventory"
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/lifecycle"
+	"github.com/hossokawa/go-nethttp-example/internal/metrics"
 	"github.com/hossokawa/go-nethttp-example/internal/player"
-	"github.com/jackc/pgx/v5"
+	"github.com/hossokawa/go-nethttp-example/internal/pvp"
+	"github.com/hossokawa/go-nethttp-example/internal/shop"
+	"github.com/hossokawa/go-nethttp-example/internal/storage"
+	"github.com/hossokawa/go-nethttp-example/internal/zone"
 )
 
-func SetupRoutes(router *http.ServeMux, db *pgx.Conn) {
-	playerRepo := player.NewPostgresRepository(db)
-	playerService := player.NewPlayerService(playerRepo)
-	playerHandler := handler.NewPlayerHandler(playerService)
+const (
+	effectSweepInterval = time.Minute
+	matchmakingInterval = 2 * time.Second
+	idempotencyKeyTTL   = 24 * time.Hour
+	idempotencySweep    = time.Hour
+	purgeInterval       = time.Hour
+	gameSampleInterval  = 30 * time.Second
+
+	// Purging a player removes everything they own, and purchases wait on
+	// the payment verifier, so both may take longer than other requests.
+	purgeRequestTimeout    = 30 * time.Second
+	purchaseRequestTimeout = 15 * time.Second
+
+	// idempotencyLeaseMargin is how much longer than the longest request
+	// deadline a request holds its idempotency key for.
+	idempotencyLeaseMargin = time.Minute
+)
+
+// Config holds the settings of the services wired by SetupRoutes.
+type Config struct {
+	UsernamePolicy player.UsernamePolicy
+	StartingGold   int32
+	// RequestTimeout is the deadline of requests to routes that do not set
+	// their own.
+	RequestTimeout time.Duration
+	// RestoreGracePeriod is how long deleted players and items can be
+	// restored before they are purged.
+	RestoreGracePeriod time.Duration
+	// Health reports on the storage repos are backed by.
+	Health *health.Service
+	// Metrics records the requests served and is exported on /metrics.
+	Metrics *metrics.Metrics
+	// PaymentVerifier verifies purchase receipts. Without one, the shop
+	// routes are left out.
+	PaymentVerifier shop.PaymentVerifier
+}
+
+// SetupRoutes registers every route on router and returns the handler to
+// serve, with middleware applied. Background workers needed by the routes are
+// appended to lc, to be started and stopped with the application. Routes of
+// domains the storage backend does not keep are left out.
+func SetupRoutes(lc *lifecycle.Lifecycle, router *http.ServeMux, repos *storage.Repositories, cfg Config) http.Handler {
+	playerService := player.NewPlayerService(repos.Players, cfg.UsernamePolicy, cfg.RestoreGracePeriod, cfg.StartingGold)
+	lc.Append(lifecycle.Worker("player purger", player.NewPurger(playerService, purgeInterval).Run))
+
+	itemService := item.NewItemService(repos.Items, cfg.RestoreGracePeriod)
+	lc.Append(lifecycle.Worker("item purger", item.NewPurger(itemService, purgeInterval).Run))
+
+	inventoryService := inventory.NewInventoryService(repos.Inventory)
+
+	var effectService *effect.EffectService
+	if repos.Effects != nil {
+		effectService = effect.NewEffectService(repos.Effects)
+		lc.Append(lifecycle.Worker("effect sweeper", effect.NewSweeper(effectService, effectSweepInterval).Run))
+	}
+
+	healthHandler := handler.NewHealthHandler(cfg.Health)
+
+	router.HandleFunc("GET /healthz", healthHandler.Healthz)
+	router.HandleFunc("GET /readyz", healthHandler.Readyz)
+	router.HandleFunc("GET /version", healthHandler.Version)
+
+	router.Handle("GET /metrics", cfg.Metrics.Handler())
+	lc.Append(lifecycle.Worker("game metrics sampler", metrics.NewGameSampler(cfg.Metrics, playerService, itemService, gameSampleInterval).Run))
+
+	playerHandler := handler.NewPlayerHandler(playerService, inventoryService, effectService)
+	itemHandler := handler.NewItemHandler(itemService)
+	inventoryHandler := handler.NewInventoryHandler(inventoryService, playerService)
 
 	router.HandleFunc("POST /player", playerHandler.CreatePlayer)
-	router.HandleFunc("GET /player", playerHandler.GetAllPlayers)
+	router.HandleFunc("GET /player", playerHandler.ListPlayers)
 	router.HandleFunc("GET /player/{id}", playerHandler.GetPlayerByID)
+	router.HandleFunc("PATCH /player/{id}", playerHandler.PatchPlayer)
 	router.HandleFunc("DELETE /player/{id}", playerHandler.DeletePlayerByID)
+	router.HandleFunc("POST /player/{id}/restore", playerHandler.RestorePlayer)
+	router.Handle("POST /player/{id}/purge", api.WithTimeout(purgeRequestTimeout, playerHandler.PurgePlayer))
+	router.HandleFunc("POST /player/{id}/rename", playerHandler.RenamePlayer)
+	router.HandleFunc("GET /player/{id}/name-history", playerHandler.ListNameHistory)
+
+	router.HandleFunc("GET /player/{id}/inventory", inventoryHandler.ListInventory)
+	router.HandleFunc("POST /player/{id}/inventory/{itemID}/equip", inventoryHandler.EquipItem)
+	router.HandleFunc("DELETE /player/{id}/inventory/{itemID}/equip", inventoryHandler.UnequipItem)
+
+	router.HandleFunc("GET /item", itemHandler.ListItems)
+	router.HandleFunc("GET /item/{id}", itemHandler.GetItemByID)
+	router.HandleFunc("PATCH /item/{id}", itemHandler.PatchItem)
+	router.HandleFunc("DELETE /item/{id}", itemHandler.DeleteItemByID)
+	router.HandleFunc("POST /item/{id}/restore", itemHandler.RestoreItem)
+	router.HandleFunc("PUT /item/{id}/prices/{currency}", itemHandler.SetItemPrice)
+	router.HandleFunc("DELETE /item/{id}/prices/{currency}", itemHandler.RemoveItemPrice)
+
+	if effectService != nil {
+		effectHandler := handler.NewEffectHandler(effectService, playerService)
+
+		router.HandleFunc("GET /player/{id}/effects", effectHandler.ListPlayerEffects)
+		router.HandleFunc("POST /player/{id}/effects", effectHandler.ApplyEffect)
+		router.HandleFunc("POST /player/{id}/effects/{effectID}/refresh", effectHandler.RefreshEffect)
+		router.HandleFunc("DELETE /player/{id}/effects/{effectID}", effectHandler.RemoveEffect)
+	}
+
+	if repos.Accounts != nil {
+		accountService := account.NewAccountService(repos.Accounts, playerService)
+		accountHandler := handler.NewAccountHandler(accountService)
+
+		router.HandleFunc("PUT /player/{id}/account", accountHandler.SetPlayerAccount)
+		router.HandleFunc("POST /account", accountHandler.CreateAccount)
+		router.HandleFunc("GET /account/{id}", accountHandler.GetAccountByID)
+	}
+
+	if repos.Banks != nil {
+		bankService := bank.NewBankService(repos.Banks, playerService)
+		bankHandler := handler.NewBankHandler(bankService)
+
+		router.HandleFunc("GET /player/{id}/bank", bankHandler.GetStorage(bank.KindBank))
+		router.HandleFunc("POST /player/{id}/bank/items", bankHandler.DepositItem(bank.KindBank))
+		router.HandleFunc("DELETE /player/{id}/bank/items/{itemID}", bankHandler.WithdrawItem(bank.KindBank))
+		router.HandleFunc("POST /player/{id}/bank/gold/deposit", bankHandler.DepositGold(bank.KindBank))
+		router.HandleFunc("POST /player/{id}/bank/gold/withdraw", bankHandler.WithdrawGold(bank.KindBank))
+
+		router.HandleFunc("GET /player/{id}/shared-storage", bankHandler.GetStorage(bank.KindShared))
+		router.HandleFunc("POST /player/{id}/shared-storage/items", bankHandler.DepositItem(bank.KindShared))
+		router.HandleFunc("DELETE /player/{id}/shared-storage/items/{itemID}", bankHandler.WithdrawItem(bank.KindShared))
+		router.HandleFunc("POST /player/{id}/shared-storage/gold/deposit", bankHandler.DepositGold(bank.KindShared))
+		router.HandleFunc("POST /player/{id}/shared-storage/gold/withdraw", bankHandler.WithdrawGold(bank.KindShared))
+	}
+
+	if repos.Currencies != nil {
+		currencyService := currency.NewCurrencyService(repos.Currencies, playerService)
+		currencyHandler := handler.NewCurrencyHandler(currencyService)
+
+		router.HandleFunc("GET /player/{id}/currencies", currencyHandler.ListPlayerBalances)
+		router.HandleFunc("POST /player/{id}/currencies/{currency}/increase", currencyHandler.IncreaseBalance)
+		router.HandleFunc("POST /player/{id}/currencies/{currency}/decrease", currencyHandler.DecreaseBalance)
+		router.HandleFunc("GET /currency", currencyHandler.GetAllCurrencies)
+
+		if repos.Shop != nil && cfg.PaymentVerifier != nil {
+			shopService := shop.NewShopService(repos.Shop, repos.Tx, cfg.PaymentVerifier, playerService, currencyService, inventoryService)
+			shopHandler := handler.NewShopHandler(shopService)
+
+			router.HandleFunc("GET /shop/bundles", shopHandler.GetAllBundles)
+			router.Handle("POST /shop/purchases", api.WithTimeout(purchaseRequestTimeout, shopHandler.Purchase))
+			router.HandleFunc("GET /shop/purchases/{transactionID}", shopHandler.GetReceipt)
+			router.HandleFunc("POST /shop/purchases/{transactionID}/refund", shopHandler.Refund)
+		}
+	}
+
+	if repos.Zones != nil {
+		zoneService := zone.NewZoneService(repos.Zones, playerService)
+		zoneHandler := handler.NewZoneHandler(zoneService)
+
+		router.HandleFunc("GET /player/{id}/location", zoneHandler.GetPlayerLocation)
+		router.HandleFunc("PUT /player/{id}/location", zoneHandler.UpdatePlayerLocation)
+
+		router.HandleFunc("GET /zone", zoneHandler.GetAllZones)
+		router.HandleFunc("GET /zone/{id}", zoneHandler.GetZoneByID)
+		router.HandleFunc("GET /zone/{id}/players", zoneHandler.ListPlayersInZone)
+	}
+
+	if repos.PvP != nil {
+		pvpService := pvp.NewPvPService(repos.PvP, repos.Tx, playerService, pvp.NewQueue(pvp.DefaultMatchRules))
+		lc.Append(lifecycle.Worker("matchmaker", pvp.NewMatchmaker(pvpService, matchmakingInterval).Run))
+		pvpHandler := handler.NewPvPHandler(pvpService)
+
+		router.HandleFunc("GET /player/{id}/pvp", pvpHandler.GetRating)
+		router.HandleFunc("GET /player/{id}/pvp/matches", pvpHandler.ListPlayerMatches)
+
+		router.HandleFunc("POST /pvp/queue", pvpHandler.JoinQueue)
+		router.HandleFunc("GET /pvp/queue/{playerID}", pvpHandler.GetQueueEntry)
+		router.HandleFunc("DELETE /pvp/queue/{playerID}", pvpHandler.LeaveQueue)
+		router.HandleFunc("GET /pvp/matches/{id}", pvpHandler.GetMatchByID)
+		router.HandleFunc("POST /pvp/matches/{id}/result", pvpHandler.ReportResult)
+	}
+
+	var handler http.Handler = router
+	if repos.Idempotency != nil {
+		lc.Append(lifecycle.Worker("idempotency sweeper", idempotency.NewSweeper(repos.Idempotency, idempotencySweep).Run))
+		lease := max(cfg.RequestTimeout, purgeRequestTimeout, purchaseRequestTimeout) + idempotencyLeaseMargin
+		handler = idempotency.NewMiddleware(repos.Idempotency, idempotencyKeyTTL, lease).Wrap(handler)
+	}
+	handler = api.NewDeadline(router, cfg.RequestTimeout).Wrap(handler)
+
+	return cfg.Metrics.Wrap(router, handler)
 }
@@ -0,0 +1,201 @@
+package shop
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+)
+
+type memRepository struct {
+	db *memdb.DB
+}
+
+// NewMemoryRepository returns a ShopRepository that keeps bundles and
+// receipts in db. It behaves like the Postgres repository, down to the errors
+// it returns.
+func NewMemoryRepository(db *memdb.DB) ShopRepository {
+	return &memRepository{db: db}
+}
+
+func toBundle(row memdb.Bundle) *Bundle {
+	b := &Bundle{
+		ID:         row.ID,
+		Name:       row.Name,
+		PriceCents: row.PriceCents,
+		Active:     row.Active,
+		Currencies: make([]CurrencyAmount, 0, len(row.Currencies)),
+		ItemIDs:    slices.Clone(row.ItemIDs),
+	}
+	for code, amount := range row.Currencies {
+		b.Currencies = append(b.Currencies, CurrencyAmount{Currency: code, Amount: amount})
+	}
+	slices.SortFunc(b.Currencies, func(x, y CurrencyAmount) int { return cmp.Compare(x.Currency, y.Currency) })
+	slices.SortFunc(b.ItemIDs, func(x, y uuid.UUID) int { return strings.Compare(x.String(), y.String()) })
+	if b.ItemIDs == nil {
+		b.ItemIDs = []uuid.UUID{}
+	}
+
+	return b
+}
+
+func (r *memRepository) GetAllBundles(ctx context.Context) ([]*Bundle, error) {
+	var bundles []*Bundle
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Bundles {
+			if row.Active {
+				bundles = append(bundles, toBundle(row))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(bundles, func(a, b *Bundle) int {
+		if c := cmp.Compare(a.PriceCents, b.PriceCents); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return bundles, nil
+}
+
+func (r *memRepository) GetBundleByID(ctx context.Context, id string) (*Bundle, error) {
+	var b *Bundle
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		if row, ok := t.Bundles[id]; ok {
+			b = toBundle(row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func receipt(t *memdb.Tables, transactionID string) *Receipt {
+	row, ok := t.Receipts[transactionID]
+	if !ok {
+		return nil
+	}
+
+	rc := &Receipt{
+		TransactionID: row.TransactionID,
+		PlayerID:      row.PlayerID,
+		BundleID:      row.BundleID,
+		Status:        row.Status,
+		Grants:        []Grant{},
+		CreatedAt:     row.CreatedAt,
+		GrantedAt:     row.GrantedAt,
+		RefundedAt:    row.RefundedAt,
+	}
+	for _, g := range t.Grants {
+		if g.TransactionID == transactionID {
+			rc.Grants = append(rc.Grants, Grant{Currency: g.Currency, ItemID: g.ItemID, Amount: g.Amount})
+		}
+	}
+
+	return rc
+}
+
+func (r *memRepository) GetReceipt(ctx context.Context, transactionID string) (*Receipt, error) {
+	var rc *Receipt
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		rc = receipt(t, transactionID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rc, nil
+}
+
+func (r *memRepository) CreateReceipt(ctx context.Context, args CreateReceiptParams) (*Receipt, bool, error) {
+	var rc *Receipt
+	var created bool
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		if _, ok := t.Receipts[args.TransactionID]; !ok {
+			if _, ok := t.Bundles[args.BundleID]; !ok {
+				return fmt.Errorf("inserting receipt: %w", memdb.ErrForeignKey)
+			}
+
+			t.Receipts[args.TransactionID] = memdb.Receipt{
+				TransactionID: args.TransactionID,
+				PlayerID:      args.PlayerID,
+				BundleID:      args.BundleID,
+				Status:        ReceiptPending,
+				CreatedAt:     time.Now(),
+			}
+			created = true
+		}
+
+		rc = receipt(t, args.TransactionID)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return rc, created, nil
+}
+
+func (r *memRepository) AddGrant(ctx context.Context, args AddGrantParams) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		if _, ok := t.Receipts[args.TransactionID]; !ok {
+			return fmt.Errorf("recording receipt grant: %w", memdb.ErrForeignKey)
+		}
+
+		t.Grants = append(t.Grants, memdb.Grant{
+			ID:            t.NextID("purchase_receipt_grant"),
+			TransactionID: args.TransactionID,
+			Currency:      args.Grant.Currency,
+			ItemID:        args.Grant.ItemID,
+			Amount:        args.Grant.Amount,
+		})
+
+		return nil
+	})
+}
+
+func (r *memRepository) MarkReceiptGranted(ctx context.Context, transactionID string) error {
+	return r.transition(ctx, transactionID, ReceiptPending, func(row *memdb.Receipt, now time.Time) {
+		row.Status = ReceiptGranted
+		row.GrantedAt = &now
+	})
+}
+
+func (r *memRepository) MarkReceiptRefunded(ctx context.Context, transactionID string) error {
+	return r.transition(ctx, transactionID, ReceiptGranted, func(row *memdb.Receipt, now time.Time) {
+		row.Status = ReceiptRefunded
+		row.RefundedAt = &now
+	})
+}
+
+func (r *memRepository) transition(ctx context.Context, transactionID, from string, fn func(row *memdb.Receipt, now time.Time)) error {
+	return r.db.Write(ctx, func(t *memdb.Tables) error {
+		row, ok := t.Receipts[transactionID]
+		if !ok || row.Status != from {
+			return ErrReceiptStatus
+		}
+
+		fn(&row, time.Now())
+		t.Receipts[transactionID] = row
+
+		return nil
+	})
+}
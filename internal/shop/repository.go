@@ -0,0 +1,298 @@
+package shop
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrReceiptStatus = apperr.Conflict("receipt is not in the expected status")
+
+type ShopRepository interface {
+	GetAllBundles(ctx context.Context) ([]*Bundle, error)
+	GetBundleByID(ctx context.Context, id string) (*Bundle, error)
+	GetReceipt(ctx context.Context, transactionID string) (*Receipt, error)
+	CreateReceipt(ctx context.Context, args CreateReceiptParams) (*Receipt, bool, error)
+	AddGrant(ctx context.Context, args AddGrantParams) error
+	MarkReceiptGranted(ctx context.Context, transactionID string) error
+	MarkReceiptRefunded(ctx context.Context, transactionID string) error
+}
+
+type pgRepository struct {
+	db *database.DB
+}
+
+func NewPostgresRepository(db *database.DB) ShopRepository {
+	return &pgRepository{db: db}
+}
+
+const getAllBundles = `
+SELECT id, name, price_cents, active FROM shop_bundle WHERE active ORDER BY price_cents, id
+`
+
+func (r *pgRepository) GetAllBundles(ctx context.Context) ([]*Bundle, error) {
+	ctx = querylabel.With(ctx, "shop", "GetAllBundles")
+
+	rows, err := r.db.Query(ctx, getAllBundles)
+	if err != nil {
+		return nil, fmt.Errorf("querying for all bundles: %w", err)
+	}
+
+	var bundles []*Bundle
+
+	for rows.Next() {
+		var b Bundle
+
+		if err := rows.Scan(&b.ID, &b.Name, &b.PriceCents, &b.Active); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning rows into bundle struct: %w", err)
+		}
+
+		bundles = append(bundles, &b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, b := range bundles {
+		if err := r.loadBundleContents(ctx, b); err != nil {
+			return nil, err
+		}
+	}
+
+	return bundles, nil
+}
+
+const getBundleByID = `
+SELECT id, name, price_cents, active FROM shop_bundle WHERE id = $1
+`
+
+func (r *pgRepository) GetBundleByID(ctx context.Context, id string) (*Bundle, error) {
+	ctx = querylabel.With(ctx, "shop", "GetBundleByID")
+
+	var b Bundle
+
+	err := r.db.QueryRow(ctx, getBundleByID, id).Scan(&b.ID, &b.Name, &b.PriceCents, &b.Active)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into bundle struct: %w", err)
+	}
+
+	if err := r.loadBundleContents(ctx, &b); err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+const listBundleCurrencies = `
+SELECT currency_code, amount FROM shop_bundle_currency WHERE bundle_id = $1 ORDER BY currency_code
+`
+
+const listBundleItems = `
+SELECT item_id FROM shop_bundle_item WHERE bundle_id = $1 ORDER BY item_id
+`
+
+func (r *pgRepository) loadBundleContents(ctx context.Context, b *Bundle) error {
+	rows, err := r.db.Query(ctx, listBundleCurrencies, b.ID)
+	if err != nil {
+		return fmt.Errorf("querying for bundle currencies: %w", err)
+	}
+
+	b.Currencies, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (CurrencyAmount, error) {
+		var c CurrencyAmount
+		err := row.Scan(&c.Currency, &c.Amount)
+		return c, err
+	})
+	if err != nil {
+		return fmt.Errorf("scanning rows into bundle currency struct: %w", err)
+	}
+
+	rows, err = r.db.Query(ctx, listBundleItems, b.ID)
+	if err != nil {
+		return fmt.Errorf("querying for bundle items: %w", err)
+	}
+
+	b.ItemIDs, err = pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return fmt.Errorf("scanning rows into bundle item ids: %w", err)
+	}
+
+	return nil
+}
+
+const getReceipt = `
+SELECT transaction_id, player_id, bundle_id, status, created_at, granted_at, refunded_at
+FROM purchase_receipt
+WHERE transaction_id = $1
+`
+
+const listReceiptGrants = `
+SELECT currency_code, item_id, amount FROM purchase_receipt_grant WHERE transaction_id = $1 ORDER BY id
+`
+
+func (r *pgRepository) GetReceipt(ctx context.Context, transactionID string) (*Receipt, error) {
+	ctx = querylabel.With(ctx, "shop", "GetReceipt")
+
+	var rc Receipt
+
+	row := r.db.QueryRow(ctx, getReceipt, transactionID)
+	err := row.Scan(
+		&rc.TransactionID,
+		&rc.PlayerID,
+		&rc.BundleID,
+		&rc.Status,
+		&rc.CreatedAt,
+		&rc.GrantedAt,
+		&rc.RefundedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into receipt struct: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, listReceiptGrants, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("querying for receipt grants: %w", err)
+	}
+
+	rc.Grants, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (Grant, error) {
+		var g Grant
+		err := row.Scan(&g.Currency, &g.ItemID, &g.Amount)
+		return g, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning rows into grant struct: %w", err)
+	}
+
+	return &rc, nil
+}
+
+const createReceipt = `
+INSERT INTO purchase_receipt (transaction_id, player_id, bundle_id, status, created_at)
+VALUES ($1, $2, $3, 'pending', now())
+ON CONFLICT (transaction_id) DO NOTHING
+`
+
+type CreateReceiptParams struct {
+	TransactionID string `json:"transaction_id"`
+	PlayerID      int32  `json:"player_id"`
+	BundleID      string `json:"bundle_id"`
+}
+
+// CreateReceipt stores a pending receipt. The returned bool is false when a
+// receipt with the same transaction id already existed, in which case the
+// existing receipt is returned unchanged.
+func (r *pgRepository) CreateReceipt(ctx context.Context, args CreateReceiptParams) (*Receipt, bool, error) {
+	ctx = querylabel.With(ctx, "shop", "CreateReceipt")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, createReceipt, args.TransactionID, args.PlayerID, args.BundleID)
+	if err != nil {
+		return nil, false, fmt.Errorf("inserting receipt: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	rc, err := r.GetReceipt(ctx, args.TransactionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return rc, tag.RowsAffected() == 1, nil
+}
+
+const addGrant = `
+INSERT INTO purchase_receipt_grant (transaction_id, currency_code, item_id, amount)
+VALUES ($1, $2, $3, $4)
+`
+
+type AddGrantParams struct {
+	TransactionID string `json:"transaction_id"`
+	Grant         Grant  `json:"grant"`
+}
+
+func (r *pgRepository) AddGrant(ctx context.Context, args AddGrantParams) error {
+	ctx = querylabel.With(ctx, "shop", "AddGrant")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, addGrant, args.TransactionID, args.Grant.Currency, args.Grant.ItemID, args.Grant.Amount)
+	if err != nil {
+		return fmt.Errorf("recording receipt grant: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
+
+const markReceiptGranted = `
+UPDATE purchase_receipt SET status = 'granted', granted_at = now()
+WHERE transaction_id = $1 AND status = 'pending'
+`
+
+func (r *pgRepository) MarkReceiptGranted(ctx context.Context, transactionID string) error {
+	ctx = querylabel.With(ctx, "shop", "MarkReceiptGranted")
+
+	return r.transition(ctx, markReceiptGranted, transactionID)
+}
+
+const markReceiptRefunded = `
+UPDATE purchase_receipt SET status = 'refunded', refunded_at = now()
+WHERE transaction_id = $1 AND status = 'granted'
+`
+
+// MarkReceiptRefunded moves a granted receipt to refunded. It fails with
+// ErrReceiptStatus if the receipt was not granted, which makes it safe to use
+// as the claim that only one refund request may win.
+func (r *pgRepository) MarkReceiptRefunded(ctx context.Context, transactionID string) error {
+	ctx = querylabel.With(ctx, "shop", "MarkReceiptRefunded")
+
+	return r.transition(ctx, markReceiptRefunded, transactionID)
+}
+
+func (r *pgRepository) transition(ctx context.Context, query, transactionID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, query, transactionID)
+	if err != nil {
+		return fmt.Errorf("updating receipt status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrReceiptStatus
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
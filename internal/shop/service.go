@@ -0,0 +1,280 @@
+package shop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/currency"
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+)
+
+// PlayerLookup is the part of the player service the shop depends on.
+type PlayerLookup interface {
+	GetPlayerByID(ctx context.Context, id int32) (*player.Player, error)
+}
+
+// Wallet is the part of the currency service the shop depends on.
+type Wallet interface {
+	ListPlayerBalances(ctx context.Context, playerID int32) ([]*currency.Balance, error)
+	IncreaseBalance(ctx context.Context, playerID int32, code string, amount int32) (int32, error)
+	DecreaseBalance(ctx context.Context, playerID int32, code string, amount int32) (int32, error)
+}
+
+// Inventory is the part of the inventory service the shop depends on.
+type Inventory interface {
+	ListPlayerItems(ctx context.Context, playerID int32) ([]item.Item, error)
+	AddItem(ctx context.Context, playerID int32, itemID uuid.UUID) error
+	RemoveItem(ctx context.Context, playerID int32, itemID uuid.UUID) error
+}
+
+// Transactor runs fn in one database transaction, which the repositories
+// join through the context fn is given.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type ShopService struct {
+	repo      ShopRepository
+	tx        Transactor
+	verifier  PaymentVerifier
+	players   PlayerLookup
+	wallet    Wallet
+	inventory Inventory
+}
+
+func NewShopService(repo ShopRepository, tx Transactor, verifier PaymentVerifier, players PlayerLookup, wallet Wallet, inventory Inventory) *ShopService {
+	return &ShopService{repo: repo, tx: tx, verifier: verifier, players: players, wallet: wallet, inventory: inventory}
+}
+
+var (
+	ErrBundleUnavailable = apperr.Conflict("bundle is not available for purchase")
+	ErrReceiptMismatch   = apperr.Conflict("transaction id was already used for a different purchase")
+	ErrNotRefundable     = apperr.Conflict("only granted purchases can be refunded")
+)
+
+func (s *ShopService) GetAllBundles(ctx context.Context) ([]*Bundle, error) {
+	return s.repo.GetAllBundles(ctx)
+}
+
+func (s *ShopService) GetReceipt(ctx context.Context, transactionID string) (*Receipt, error) {
+	rc, err := s.repo.GetReceipt(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("getting receipt with transaction id '%v': %w", transactionID, err)
+	}
+	if rc == nil {
+		return nil, apperr.NotFound("receipt", "id", transactionID)
+	}
+
+	return rc, nil
+}
+
+type PurchaseRequest struct {
+	PlayerID      int32
+	BundleID      string
+	TransactionID string
+	Receipt       string
+}
+
+// Purchase grants the contents of a bundle exactly once per transaction id.
+// Submitting the same transaction again returns the stored receipt without
+// granting anything; the returned bool reports whether this call granted.
+func (s *ShopService) Purchase(ctx context.Context, req PurchaseRequest) (*Receipt, bool, error) {
+	existing, err := s.repo.GetReceipt(ctx, req.TransactionID)
+	if err != nil {
+		return nil, false, fmt.Errorf("getting receipt with transaction id '%v': %w", req.TransactionID, err)
+	}
+	if existing != nil {
+		if existing.PlayerID != req.PlayerID || existing.BundleID != req.BundleID {
+			return nil, false, ErrReceiptMismatch
+		}
+		return existing, false, nil
+	}
+
+	if _, err := s.players.GetPlayerByID(ctx, req.PlayerID); err != nil {
+		return nil, false, err
+	}
+
+	bundle, err := s.repo.GetBundleByID(ctx, req.BundleID)
+	if err != nil {
+		return nil, false, fmt.Errorf("getting bundle with id '%v': %w", req.BundleID, err)
+	}
+	if bundle == nil {
+		return nil, false, apperr.NotFound("bundle", "id", req.BundleID)
+	}
+	if !bundle.Active {
+		return nil, false, ErrBundleUnavailable
+	}
+
+	if err := s.verifier.Verify(ctx, req.TransactionID, req.Receipt, bundle); err != nil {
+		return nil, false, err
+	}
+
+	// The receipt, the grants and the player's new balances and items are
+	// written in one transaction, so a failure part way leaves nothing behind.
+	var rc *Receipt
+	var created bool
+
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		rc, created, err = s.repo.CreateReceipt(ctx, CreateReceiptParams{
+			TransactionID: req.TransactionID,
+			PlayerID:      req.PlayerID,
+			BundleID:      req.BundleID,
+		})
+		if err != nil {
+			return fmt.Errorf("creating receipt with transaction id '%v': %w", req.TransactionID, err)
+		}
+		if !created {
+			// A concurrent request with the same transaction id won the
+			// insert and is responsible for granting.
+			if rc.PlayerID != req.PlayerID || rc.BundleID != req.BundleID {
+				return ErrReceiptMismatch
+			}
+			return nil
+		}
+
+		if err := s.grant(ctx, rc, bundle); err != nil {
+			return err
+		}
+
+		if err := s.repo.MarkReceiptGranted(ctx, rc.TransactionID); err != nil {
+			return fmt.Errorf("marking receipt with transaction id '%v' as granted: %w", rc.TransactionID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if !created {
+		return rc, false, nil
+	}
+
+	rc, err = s.GetReceipt(ctx, rc.TransactionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return rc, true, nil
+}
+
+func (s *ShopService) grant(ctx context.Context, rc *Receipt, bundle *Bundle) error {
+	for _, c := range bundle.Currencies {
+		if _, err := s.wallet.IncreaseBalance(ctx, rc.PlayerID, c.Currency, c.Amount); err != nil {
+			return fmt.Errorf("granting %v %v: %w", c.Amount, c.Currency, err)
+		}
+
+		if err := s.repo.AddGrant(ctx, AddGrantParams{
+			TransactionID: rc.TransactionID,
+			Grant:         Grant{Currency: &c.Currency, Amount: c.Amount},
+		}); err != nil {
+			return err
+		}
+	}
+
+	owned, err := s.inventory.ListPlayerItems(ctx, rc.PlayerID)
+	if err != nil {
+		return err
+	}
+	has := make(map[uuid.UUID]bool, len(owned))
+	for _, i := range owned {
+		has[i.ID] = true
+	}
+
+	for _, id := range bundle.ItemIDs {
+		if has[id] {
+			continue
+		}
+
+		if err := s.inventory.AddItem(ctx, rc.PlayerID, id); err != nil {
+			return fmt.Errorf("granting item with id %v: %w", id, err)
+		}
+
+		if err := s.repo.AddGrant(ctx, AddGrantParams{
+			TransactionID: rc.TransactionID,
+			Grant:         Grant{ItemID: &id, Amount: 1},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Refund revokes everything a granted receipt gave the player. Currency that
+// was already spent is taken back only down to a zero balance. Refunding the
+// same receipt again returns it unchanged.
+func (s *ShopService) Refund(ctx context.Context, transactionID string) (*Receipt, error) {
+	rc, err := s.GetReceipt(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if rc.Status == ReceiptRefunded {
+		return rc, nil
+	}
+	if rc.Status != ReceiptGranted {
+		return nil, ErrNotRefundable
+	}
+	// Receipts outlive purged players; there is nobody left to revoke from.
+	if _, err := s.players.GetPlayerByID(ctx, rc.PlayerID); err != nil {
+		return nil, err
+	}
+
+	err = s.tx.InTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.MarkReceiptRefunded(ctx, transactionID); err != nil {
+			return err
+		}
+
+		for _, g := range rc.Grants {
+			if err := s.revoke(ctx, rc.PlayerID, g); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrReceiptStatus) {
+			return s.GetReceipt(ctx, transactionID)
+		}
+		return nil, fmt.Errorf("refunding receipt with transaction id '%v': %w", transactionID, err)
+	}
+
+	return s.GetReceipt(ctx, transactionID)
+}
+
+func (s *ShopService) revoke(ctx context.Context, playerID int32, g Grant) error {
+	if g.ItemID != nil {
+		if err := s.inventory.RemoveItem(ctx, playerID, *g.ItemID); err != nil {
+			return fmt.Errorf("revoking item with id %v: %w", *g.ItemID, err)
+		}
+		return nil
+	}
+
+	_, err := s.wallet.DecreaseBalance(ctx, playerID, *g.Currency, g.Amount)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, currency.ErrInsufficientFunds) {
+		return fmt.Errorf("revoking %v %v: %w", g.Amount, *g.Currency, err)
+	}
+
+	balances, err := s.wallet.ListPlayerBalances(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	for _, b := range balances {
+		if b.Currency != *g.Currency || b.Amount == 0 {
+			continue
+		}
+		if _, err := s.wallet.DecreaseBalance(ctx, playerID, b.Currency, min(b.Amount, g.Amount)); err != nil {
+			return fmt.Errorf("revoking remaining %v: %w", b.Currency, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package shop
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ReceiptPending  = "pending"
+	ReceiptGranted  = "granted"
+	ReceiptRefunded = "refunded"
+)
+
+type Bundle struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	PriceCents int32            `json:"price_cents"`
+	Active     bool             `json:"active"`
+	Currencies []CurrencyAmount `json:"currencies"`
+	ItemIDs    []uuid.UUID      `json:"item_ids"`
+}
+
+type CurrencyAmount struct {
+	Currency string `json:"currency"`
+	Amount   int32  `json:"amount"`
+}
+
+type Receipt struct {
+	TransactionID string     `json:"transaction_id"`
+	PlayerID      int32      `json:"player_id"`
+	BundleID      string     `json:"bundle_id"`
+	Status        string     `json:"status"`
+	Grants        []Grant    `json:"grants"`
+	CreatedAt     time.Time  `json:"created_at"`
+	GrantedAt     *time.Time `json:"granted_at"`
+	RefundedAt    *time.Time `json:"refunded_at"`
+}
+
+// Grant is something a receipt actually gave the player. Exactly one of
+// Currency and ItemID is set. Refunds revoke grants rather than the bundle
+// contents, so items the player already owned before buying are left alone.
+type Grant struct {
+	Currency *string    `json:"currency,omitempty"`
+	ItemID   *uuid.UUID `json:"item_id,omitempty"`
+	Amount   int32      `json:"amount"`
+}
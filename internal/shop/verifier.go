@@ -0,0 +1,90 @@
+package shop
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+)
+
+var ErrInvalidReceipt = apperr.New(apperr.KindPaymentRequired, "payment receipt could not be verified")
+
+// PaymentVerifier checks a purchase receipt with the payment provider before
+// any contents are granted.
+type PaymentVerifier interface {
+	Verify(ctx context.Context, transactionID, receipt string, bundle *Bundle) error
+}
+
+// HTTPVerifier verifies receipts with a payment provider's HTTP endpoint. It
+// posts the transaction, the receipt and the expected price, and takes a 200
+// as a valid receipt and a 4xx as an invalid one.
+type HTTPVerifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPVerifier(url string, client *http.Client) *HTTPVerifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPVerifier{url: url, client: client}
+}
+
+type verifyRequest struct {
+	TransactionID string `json:"transaction_id"`
+	Receipt       string `json:"receipt"`
+	BundleID      string `json:"bundle_id"`
+	PriceCents    int32  `json:"price_cents"`
+}
+
+func (v *HTTPVerifier) Verify(ctx context.Context, transactionID, receipt string, bundle *Bundle) error {
+	body, err := json.Marshal(verifyRequest{
+		TransactionID: transactionID,
+		Receipt:       receipt,
+		BundleID:      bundle.ID,
+		PriceCents:    bundle.PriceCents,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building receipt verification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("verifying receipt of transaction '%v': %w", transactionID, err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusOK:
+		return nil
+	case res.StatusCode >= 400 && res.StatusCode < 500:
+		return ErrInvalidReceipt
+	default:
+		return fmt.Errorf("verifying receipt of transaction '%v': payment provider responded %s", transactionID, res.Status)
+	}
+}
+
+// FakeVerifier is a local stand-in for a payment provider. It accepts a
+// receipt if it is "fake-receipt:" followed by the transaction id, so anyone
+// can forge one: it is only used in dev mode.
+type FakeVerifier struct{}
+
+func NewFakeVerifier() *FakeVerifier {
+	return &FakeVerifier{}
+}
+
+func (v *FakeVerifier) Verify(ctx context.Context, transactionID, receipt string, bundle *Bundle) error {
+	if receipt != "fake-receipt:"+transactionID {
+		return ErrInvalidReceipt
+	}
+	return nil
+}
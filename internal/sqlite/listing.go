@@ -0,0 +1,33 @@
+package sqlite
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/listing"
+)
+
+// Dialect writes list queries for SQLite. It has no casts, so cursor values
+// are converted to the representation their column has before being passed.
+var Dialect listing.Dialect = dialect{}
+
+type dialect struct{}
+
+func (dialect) Placeholder(n int) string {
+	return fmt.Sprintf("?%d", n)
+}
+
+func (dialect) CursorValue(q *listing.Query, v string, typ listing.Type) string {
+	switch typ {
+	case listing.Int:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return q.Arg(n)
+		}
+	case listing.Timestamp:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return q.Arg(Timestamp(t))
+		}
+	}
+	return q.Arg(v)
+}
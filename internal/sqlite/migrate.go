@@ -0,0 +1,65 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/hossokawa/go-nethttp-example/migrations"
+)
+
+// Version returns the number of the newest migration applied to the database.
+func (db *DB) Version(ctx context.Context) (int, error) {
+	var version int
+	if err := db.QueryRow(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	return version, nil
+}
+
+// migrate applies the embedded up migrations the database has not seen yet,
+// each in its own transaction. The number of the last one applied is kept in
+// the user_version pragma.
+func (db *DB) migrate(ctx context.Context) error {
+	current, err := db.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	files, err := fs.Glob(migrations.SQLite, "sqlite/*.up.sql")
+	if err != nil {
+		return fmt.Errorf("listing migrations: %w", err)
+	}
+
+	for _, file := range files {
+		name := path.Base(file)
+		version, err := strconv.Atoi(strings.SplitN(name, "_", 2)[0])
+		if err != nil {
+			return fmt.Errorf("invalid migration name '%v'", name)
+		}
+		if version <= current {
+			continue
+		}
+
+		stmts, err := fs.ReadFile(migrations.SQLite, file)
+		if err != nil {
+			return fmt.Errorf("reading migration %v: %w", name, err)
+		}
+
+		err = db.InTx(ctx, func(ctx context.Context) error {
+			if _, err := db.Exec(ctx, string(stmts)); err != nil {
+				return err
+			}
+			_, err := db.Exec(ctx, fmt.Sprintf("PRAGMA user_version = %d", version))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("applying migration %v: %w", name, err)
+		}
+	}
+
+	return nil
+}
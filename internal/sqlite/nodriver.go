@@ -0,0 +1,14 @@
+//go:build !sqlite
+
+package sqlite
+
+// Available reports whether the SQLite driver is built in.
+const Available = false
+
+const driverName = ""
+
+// IsUniqueViolation reports whether err is a unique or primary key violation.
+// Without the driver no query ever runs, so it never is.
+func IsUniqueViolation(err error) bool {
+	return false
+}
@@ -0,0 +1,221 @@
+// Package sqlite provides the SQLite database behind the embedded storage
+// backend, with the same unit of work as the Postgres one in package database.
+//
+// The SQLite driver needs cgo, so it is only built in with the sqlite build
+// tag; without it, the default build stays pure Go and Open fails.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Memory is the path of a private in-memory database.
+const Memory = ":memory:"
+
+// ErrUnavailable is returned by Open in builds without the sqlite tag.
+var ErrUnavailable = errors.New("SQLite support is not built in, rebuild with -tags sqlite")
+
+// DB runs queries on a database file, or on the transaction carried by the
+// context when called inside InTx.
+type DB struct {
+	db         *sql.DB
+	savepoints atomic.Int64
+	observer   QueryObserver
+}
+
+// QueryObserver is told how long each query took and whether it failed. It
+// runs on the goroutine that made the query.
+type QueryObserver func(ctx context.Context, elapsed time.Duration, err error)
+
+// Observe sets the observer of every query made from now on. It must be
+// called before the database is shared with other goroutines.
+func (db *DB) Observe(observer QueryObserver) {
+	db.observer = observer
+}
+
+func (db *DB) observe(ctx context.Context, start time.Time, err error) {
+	if db.observer != nil {
+		db.observer(ctx, time.Since(start), err)
+	}
+}
+
+// Open opens the database at path, creating it if needed, and brings its
+// schema up to date. Write transactions take the database lock when they
+// begin, so concurrent writers wait for each other instead of failing.
+func Open(ctx context.Context, path string) (*DB, error) {
+	if !Available {
+		return nil, ErrUnavailable
+	}
+
+	dsn := "file:" + path + "?_txlock=immediate&_busy_timeout=5000&_foreign_keys=on&_cslike=on&_journal_mode=WAL"
+
+	sqlDB, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	if path == Memory {
+		// Every connection to :memory: gets its own empty database.
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	db := &DB{db: sqlDB}
+
+	if err := db.migrate(ctx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func (db *DB) Close() {
+	db.db.Close()
+}
+
+func (db *DB) Ping(ctx context.Context) error {
+	return db.db.PingContext(ctx)
+}
+
+// Pool returns the underlying connection pool, for callers that need its
+// statistics.
+func (db *DB) Pool() *sql.DB {
+	return db.db
+}
+
+// Tx is a transaction, or a savepoint in the transaction it was begun in.
+type Tx struct {
+	db        *DB
+	tx        *sql.Tx
+	savepoint string
+	done      bool
+}
+
+func (tx *Tx) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	res, err := tx.tx.ExecContext(ctx, query, args...)
+	tx.db.observe(ctx, start, err)
+	return res, err
+}
+
+func (tx *Tx) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := tx.tx.QueryContext(ctx, query, args...)
+	tx.db.observe(ctx, start, err)
+	return rows, err
+}
+
+func (tx *Tx) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := tx.tx.QueryRowContext(ctx, query, args...)
+	tx.db.observe(ctx, start, row.Err())
+	return row
+}
+
+func (tx *Tx) Commit(ctx context.Context) error {
+	if tx.done {
+		return sql.ErrTxDone
+	}
+	tx.done = true
+
+	if tx.savepoint != "" {
+		_, err := tx.tx.ExecContext(ctx, "RELEASE "+tx.savepoint)
+		return err
+	}
+	return tx.tx.Commit()
+}
+
+// Rollback undoes the transaction. Like pgx, it does nothing once the
+// transaction is committed, so it can always be deferred.
+func (tx *Tx) Rollback(ctx context.Context) error {
+	if tx.done {
+		return nil
+	}
+	tx.done = true
+
+	if tx.savepoint != "" {
+		_, err := tx.tx.ExecContext(ctx, "ROLLBACK TO "+tx.savepoint+"; RELEASE "+tx.savepoint)
+		return err
+	}
+	return tx.tx.Rollback()
+}
+
+type txKey struct{}
+
+func txFrom(ctx context.Context) *Tx {
+	tx, _ := ctx.Value(txKey{}).(*Tx)
+	return tx
+}
+
+// Begin starts a transaction. Inside InTx it starts a savepoint in the
+// surrounding transaction instead.
+func (db *DB) Begin(ctx context.Context) (*Tx, error) {
+	if outer := txFrom(ctx); outer != nil {
+		name := fmt.Sprintf("sp%d", db.savepoints.Add(1))
+		if _, err := outer.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return nil, err
+		}
+		return &Tx{db: db, tx: outer.tx, savepoint: name}, nil
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{db: db, tx: tx}, nil
+}
+
+func (db *DB) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if tx := txFrom(ctx); tx != nil {
+		return tx.Exec(ctx, query, args...)
+	}
+	start := time.Now()
+	res, err := db.db.ExecContext(ctx, query, args...)
+	db.observe(ctx, start, err)
+	return res, err
+}
+
+func (db *DB) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if tx := txFrom(ctx); tx != nil {
+		return tx.Query(ctx, query, args...)
+	}
+	start := time.Now()
+	rows, err := db.db.QueryContext(ctx, query, args...)
+	db.observe(ctx, start, err)
+	return rows, err
+}
+
+func (db *DB) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	if tx := txFrom(ctx); tx != nil {
+		return tx.QueryRow(ctx, query, args...)
+	}
+	start := time.Now()
+	row := db.db.QueryRowContext(ctx, query, args...)
+	db.observe(ctx, start, row.Err())
+	return row
+}
+
+// InTx runs fn in a transaction that every DB call made with the context it
+// is given joins. The transaction commits if fn returns nil and rolls back
+// otherwise. Nested calls run in a savepoint of the outer transaction.
+func (db *DB) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hossokawa/go-nethttp-example/internal/repotest"
+	"github.com/hossokawa/go-nethttp-example/internal/sqlite"
+)
+
+// TestQueryCancelledInFlight runs against an in-memory database.
+func TestQueryCancelledInFlight(t *testing.T) {
+	if !sqlite.Available {
+		t.Skip("built without the sqlite tag")
+	}
+
+	db, err := sqlite.Open(t.Context(), sqlite.Memory)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	repotest.QueryCancelledInFlight(t, repotest.Backend{
+		Slow: func(ctx context.Context) error {
+			var n int64
+			return db.QueryRow(ctx, repotest.SlowSQLiteQuery).Scan(&n)
+		},
+		InUse: func() int { return db.Pool().Stats().InUse },
+		Ping:  db.Ping,
+	})
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TimeFormat is how timestamps are stored: in UTC and at a fixed width, so
+// that comparing them as text orders them in time.
+const TimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// Timestamp formats t for storage.
+func Timestamp(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// Time returns a scanner that reads a stored timestamp into t.
+func Time(t *time.Time) sql.Scanner {
+	return timeScanner{t: t}
+}
+
+// NullTime returns a scanner that reads a nullable stored timestamp into t,
+// setting it to nil for NULL.
+func NullTime(t **time.Time) sql.Scanner {
+	return nullTimeScanner{t: t}
+}
+
+type timeScanner struct {
+	t *time.Time
+}
+
+func (s timeScanner) Scan(src any) error {
+	var err error
+
+	switch v := src.(type) {
+	case time.Time:
+		*s.t = v.UTC()
+	case string:
+		*s.t, err = time.Parse(TimeFormat, v)
+	case []byte:
+		*s.t, err = time.Parse(TimeFormat, string(v))
+	default:
+		err = fmt.Errorf("cannot scan %T into a timestamp", src)
+	}
+
+	return err
+}
+
+type nullTimeScanner struct {
+	t **time.Time
+}
+
+func (s nullTimeScanner) Scan(src any) error {
+	if src == nil {
+		*s.t = nil
+		return nil
+	}
+
+	var t time.Time
+	if err := (timeScanner{t: &t}).Scan(src); err != nil {
+		return err
+	}
+	*s.t = &t
+
+	return nil
+}
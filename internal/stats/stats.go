@@ -0,0 +1,127 @@
+package stats
+
+const (
+	Strength  = "strength"
+	Agility   = "agility"
+	Intellect = "intellect"
+	Stamina   = "stamina"
+	Health    = "health"
+	Mana      = "mana"
+)
+
+const (
+	healthPerStamina = 10
+	manaPerIntellect = 10
+)
+
+type Stats struct {
+	Strength  int32 `json:"strength"`
+	Agility   int32 `json:"agility"`
+	Intellect int32 `json:"intellect"`
+	Stamina   int32 `json:"stamina"`
+	Health    int32 `json:"health"`
+	Mana      int32 `json:"mana"`
+}
+
+// Modifier is a flat bonus (or penalty, when Value is negative) to a single stat.
+type Modifier struct {
+	Stat  string `json:"stat"`
+	Value int32  `json:"value"`
+}
+
+type classGrowth struct {
+	base     Stats
+	perLevel Stats
+}
+
+var classes = map[string]classGrowth{
+	"Warrior": {
+		base:     Stats{Strength: 12, Agility: 8, Intellect: 4, Stamina: 12},
+		perLevel: Stats{Strength: 3, Agility: 1, Intellect: 0, Stamina: 3},
+	},
+	"Rogue": {
+		base:     Stats{Strength: 8, Agility: 12, Intellect: 5, Stamina: 9},
+		perLevel: Stats{Strength: 1, Agility: 3, Intellect: 1, Stamina: 2},
+	},
+	"Mage": {
+		base:     Stats{Strength: 4, Agility: 6, Intellect: 14, Stamina: 7},
+		perLevel: Stats{Strength: 0, Agility: 1, Intellect: 3, Stamina: 1},
+	},
+	"Sorcerer": {
+		base:     Stats{Strength: 4, Agility: 5, Intellect: 15, Stamina: 7},
+		perLevel: Stats{Strength: 0, Agility: 1, Intellect: 3, Stamina: 2},
+	},
+	"Druid": {
+		base:     Stats{Strength: 7, Agility: 7, Intellect: 11, Stamina: 9},
+		perLevel: Stats{Strength: 1, Agility: 1, Intellect: 2, Stamina: 2},
+	},
+}
+
+var defaultClass = classGrowth{
+	base:     Stats{Strength: 8, Agility: 8, Intellect: 8, Stamina: 8},
+	perLevel: Stats{Strength: 1, Agility: 1, Intellect: 1, Stamina: 1},
+}
+
+func IsValidStat(stat string) bool {
+	switch stat {
+	case Strength, Agility, Intellect, Stamina, Health, Mana:
+		return true
+	}
+	return false
+}
+
+// BaseStats returns the stats of a class at the given level before any
+// equipment or effects are taken into account.
+func BaseStats(class string, level int32) Stats {
+	growth, ok := classes[class]
+	if !ok {
+		growth = defaultClass
+	}
+
+	levels := max(level-1, 0)
+
+	s := Stats{
+		Strength:  growth.base.Strength + growth.perLevel.Strength*levels,
+		Agility:   growth.base.Agility + growth.perLevel.Agility*levels,
+		Intellect: growth.base.Intellect + growth.perLevel.Intellect*levels,
+		Stamina:   growth.base.Stamina + growth.perLevel.Stamina*levels,
+	}
+	s.Health = s.Stamina * healthPerStamina
+	s.Mana = s.Intellect * manaPerIntellect
+
+	return s
+}
+
+// Compute derives the final stats of a character. Primary stat modifiers are
+// applied before health and mana are derived from them, so a stamina buff
+// also raises health; health and mana modifiers are applied last.
+func Compute(class string, level int32, modifiers []Modifier) Stats {
+	s := BaseStats(class, level)
+
+	var health, mana int32
+	for _, m := range modifiers {
+		switch m.Stat {
+		case Strength:
+			s.Strength += m.Value
+		case Agility:
+			s.Agility += m.Value
+		case Intellect:
+			s.Intellect += m.Value
+		case Stamina:
+			s.Stamina += m.Value
+		case Health:
+			health += m.Value
+		case Mana:
+			mana += m.Value
+		}
+	}
+
+	s.Strength = max(s.Strength, 0)
+	s.Agility = max(s.Agility, 0)
+	s.Intellect = max(s.Intellect, 0)
+	s.Stamina = max(s.Stamina, 0)
+	s.Health = max(s.Stamina*healthPerStamina+health, 1)
+	s.Mana = max(s.Intellect*manaPerIntellect+mana, 0)
+
+	return s
+}
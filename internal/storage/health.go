@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/health"
+	"github.com/hossokawa/go-nethttp-example/internal/migrate"
+	"github.com/hossokawa/go-nethttp-example/internal/sqlite"
+)
+
+// NewPostgresHealth checks that the database answers, that the migrations m
+// knows of are applied, and that the pool has a connection to spare. A schema
+// newer than m knows of passes, so that instances still running the previous
+// release stay ready while a new one rolls out.
+func NewPostgresHealth(db *database.DB, m *migrate.Migrator) *health.Service {
+	return health.NewService(m.Version,
+		health.Check{Name: "database", Run: db.Ping},
+		health.Check{Name: "migrations", Run: func(ctx context.Context) error {
+			version, err := m.Version(ctx)
+			if err != nil {
+				return err
+			}
+			if latest := m.Latest(); version < latest {
+				return fmt.Errorf("schema is at version %d, want %d", version, latest)
+			}
+			return nil
+		}},
+		health.Check{Name: "pool", Run: func(ctx context.Context) error {
+			stat := db.Pool().Stat()
+			if stat.AcquiredConns() >= stat.MaxConns() {
+				return fmt.Errorf("all %d connections are in use", stat.MaxConns())
+			}
+			return nil
+		}},
+	)
+}
+
+// NewSQLiteHealth checks that the database answers. Its schema is brought up
+// to date when it is opened.
+func NewSQLiteHealth(db *sqlite.DB) *health.Service {
+	return health.NewService(db.Version, health.Check{Name: "database", Run: db.Ping})
+}
+
+// NewMemoryHealth has nothing to check, memory storage is always ready.
+func NewMemoryHealth() *health.Service {
+	return health.NewService(nil)
+}
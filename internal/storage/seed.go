@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+)
+
+var (
+	rustySword    = uuid.MustParse("42c6294c-56de-49d2-be2e-055b2a2151a6")
+	batWing       = uuid.MustParse("2e9e9593-c5ec-4554-9e15-131aa0b63127")
+	skeletonFemur = uuid.MustParse("0faffedc-2047-4616-9357-51d22fe80ff7")
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateTime, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Seed loads the sample data inserted by the migrations into db, so that the
+// in-memory backend starts out like a freshly migrated database.
+func Seed(ctx context.Context, db *memdb.DB) error {
+	return db.Write(ctx, func(t *memdb.Tables) error {
+		for _, p := range []struct {
+			username, class string
+			level, gold     int32
+			created, update string
+		}{
+			{"Sephiro", "Warrior", 32, 4309, "2024-01-15 14:23:45", "2024-02-10 08:30:15"},
+			{"SpellQueen", "Mage", 47, 7390, "2023-12-05 10:15:12", "2024-01-22 16:45:22"},
+			{"StormcallerX", "Druid", 29, 3982, "2023-11-20 09:20:34", "2024-01-10 12:10:58"},
+			{"ShadowMaster", "Rogue", 50, 7521, "2023-10-08 19:50:14", "2023-12-18 11:35:45"},
+			{"FireFurry", "Sorcerer", 55, 8097, "2023-09-03 17:45:23", "2023-11-14 14:20:30"},
+		} {
+			id := t.NextID("player")
+			t.Players[id] = memdb.Player{
+				ID:          id,
+				Username:    p.username,
+				UsernameKey: player.UsernameKey(p.username),
+				Class:       p.class,
+				Level:       p.level,
+				Gold:        p.gold,
+				Version:     1,
+				CreatedAt:   date(p.created),
+				UpdatedAt:   date(p.update),
+			}
+		}
+
+		t.Items[rustySword] = memdb.Item{ID: rustySword, Name: "Rusty Sword", Value: 100, Stats: map[string]int32{"strength": 3}, Version: 1}
+		t.Items[batWing] = memdb.Item{ID: batWing, Name: "Bat Wing", Value: 6, Stats: map[string]int32{}, Version: 1}
+		t.Items[skeletonFemur] = memdb.Item{ID: skeletonFemur, Name: "Skeleton Femur", Value: 2, Stats: map[string]int32{}, Version: 1}
+
+		for _, inv := range []struct {
+			playerID int32
+			itemID   uuid.UUID
+		}{
+			{1, rustySword},
+			{2, batWing},
+			{3, skeletonFemur},
+			{4, rustySword},
+			{5, skeletonFemur},
+		} {
+			t.Inventory[memdb.InventoryKey{PlayerID: inv.playerID, ItemID: inv.itemID}] = memdb.InventoryRow{Equipped: inv.itemID == rustySword}
+		}
+
+		now := time.Now()
+		for _, z := range []memdb.Zone{
+			{Name: "Elwynn Meadows", MinLevel: 1, MaxLevel: 10},
+			{Name: "Duskwood Hollow", MinLevel: 10, MaxLevel: 25},
+			{Name: "Stranglethorn Vale", MinLevel: 25, MaxLevel: 40, PvP: true},
+			{Name: "Burning Steppes", MinLevel: 40, MaxLevel: 60, PvP: true},
+		} {
+			z.ID = t.NextID("zone")
+			t.Zones[z.ID] = z
+		}
+		for _, c := range [][2]int32{{1, 2}, {2, 1}, {2, 3}, {3, 2}, {3, 4}, {4, 3}} {
+			t.ZoneConnections[memdb.ZoneConnection{FromZoneID: c[0], ToZoneID: c[1]}] = true
+		}
+		for _, l := range []memdb.Location{
+			{PlayerID: 1, ZoneID: 3, X: 120.5, Y: 88.0},
+			{PlayerID: 2, ZoneID: 4, X: 42.0, Y: 310.25, Z: 12.5},
+			{PlayerID: 3, ZoneID: 3, X: 201.75, Y: 16.0},
+			{PlayerID: 4, ZoneID: 4, X: 5.0, Y: 5.0},
+			{PlayerID: 5, ZoneID: 4, X: 77.5, Y: 140.0, Z: 3.0},
+		} {
+			l.UpdatedAt = now
+			t.Locations[l.PlayerID] = l
+		}
+
+		honorCap, winterveilCap := int32(75000), int32(500)
+		for _, c := range []memdb.Currency{
+			{Code: "gold", Name: "Gold"},
+			{Code: "gems", Name: "Premium Gems"},
+			{Code: "honor", Name: "Honor Tokens", Cap: &honorCap},
+			{Code: "winterveil", Name: "Winterveil Tokens", Cap: &winterveilCap},
+		} {
+			t.Currencies[c.Code] = c
+		}
+		t.Balances[memdb.BalanceKey{PlayerID: 1, Currency: "honor"}] = 1200
+		t.Balances[memdb.BalanceKey{PlayerID: 2, Currency: "gems"}] = 250
+		t.Balances[memdb.BalanceKey{PlayerID: 4, Currency: "honor"}] = 5400
+		t.Balances[memdb.BalanceKey{PlayerID: 5, Currency: "winterveil"}] = 35
+
+		t.ItemPrices[memdb.ItemPriceKey{ItemID: rustySword, Currency: "gold"}] = 450
+		t.ItemPrices[memdb.ItemPriceKey{ItemID: rustySword, Currency: "honor"}] = 300
+		t.ItemPrices[memdb.ItemPriceKey{ItemID: batWing, Currency: "gold"}] = 20
+
+		t.Bundles["gems_small"] = memdb.Bundle{
+			ID: "gems_small", Name: "Pouch of Gems", PriceCents: 499, Active: true,
+			Currencies: map[string]int32{"gems": 500},
+		}
+		t.Bundles["gems_large"] = memdb.Bundle{
+			ID: "gems_large", Name: "Chest of Gems", PriceCents: 1999, Active: true,
+			Currencies: map[string]int32{"gems": 2200},
+		}
+		t.Bundles["starter_pack"] = memdb.Bundle{
+			ID: "starter_pack", Name: "Adventurer Starter Pack", PriceCents: 999, Active: true,
+			Currencies: map[string]int32{"gems": 300, "gold": 1000},
+			ItemIDs:    []uuid.UUID{rustySword},
+		}
+
+		accountID := t.NextID("account")
+		t.Accounts[accountID] = memdb.Account{ID: accountID, Name: "sephiro-family", CreatedAt: date("2024-01-15 14:20:00")}
+		for _, id := range []int32{1, 3} {
+			p := t.Players[id]
+			p.AccountID = &accountID
+			t.Players[id] = p
+		}
+
+		return nil
+	})
+}
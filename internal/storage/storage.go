@@ -0,0 +1,89 @@
+// Package storage builds the set of repositories the API runs on, backed by
+// Postgres, memory or SQLite.
+package storage
+
+import (
+	"github.com/hossokawa/go-nethttp-example/internal/account"
+	"github.com/hossokawa/go-nethttp-example/internal/bank"
+	"github.com/hossokawa/go-nethttp-example/internal/currency"
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/effect"
+	"github.com/hossokawa/go-nethttp-example/internal/idempotency"
+	"github.com/hossokawa/go-nethttp-example/internal/inventory"
+	"github.com/hossokawa/go-nethttp-example/internal/item"
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+	"github.com/hossokawa/go-nethttp-example/internal/pvp"
+	"github.com/hossokawa/go-nethttp-example/internal/shop"
+	"github.com/hossokawa/go-nethttp-example/internal/sqlite"
+	"github.com/hossokawa/go-nethttp-example/internal/zone"
+)
+
+const (
+	Postgres = "postgres"
+	Memory   = "memory"
+	SQLite   = "sqlite"
+)
+
+// Repositories holds one repository per domain, all backed by the same
+// store. Tx runs a unit of work that the repositories join. A backend that
+// does not store a domain leaves its repository nil.
+type Repositories struct {
+	Tx          shop.Transactor
+	Players     player.PlayerRepository
+	Items       item.ItemRepository
+	Inventory   inventory.InventoryRepository
+	Effects     effect.EffectRepository
+	PvP         pvp.PvPRepository
+	Zones       zone.ZoneRepository
+	Currencies  currency.CurrencyRepository
+	Shop        shop.ShopRepository
+	Accounts    account.AccountRepository
+	Banks       bank.BankRepository
+	Idempotency idempotency.IdempotencyRepository
+}
+
+func NewPostgresRepositories(db *database.DB) *Repositories {
+	return &Repositories{
+		Tx:          db,
+		Players:     player.NewPostgresRepository(db),
+		Items:       item.NewPostgresRepository(db),
+		Inventory:   inventory.NewPostgresRepository(db),
+		Effects:     effect.NewPostgresRepository(db),
+		PvP:         pvp.NewPostgresRepository(db),
+		Zones:       zone.NewPostgresRepository(db),
+		Currencies:  currency.NewPostgresRepository(db),
+		Shop:        shop.NewPostgresRepository(db),
+		Accounts:    account.NewPostgresRepository(db),
+		Banks:       bank.NewPostgresRepository(db),
+		Idempotency: idempotency.NewPostgresRepository(db),
+	}
+}
+
+func NewMemoryRepositories(db *memdb.DB) *Repositories {
+	return &Repositories{
+		Tx:          db,
+		Players:     player.NewMemoryRepository(db),
+		Items:       item.NewMemoryRepository(db),
+		Inventory:   inventory.NewMemoryRepository(db),
+		Effects:     effect.NewMemoryRepository(db),
+		PvP:         pvp.NewMemoryRepository(db),
+		Zones:       zone.NewMemoryRepository(db),
+		Currencies:  currency.NewMemoryRepository(db),
+		Shop:        shop.NewMemoryRepository(db),
+		Accounts:    account.NewMemoryRepository(db),
+		Banks:       bank.NewMemoryRepository(db),
+		Idempotency: idempotency.NewMemoryRepository(db),
+	}
+}
+
+// NewSQLiteRepositories covers players, items and inventories only; the
+// other repositories are nil.
+func NewSQLiteRepositories(db *sqlite.DB) *Repositories {
+	return &Repositories{
+		Tx:        db,
+		Players:   player.NewSQLiteRepository(db),
+		Items:     item.NewSQLiteRepository(db),
+		Inventory: inventory.NewSQLiteRepository(db),
+	}
+}
@@ -0,0 +1,97 @@
+package storage_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+	"github.com/hossokawa/go-nethttp-example/internal/migrate"
+	"github.com/hossokawa/go-nethttp-example/internal/repotest"
+	"github.com/hossokawa/go-nethttp-example/internal/sqlite"
+	"github.com/hossokawa/go-nethttp-example/internal/storage"
+	"github.com/hossokawa/go-nethttp-example/migrations"
+)
+
+func TestMemoryRepositories(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		return repotest.FromStorage(storage.NewMemoryRepositories(memdb.New()))
+	})
+}
+
+func TestMemoryGoldCap(t *testing.T) {
+	db := memdb.New()
+
+	repotest.GoldCap(t, repotest.FromStorage(storage.NewMemoryRepositories(db)), func(cap int32) {
+		err := db.Write(t.Context(), func(tables *memdb.Tables) error {
+			tables.Currencies["gold"] = memdb.Currency{Code: "gold", Name: "Gold", Cap: &cap}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("setting gold cap: %v", err)
+		}
+	})
+}
+
+func TestSQLiteRepositories(t *testing.T) {
+	if !sqlite.Available {
+		t.Skip("built without the sqlite tag")
+	}
+
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		db, err := sqlite.Open(t.Context(), sqlite.Memory)
+		if err != nil {
+			t.Fatalf("opening database: %v", err)
+		}
+		t.Cleanup(db.Close)
+
+		return repotest.FromStorage(storage.NewSQLiteRepositories(db))
+	})
+}
+
+func TestSQLiteGoldCap(t *testing.T) {
+	if !sqlite.Available {
+		t.Skip("built without the sqlite tag")
+	}
+
+	db, err := sqlite.Open(t.Context(), sqlite.Memory)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	repotest.GoldCap(t, repotest.FromStorage(storage.NewSQLiteRepositories(db)), func(cap int32) {
+		if _, err := db.Exec(t.Context(), `UPDATE currency SET cap = ?1 WHERE code = 'gold'`, cap); err != nil {
+			t.Fatalf("setting gold cap: %v", err)
+		}
+	})
+}
+
+// TestPostgresRepositories runs against the database at TEST_DB_URL, which
+// it migrates up first. The suite leaves the players and items it creates
+// behind, so the database should be one set aside for tests.
+func TestPostgresRepositories(t *testing.T) {
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+
+	db, err := database.Open(t.Context(), database.Config{URL: url})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	ms, err := migrate.Load(migrations.Postgres)
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	if _, err := migrate.New(db, ms).Up(t.Context()); err != nil {
+		t.Fatalf("migrating database: %v", err)
+	}
+
+	repos := repotest.FromStorage(storage.NewPostgresRepositories(db))
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		return repos
+	})
+}
@@ -0,0 +1,155 @@
+package validate_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hossokawa/go-nethttp-example/internal/validate"
+)
+
+// TestRepositoryTags type-checks every package of the module and checks the
+// validate tags of every struct declared in them, so that a malformed tag
+// fails here rather than panicking on the first request that reaches it.
+func TestRepositoryTags(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds every package of the module")
+	}
+
+	module := goList(t, "-m")[0]
+
+	// Imports are loaded from export data rather than type-checked again.
+	exports := make(map[string]string)
+	for _, line := range goList(t, "-export", "-deps", "-f", "{{.ImportPath}}={{.Export}}", module+"/...") {
+		path, file, _ := strings.Cut(line, "=")
+		exports[path] = file
+	}
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		file, ok := exports[path]
+		if !ok || file == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(file)
+	})
+
+	// Export data leaves out unexported types, such as the request bodies of
+	// the handlers, so the packages of the module are checked from source.
+	var checked int
+	for _, line := range goList(t, "-f", `{{.ImportPath}}={{.Dir}}={{join .GoFiles " "}}`, module+"/...") {
+		parts := strings.SplitN(line, "=", 3)
+		path, dir := parts[0], parts[1]
+
+		var files []*ast.File
+		for _, name := range strings.Fields(parts[2]) {
+			f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, f)
+		}
+
+		info := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+		conf := types.Config{Importer: imp}
+		if _, err := conf.Check(path, fset, files, info); err != nil {
+			t.Fatalf("type-checking %s: %v", path, err)
+		}
+
+		for id, obj := range info.Defs {
+			if _, ok := obj.(*types.TypeName); !ok {
+				continue
+			}
+			if st, ok := obj.Type().Underlying().(*types.Struct); ok {
+				checked += checkTags(t, path+"."+id.Name, st)
+			}
+		}
+	}
+
+	if checked == 0 {
+		t.Fatal("found no validate tags")
+	}
+}
+
+// goList runs go list with args and returns the lines it printed.
+func goList(t *testing.T, args ...string) []string {
+	out, err := exec.Command("go", append([]string{"list"}, args...)...).Output()
+	if err != nil {
+		t.Fatalf("go list %v: %v", args, err)
+	}
+	return strings.Split(strings.TrimSpace(string(out)), "\n")
+}
+
+// checkTags checks the validate tags of the fields of st and of the structs
+// it declares inline, and returns how many it checked.
+func checkTags(t *testing.T, name string, st *types.Struct) int {
+	var checked int
+
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		typ := f.Type()
+		if p, ok := typ.(*types.Pointer); ok {
+			typ = p.Elem()
+		}
+
+		if tag := reflect.StructTag(st.Tag(i)).Get("validate"); tag != "" {
+			if err := validate.CheckTag(tag, kind(typ)); err != nil {
+				t.Errorf("%s.%s: %v", name, f.Name(), err)
+			}
+			checked++
+		}
+
+		if inline, ok := typ.(*types.Struct); ok {
+			checked += checkTags(t, name+"."+f.Name(), inline)
+		}
+	}
+
+	return checked
+}
+
+var basicKinds = map[types.BasicKind]reflect.Kind{
+	types.Bool:    reflect.Bool,
+	types.Int:     reflect.Int,
+	types.Int8:    reflect.Int8,
+	types.Int16:   reflect.Int16,
+	types.Int32:   reflect.Int32,
+	types.Int64:   reflect.Int64,
+	types.Uint:    reflect.Uint,
+	types.Uint8:   reflect.Uint8,
+	types.Uint16:  reflect.Uint16,
+	types.Uint32:  reflect.Uint32,
+	types.Uint64:  reflect.Uint64,
+	types.Float32: reflect.Float32,
+	types.Float64: reflect.Float64,
+	types.String:  reflect.String,
+}
+
+// kind returns the reflect.Kind values of typ have at run time.
+func kind(typ types.Type) reflect.Kind {
+	switch u := typ.Underlying().(type) {
+	case *types.Basic:
+		return basicKinds[u.Kind()]
+	case *types.Slice:
+		return reflect.Slice
+	case *types.Array:
+		return reflect.Array
+	case *types.Map:
+		return reflect.Map
+	case *types.Struct:
+		return reflect.Struct
+	case *types.Pointer:
+		return reflect.Pointer
+	case *types.Interface:
+		return reflect.Interface
+	}
+	return reflect.Invalid
+}
@@ -0,0 +1,253 @@
+// Package validate checks structs against the rules declared in their
+// `validate` tags, for example:
+//
+//	Username string `json:"username" validate:"required,min=3,max=20,charset=username"`
+//
+// Supported rules are required, notblank, min, max, oneof and charset. For
+// strings min and max bound the length in characters, for numbers the value
+// and for slices and maps the number of elements. Nil pointers are skipped
+// unless the field is required. Fields are reported by their JSON name.
+//
+// A malformed tag is a programming error: Struct panics on it. CheckTag
+// reports the same mistakes as an error, for tests to catch them early.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+)
+
+// charsets are the character classes that can be named by the charset rule.
+var charsets = map[string]struct {
+	desc    string
+	allowed func(rune) bool
+}{
+	"alnum": {
+		desc:    "letters and digits",
+		allowed: func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) },
+	},
+	"username": {
+		desc:    "letters, digits, '_' and '-'",
+		allowed: func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' },
+	},
+	"printable": {
+		desc:    "printable characters",
+		allowed: unicode.IsPrint,
+	},
+	"code": {
+		desc:    "lowercase letters, digits, '_' and '-'",
+		allowed: func(r rune) bool { return (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' },
+	},
+}
+
+// Struct validates v, which must be a struct or a pointer to one, and returns
+// every violation found.
+func Struct(v any) apperr.Fields {
+	var fields apperr.Fields
+	checkStruct(reflect.Indirect(reflect.ValueOf(v)), "", &fields)
+	return fields
+}
+
+func checkStruct(v reflect.Value, prefix string, fields *apperr.Fields) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		fv := v.Field(i)
+
+		// Embedded structs contribute their fields at the same level, as in
+		// encoding/json.
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			checkStruct(fv, prefix, fields)
+			continue
+		}
+
+		name := jsonName(f)
+		if name == "-" {
+			continue
+		}
+		name = prefix + name
+
+		checkField(fv, name, f.Tag.Get("validate"), fields)
+
+		if fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct && !opaque(fv.Type()) {
+			checkStruct(fv, name+".", fields)
+		}
+	}
+}
+
+// opaque reports whether a struct is a value type such as time.Time whose
+// fields are not part of the request.
+func opaque(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// CheckTag reports whether tag is a valid list of rules for a field of the
+// given kind. For pointers the kind is that of the value pointed to.
+func CheckTag(tag string, kind reflect.Kind) error {
+	for _, rule := range strings.Split(tag, ",") {
+		key, arg, _ := strings.Cut(rule, "=")
+
+		switch key {
+		case "required":
+		case "notblank":
+			if kind != reflect.String {
+				return fmt.Errorf("notblank not supported on %s", kind)
+			}
+		case "min", "max":
+			if _, err := strconv.ParseFloat(arg, 64); err != nil {
+				return fmt.Errorf("invalid bound %q for %s", arg, key)
+			}
+			if !bounded(kind) {
+				return fmt.Errorf("%s not supported on %s", key, kind)
+			}
+		case "oneof":
+			if len(strings.Fields(arg)) == 0 {
+				return errors.New("oneof without options")
+			}
+		case "charset":
+			if _, ok := charsets[arg]; !ok {
+				return fmt.Errorf("unknown charset %q", arg)
+			}
+			if kind != reflect.String {
+				return fmt.Errorf("charset not supported on %s", kind)
+			}
+		default:
+			return fmt.Errorf("unknown rule %q", key)
+		}
+	}
+	return nil
+}
+
+// bounded reports whether min and max apply to values of kind.
+func bounded(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func checkField(v reflect.Value, name, tag string, fields *apperr.Fields) {
+	if tag == "" {
+		return
+	}
+
+	kind := v.Kind()
+	if kind == reflect.Pointer {
+		kind = v.Type().Elem().Kind()
+	}
+	if err := CheckTag(tag, kind); err != nil {
+		panic(fmt.Sprintf("validate: %s on %s", err, name))
+	}
+
+	rules := strings.Split(tag, ",")
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			for _, rule := range rules {
+				if rule == "required" {
+					fields.Add(name, "is required")
+				}
+			}
+			return
+		}
+		v = v.Elem()
+	}
+
+	for _, rule := range rules {
+		key, arg, _ := strings.Cut(rule, "=")
+
+		var msg string
+		switch key {
+		case "required":
+			if v.IsZero() {
+				// A missing value makes every other rule moot.
+				fields.Add(name, "is required")
+				return
+			}
+		case "notblank":
+			if strings.TrimSpace(v.String()) == "" {
+				msg = "must not be blank"
+			}
+		case "min":
+			msg = checkBound(v, arg, true)
+		case "max":
+			msg = checkBound(v, arg, false)
+		case "oneof":
+			options := strings.Fields(arg)
+			if !slices.Contains(options, fmt.Sprint(v.Interface())) {
+				msg = "must be one of " + strings.Join(options, ", ")
+			}
+		case "charset":
+			cs := charsets[arg]
+			if strings.IndexFunc(v.String(), func(r rune) bool { return !cs.allowed(r) }) >= 0 {
+				msg = "may only contain " + cs.desc
+			}
+		}
+
+		if msg != "" {
+			fields.Add(name, msg)
+		}
+	}
+}
+
+func checkBound(v reflect.Value, arg string, lower bool) string {
+	// CheckTag has made sure the bound parses and applies to v.
+	bound, _ := strconv.ParseFloat(arg, 64)
+
+	var n float64
+	var unit string
+
+	switch v.Kind() {
+	case reflect.String:
+		n, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		n, unit = float64(v.Len()), " elements"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	}
+
+	switch {
+	case lower && n < bound:
+		return "must be at least " + arg + unit
+	case !lower && n > bound:
+		return "must be at most " + arg + unit
+	}
+	return ""
+}
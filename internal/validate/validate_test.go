@@ -0,0 +1,177 @@
+package validate_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/validate"
+)
+
+type profile struct {
+	Username string   `json:"username" validate:"required,min=3,max=20,charset=username"`
+	Title    string   `json:"title" validate:"notblank,max=8,charset=printable"`
+	Class    string   `json:"class" validate:"oneof=Warrior Mage"`
+	Level    int32    `json:"level" validate:"min=1,max=60"`
+	Ratio    float64  `json:"ratio" validate:"max=1.5"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Nickname *string  `json:"nickname" validate:"min=2"`
+	Guild    *string  `json:"guild" validate:"required"`
+	Ignored  string   `json:"-" validate:"required"`
+	internal string   `validate:"required"`
+}
+
+func validProfile() profile {
+	guild := "Knights"
+	return profile{Username: "ann_01", Title: "Sir", Class: "Mage", Level: 1, Tags: []string{"a"}, Guild: &guild}
+}
+
+func TestStruct(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name   string
+		modify func(*profile)
+		want   apperr.Fields
+	}{
+		{"valid", func(*profile) {}, nil},
+		{"required missing", func(p *profile) { p.Username = "" }, apperr.Fields{
+			{Field: "username", Message: "is required"},
+		}},
+		{"too short", func(p *profile) { p.Username = "an" }, apperr.Fields{
+			{Field: "username", Message: "must be at least 3 characters"},
+		}},
+		{"too long counts characters", func(p *profile) { p.Username = strings.Repeat("é", 21) }, apperr.Fields{
+			{Field: "username", Message: "must be at most 20 characters"},
+		}},
+		{"length in characters, not bytes", func(p *profile) { p.Username = strings.Repeat("é", 20) }, nil},
+		{"charset", func(p *profile) { p.Username = "ann 01" }, apperr.Fields{
+			{Field: "username", Message: "may only contain letters, digits, '_' and '-'"},
+		}},
+		{"blank", func(p *profile) { p.Title = "   " }, apperr.Fields{
+			{Field: "title", Message: "must not be blank"},
+		}},
+		{"notblank without required rejects empty", func(p *profile) { p.Title = "" }, apperr.Fields{
+			{Field: "title", Message: "must not be blank"},
+		}},
+		{"printable", func(p *profile) { p.Title = "a\tb" }, apperr.Fields{
+			{Field: "title", Message: "may only contain printable characters"},
+		}},
+		{"oneof", func(p *profile) { p.Class = "Bard" }, apperr.Fields{
+			{Field: "class", Message: "must be one of Warrior, Mage"},
+		}},
+		{"number below min", func(p *profile) { p.Level = 0 }, apperr.Fields{
+			{Field: "level", Message: "must be at least 1"},
+		}},
+		{"number above max", func(p *profile) { p.Level = 61 }, apperr.Fields{
+			{Field: "level", Message: "must be at most 60"},
+		}},
+		{"fractional bound", func(p *profile) { p.Ratio = 1.6 }, apperr.Fields{
+			{Field: "ratio", Message: "must be at most 1.5"},
+		}},
+		{"elements", func(p *profile) { p.Tags = []string{"a", "b", "c"} }, apperr.Fields{
+			{Field: "tags", Message: "must be at most 2 elements"},
+		}},
+		{"nil pointer skipped", func(p *profile) { p.Nickname = nil }, nil},
+		{"pointer checked", func(p *profile) { p.Nickname = ptr("x") }, apperr.Fields{
+			{Field: "nickname", Message: "must be at least 2 characters"},
+		}},
+		{"nil pointer required", func(p *profile) { p.Guild = nil }, apperr.Fields{
+			{Field: "guild", Message: "is required"},
+		}},
+		{"every violation reported", func(p *profile) { p.Username, p.Level = "", 0 }, apperr.Fields{
+			{Field: "username", Message: "is required"},
+			{Field: "level", Message: "must be at least 1"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := validProfile()
+			tt.modify(&p)
+
+			if got := validate.Struct(&p); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Struct = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type Page struct {
+	Limit int `json:"limit" validate:"min=1,max=100"`
+}
+
+type Stats struct {
+	Strength int `json:"strength" validate:"min=0"`
+}
+
+type search struct {
+	Page
+	Query string `json:"query" validate:"required"`
+	Stats Stats  `json:"stats"`
+	Extra *Stats `json:"extra"`
+}
+
+func TestStructNested(t *testing.T) {
+	s := search{Page: Page{Limit: 0}, Stats: Stats{Strength: -1}, Extra: &Stats{Strength: -2}}
+
+	want := apperr.Fields{
+		{Field: "limit", Message: "must be at least 1"},
+		{Field: "query", Message: "is required"},
+		{Field: "stats.strength", Message: "must be at least 0"},
+		{Field: "extra.strength", Message: "must be at least 0"},
+	}
+	if got := validate.Struct(s); !reflect.DeepEqual(got, want) {
+		t.Errorf("Struct = %v, want %v", got, want)
+	}
+
+	s = search{Page: Page{Limit: 10}, Query: "sword"}
+	if got := validate.Struct(&s); got != nil {
+		t.Errorf("Struct with a nil nested pointer = %v, want nothing", got)
+	}
+}
+
+func TestCheckTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		kind reflect.Kind
+		ok   bool
+	}{
+		{"required", reflect.Struct, true},
+		{"required,notblank,max=64,charset=printable", reflect.String, true},
+		{"min=0,max=100", reflect.Int32, true},
+		{"max=1.5", reflect.Float64, true},
+		{"max=3", reflect.Slice, true},
+		{"oneof=a b", reflect.String, true},
+		{"requird", reflect.String, false},
+		{"required,", reflect.String, false},
+		{"min=", reflect.Int, false},
+		{"max=ten", reflect.Int, false},
+		{"min=1", reflect.Bool, false},
+		{"max=1", reflect.Struct, false},
+		{"notblank", reflect.Int, false},
+		{"charset=emoji", reflect.String, false},
+		{"charset=alnum", reflect.Int, false},
+		{"oneof=", reflect.String, false},
+	}
+
+	for _, tt := range tests {
+		err := validate.CheckTag(tt.tag, tt.kind)
+		if (err == nil) != tt.ok {
+			t.Errorf("CheckTag(%q, %s) = %v, want ok %v", tt.tag, tt.kind, err, tt.ok)
+		}
+	}
+}
+
+func TestStructPanicsOnMalformedTag(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Struct did not panic on an unknown rule")
+		}
+	}()
+
+	validate.Struct(struct {
+		Name string `json:"name" validate:"requird"`
+	}{})
+}
@@ -0,0 +1,162 @@
+package zone
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+)
+
+type memRepository struct {
+	db *memdb.DB
+}
+
+// NewMemoryRepository returns a ZoneRepository that keeps zones and player
+// locations in db. It behaves like the Postgres repository, down to the
+// errors it returns.
+func NewMemoryRepository(db *memdb.DB) ZoneRepository {
+	return &memRepository{db: db}
+}
+
+func toZone(row memdb.Zone) *Zone {
+	return &Zone{
+		ID:       row.ID,
+		Name:     row.Name,
+		MinLevel: row.MinLevel,
+		MaxLevel: row.MaxLevel,
+		PvP:      row.PvP,
+	}
+}
+
+func toLocation(row memdb.Location) *Location {
+	return &Location{
+		PlayerID:  row.PlayerID,
+		ZoneID:    row.ZoneID,
+		X:         row.X,
+		Y:         row.Y,
+		Z:         row.Z,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
+
+func (r *memRepository) GetAllZones(ctx context.Context) ([]*Zone, error) {
+	var zones []*Zone
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Zones {
+			zones = append(zones, toZone(row))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(zones, func(a, b *Zone) int {
+		if c := cmp.Compare(a.MinLevel, b.MinLevel); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return zones, nil
+}
+
+func (r *memRepository) GetZoneByID(ctx context.Context, id int32) (*Zone, error) {
+	var z *Zone
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		if row, ok := t.Zones[id]; ok {
+			z = toZone(row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return z, nil
+}
+
+func (r *memRepository) IsConnected(ctx context.Context, fromZoneID, toZoneID int32) (bool, error) {
+	var connected bool
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		connected = t.ZoneConnections[memdb.ZoneConnection{FromZoneID: fromZoneID, ToZoneID: toZoneID}]
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return connected, nil
+}
+
+func (r *memRepository) GetPlayerLocation(ctx context.Context, playerID int32) (*Location, error) {
+	var l *Location
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		if row, ok := t.Locations[playerID]; ok {
+			l = toLocation(row)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (r *memRepository) UpdatePlayerLocation(ctx context.Context, args UpdatePlayerLocationParams) (*Location, error) {
+	var l *Location
+
+	err := r.db.Write(ctx, func(t *memdb.Tables) error {
+		_, playerOK := t.Players[args.PlayerID]
+		_, zoneOK := t.Zones[args.ZoneID]
+		if !playerOK || !zoneOK {
+			return fmt.Errorf("scanning row into location struct: %w", memdb.ErrForeignKey)
+		}
+
+		row := memdb.Location{
+			PlayerID:  args.PlayerID,
+			ZoneID:    args.ZoneID,
+			X:         args.X,
+			Y:         args.Y,
+			Z:         args.Z,
+			UpdatedAt: time.Now(),
+		}
+		t.Locations[row.PlayerID] = row
+
+		l = toLocation(row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (r *memRepository) ListPlayersInZone(ctx context.Context, zoneID int32) ([]*Location, error) {
+	var locations []*Location
+
+	err := r.db.Read(ctx, func(t *memdb.Tables) error {
+		for _, row := range t.Locations {
+			if row.ZoneID == zoneID {
+				locations = append(locations, toLocation(row))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(locations, func(a, b *Location) int { return cmp.Compare(a.PlayerID, b.PlayerID) })
+
+	return locations, nil
+}
@@ -0,0 +1,228 @@
+package zone
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/querylabel"
+	"github.com/jackc/pgx/v5"
+)
+
+type ZoneRepository interface {
+	GetAllZones(ctx context.Context) ([]*Zone, error)
+	GetZoneByID(ctx context.Context, id int32) (*Zone, error)
+	IsConnected(ctx context.Context, fromZoneID, toZoneID int32) (bool, error)
+	GetPlayerLocation(ctx context.Context, playerID int32) (*Location, error)
+	UpdatePlayerLocation(ctx context.Context, args UpdatePlayerLocationParams) (*Location, error)
+	ListPlayersInZone(ctx context.Context, zoneID int32) ([]*Location, error)
+}
+
+type pgRepository struct {
+	db *database.DB
+}
+
+func NewPostgresRepository(db *database.DB) ZoneRepository {
+	return &pgRepository{db: db}
+}
+
+const getAllZones = `
+SELECT id, name, min_level, max_level, pvp FROM zone ORDER BY min_level, id
+`
+
+func (r *pgRepository) GetAllZones(ctx context.Context) ([]*Zone, error) {
+	ctx = querylabel.With(ctx, "zone", "GetAllZones")
+
+	rows, err := r.db.Query(ctx, getAllZones)
+	if err != nil {
+		return nil, fmt.Errorf("querying for all zones: %w", err)
+	}
+	defer rows.Close()
+
+	var zones []*Zone
+
+	for rows.Next() {
+		var z Zone
+
+		err = rows.Scan(
+			&z.ID,
+			&z.Name,
+			&z.MinLevel,
+			&z.MaxLevel,
+			&z.PvP,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning rows into zone struct: %w", err)
+		}
+
+		zones = append(zones, &z)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return zones, nil
+}
+
+const getZoneByID = `
+SELECT id, name, min_level, max_level, pvp FROM zone WHERE id = $1
+`
+
+func (r *pgRepository) GetZoneByID(ctx context.Context, id int32) (*Zone, error) {
+	ctx = querylabel.With(ctx, "zone", "GetZoneByID")
+
+	var z Zone
+
+	row := r.db.QueryRow(ctx, getZoneByID, id)
+	err := row.Scan(
+		&z.ID,
+		&z.Name,
+		&z.MinLevel,
+		&z.MaxLevel,
+		&z.PvP,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into zone struct: %w", err)
+	}
+
+	return &z, nil
+}
+
+const isConnected = `
+SELECT EXISTS (
+  SELECT 1 FROM zone_connection WHERE from_zone_id = $1 AND to_zone_id = $2
+)
+`
+
+func (r *pgRepository) IsConnected(ctx context.Context, fromZoneID, toZoneID int32) (bool, error) {
+	ctx = querylabel.With(ctx, "zone", "IsConnected")
+
+	var connected bool
+
+	if err := r.db.QueryRow(ctx, isConnected, fromZoneID, toZoneID).Scan(&connected); err != nil {
+		return false, fmt.Errorf("checking zone connection: %w", err)
+	}
+
+	return connected, nil
+}
+
+const getPlayerLocation = `
+SELECT player_id, zone_id, x, y, z, updated_at FROM player_location WHERE player_id = $1
+`
+
+func (r *pgRepository) GetPlayerLocation(ctx context.Context, playerID int32) (*Location, error) {
+	ctx = querylabel.With(ctx, "zone", "GetPlayerLocation")
+
+	var l Location
+
+	row := r.db.QueryRow(ctx, getPlayerLocation, playerID)
+	err := row.Scan(
+		&l.PlayerID,
+		&l.ZoneID,
+		&l.X,
+		&l.Y,
+		&l.Z,
+		&l.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("scanning row into location struct: %w", err)
+	}
+
+	return &l, nil
+}
+
+const updatePlayerLocation = `
+INSERT INTO player_location (player_id, zone_id, x, y, z, updated_at)
+VALUES ($1, $2, $3, $4, $5, now())
+ON CONFLICT (player_id) DO UPDATE SET
+  zone_id = EXCLUDED.zone_id,
+  x = EXCLUDED.x,
+  y = EXCLUDED.y,
+  z = EXCLUDED.z,
+  updated_at = EXCLUDED.updated_at
+RETURNING player_id, zone_id, x, y, z, updated_at
+`
+
+type UpdatePlayerLocationParams struct {
+	PlayerID int32   `json:"player_id"`
+	ZoneID   int32   `json:"zone_id"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Z        float64 `json:"z"`
+}
+
+func (r *pgRepository) UpdatePlayerLocation(ctx context.Context, args UpdatePlayerLocationParams) (*Location, error) {
+	ctx = querylabel.With(ctx, "zone", "UpdatePlayerLocation")
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var l Location
+
+	row := tx.QueryRow(ctx, updatePlayerLocation, args.PlayerID, args.ZoneID, args.X, args.Y, args.Z)
+	err = row.Scan(
+		&l.PlayerID,
+		&l.ZoneID,
+		&l.X,
+		&l.Y,
+		&l.Z,
+		&l.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("scanning row into location struct: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commiting transaction: %w", err)
+	}
+
+	return &l, nil
+}
+
+const listPlayersInZone = `
+SELECT player_id, zone_id, x, y, z, updated_at FROM player_location WHERE zone_id = $1 ORDER BY player_id
+`
+
+func (r *pgRepository) ListPlayersInZone(ctx context.Context, zoneID int32) ([]*Location, error) {
+	ctx = querylabel.With(ctx, "zone", "ListPlayersInZone")
+
+	rows, err := r.db.Query(ctx, listPlayersInZone, zoneID)
+	if err != nil {
+		return nil, fmt.Errorf("querying for players in zone: %w", err)
+	}
+	defer rows.Close()
+
+	var locations []*Location
+
+	for rows.Next() {
+		var l Location
+
+		err = rows.Scan(
+			&l.PlayerID,
+			&l.ZoneID,
+			&l.X,
+			&l.Y,
+			&l.Z,
+			&l.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scanning rows into location struct: %w", err)
+		}
+
+		locations = append(locations, &l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
@@ -0,0 +1,122 @@
+package zone
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/hossokawa/go-nethttp-example/internal/apperr"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
+)
+
+// PlayerLookup is the part of the player service zones depend on.
+type PlayerLookup interface {
+	GetPlayerByID(ctx context.Context, id int32) (*player.Player, error)
+}
+
+type ZoneService struct {
+	repo    ZoneRepository
+	players PlayerLookup
+}
+
+func NewZoneService(repo ZoneRepository, players PlayerLookup) *ZoneService {
+	return &ZoneService{repo: repo, players: players}
+}
+
+// travelErr is returned when a location update breaks one of the travel
+// rules.
+func travelErr(msg string) error {
+	return apperr.New(apperr.KindValidation, msg)
+}
+
+func (s *ZoneService) GetAllZones(ctx context.Context) ([]*Zone, error) {
+	return s.repo.GetAllZones(ctx)
+}
+
+func (s *ZoneService) GetZoneByID(ctx context.Context, id int32) (*Zone, error) {
+	z, err := s.repo.GetZoneByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting zone with id %v: %w", id, err)
+	}
+	if z == nil {
+		return nil, apperr.NotFound("zone", "id", id)
+	}
+
+	return z, nil
+}
+
+func (s *ZoneService) GetPlayerLocation(ctx context.Context, playerID int32) (*Location, error) {
+	l, err := s.repo.GetPlayerLocation(ctx, playerID)
+	if err != nil {
+		return nil, fmt.Errorf("getting location of player with id %v: %w", playerID, err)
+	}
+	if l == nil {
+		return nil, apperr.NotFound("location", "player id", playerID)
+	}
+
+	return l, nil
+}
+
+// ListPlayersInZone answers "who is in zone X" for features scoped to a zone.
+func (s *ZoneService) ListPlayersInZone(ctx context.Context, zoneID int32) ([]*Location, error) {
+	if _, err := s.GetZoneByID(ctx, zoneID); err != nil {
+		return nil, err
+	}
+
+	locations, err := s.repo.ListPlayersInZone(ctx, zoneID)
+	if err != nil {
+		return nil, fmt.Errorf("getting players in zone with id %v: %w", zoneID, err)
+	}
+
+	return locations, nil
+}
+
+// MovePlayer updates a player's location. Entering a zone requires the player
+// to meet its minimum level, and moving between zones is only allowed along a
+// zone connection. A player without a location may enter any zone they meet
+// the level requirement for.
+func (s *ZoneService) MovePlayer(ctx context.Context, args UpdatePlayerLocationParams) (*Location, error) {
+	for _, c := range []float64{args.X, args.Y, args.Z} {
+		if math.IsNaN(c) || math.IsInf(c, 0) {
+			return nil, travelErr("coordinates must be finite numbers")
+		}
+	}
+
+	p, err := s.players.GetPlayerByID(ctx, args.PlayerID)
+	if err != nil {
+		return nil, err
+	}
+
+	z, err := s.GetZoneByID(ctx, args.ZoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.GetPlayerLocation(ctx, args.PlayerID)
+	if err != nil {
+		return nil, fmt.Errorf("getting location of player with id %v: %w", args.PlayerID, err)
+	}
+
+	if current == nil || current.ZoneID != z.ID {
+		if p.Level < z.MinLevel {
+			return nil, travelErr(fmt.Sprintf("zone '%v' requires level %v", z.Name, z.MinLevel))
+		}
+	}
+
+	if current != nil && current.ZoneID != z.ID {
+		connected, err := s.repo.IsConnected(ctx, current.ZoneID, z.ID)
+		if err != nil {
+			return nil, fmt.Errorf("checking travel from zone %v to zone %v: %w", current.ZoneID, z.ID, err)
+		}
+		if !connected {
+			return nil, travelErr(fmt.Sprintf("zone '%v' cannot be reached from the player's current zone", z.Name))
+		}
+	}
+
+	l, err := s.repo.UpdatePlayerLocation(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("updating location of player with id %v: %w", args.PlayerID, err)
+	}
+
+	return l, nil
+}
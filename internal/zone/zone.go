@@ -0,0 +1,20 @@
+package zone
+
+import "time"
+
+type Zone struct {
+	ID       int32  `json:"id"`
+	Name     string `json:"name"`
+	MinLevel int32  `json:"min_level"`
+	MaxLevel int32  `json:"max_level"`
+	PvP      bool   `json:"pvp"`
+}
+
+type Location struct {
+	PlayerID  int32     `json:"player_id"`
+	ZoneID    int32     `json:"zone_id"`
+	X         float64   `json:"x"`
+	Y         float64   `json:"y"`
+	Z         float64   `json:"z"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
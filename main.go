@@ -3,51 +3,360 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/hossokawa/go-nethttp-example/internal/config"
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/health"
+	"github.com/hossokawa/go-nethttp-example/internal/lifecycle"
+	"github.com/hossokawa/go-nethttp-example/internal/memdb"
+	"github.com/hossokawa/go-nethttp-example/internal/metrics"
+	"github.com/hossokawa/go-nethttp-example/internal/migrate"
+	"github.com/hossokawa/go-nethttp-example/internal/player"
 	"github.com/hossokawa/go-nethttp-example/internal/routes"
-	"github.com/jackc/pgx/v5"
+	"github.com/hossokawa/go-nethttp-example/internal/shop"
+	"github.com/hossokawa/go-nethttp-example/internal/sqlite"
+	"github.com/hossokawa/go-nethttp-example/internal/storage"
 	"github.com/joho/godotenv"
 )
 
-func run() error {
-	err := godotenv.Load()
+// run serves the API until it receives SIGINT or SIGTERM, then shuts down:
+// readiness starts failing, the server stops accepting connections and
+// drains in-flight requests, the background workers stop, and the storage is
+// closed last.
+func run(cfg *config.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	policy, err := usernamePolicy(cfg)
 	if err != nil {
-		return errors.New("error loading .env file")
+		return err
 	}
 
-	connStr := os.Getenv("DB_URL")
+	m := metrics.New()
 
-	log.Println("Connecting to the database...")
+	store, err := openStorage(cfg, m)
+	if err != nil {
+		return err
+	}
 
-	conn, err := pgx.Connect(context.Background(), connStr)
+	verifier, err := paymentVerifier(cfg, store.repos)
 	if err != nil {
-		return fmt.Errorf("error connecting to the database: %w", err)
+		store.close()
+		return err
 	}
-	defer conn.Close(context.Background())
 
-	log.Println("Connected to the database")
+	lc := lifecycle.New()
+	lc.Append(lifecycle.Hook{
+		Name: "storage",
+		Stop: func(context.Context) error {
+			store.close()
+			return nil
+		},
+	})
 
 	router := http.NewServeMux()
-	routes.SetupRoutes(router, conn)
+	handler := routes.SetupRoutes(lc, router, store.repos, routes.Config{
+		UsernamePolicy:     policy,
+		StartingGold:       cfg.Game.StartingGold,
+		RequestTimeout:     cfg.Server.RequestTimeout,
+		RestoreGracePeriod: cfg.Game.RestoreGracePeriod,
+		Health:             store.health,
+		Metrics:            m,
+		PaymentVerifier:    verifier,
+	})
 
 	server := &http.Server{
-		Addr:         ":8080",
-		Handler:      router,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		Addr:         cfg.Server.Addr,
+		Handler:      handler,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+	serveErr := make(chan error, 1)
+	lc.Append(serverHook(server, serveErr))
+	lc.Append(readinessHook(store.health, cfg.Server.ShutdownDelay))
+
+	if err := lc.Start(ctx); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down...")
+	case err = <-serveErr:
+		log.Printf("Server stopped unexpectedly, shutting down: %s", err)
+	}
+	// A second signal kills the process without waiting.
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if stopErr := lc.Stop(shutdownCtx); stopErr != nil {
+		return errors.Join(err, fmt.Errorf("error shutting down: %w", stopErr))
+	}
+
+	log.Println("Shut down")
+
+	return err
+}
+
+// readinessHook makes readiness fail first on shutdown, then keeps serving
+// for delay so the orchestrator notices before the server stops listening.
+func readinessHook(h *health.Service, delay time.Duration) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: "readiness",
+		Stop: func(ctx context.Context) error {
+			h.Shutdown()
+
+			select {
+			case <-time.After(delay):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// serverHook listens on the server's address when started, so a port that
+// is taken fails startup, and serves in the background. Errors that stop the
+// server other than shutting it down are sent on serveErr. Stopping drains
+// in-flight requests, and closes the connections that are still open if ctx
+// is done first.
+func serverHook(server *http.Server, serveErr chan<- error) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: "http server",
+		Start: func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+
+			log.Printf("Starting server on %s", ln.Addr())
+
+			go func() {
+				if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+					serveErr <- err
+				}
+			}()
+
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			if err := server.Shutdown(ctx); err != nil {
+				server.Close()
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// backend is the storage the API runs on.
+type backend struct {
+	repos  *storage.Repositories
+	health *health.Service
+	close  func()
+}
+
+// openStorage builds the repositories on the configured backend. The memory
+// backend needs no database and starts out with the same sample data as a
+// freshly migrated one. The SQLite backend keeps players, items and
+// inventories in a single file, migrating it on startup. Queries and pool
+// statistics of either database are recorded in m.
+func openStorage(cfg *config.Config, m *metrics.Metrics) (*backend, error) {
+	switch cfg.Storage.Backend {
+	case storage.Postgres:
+		log.Println("Connecting to the database...")
+
+		dbCfg := cfg.Database.Config
+		dbCfg.Tracer = m.QueryTracer()
+
+		db, err := database.Open(context.Background(), dbCfg)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to the database: %w", err)
+		}
+
+		log.Println("Connected to the database")
+
+		migrator, err := newMigrator(db)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+
+		if cfg.Database.AutoMigrate {
+			if err := autoMigrate(migrator); err != nil {
+				db.Close()
+				return nil, err
+			}
+		}
+
+		m.RegisterPool(db.Pool())
+
+		return &backend{
+			repos:  storage.NewPostgresRepositories(db),
+			health: storage.NewPostgresHealth(db, migrator),
+			close:  db.Close,
+		}, nil
+	case storage.Memory:
+		db := memdb.New()
+		if err := storage.Seed(context.Background(), db); err != nil {
+			return nil, fmt.Errorf("seeding in-memory storage: %w", err)
+		}
+
+		log.Println("Using in-memory storage")
+
+		return &backend{
+			repos:  storage.NewMemoryRepositories(db),
+			health: storage.NewMemoryHealth(),
+			close:  func() {},
+		}, nil
+	case storage.SQLite:
+		db, err := sqlite.Open(context.Background(), cfg.Storage.SQLitePath)
+		if err != nil {
+			return nil, fmt.Errorf("error opening the SQLite database: %w", err)
+		}
+
+		log.Printf("Using SQLite storage at %s", cfg.Storage.SQLitePath)
+
+		db.Observe(m.ObserveSQLiteQuery)
+		m.RegisterSQLDB(db.Pool(), "sqlite")
+
+		return &backend{
+			repos:  storage.NewSQLiteRepositories(db),
+			health: storage.NewSQLiteHealth(db),
+			close:  db.Close,
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid storage backend '%s'", cfg.Storage.Backend)
+	}
+}
+
+// paymentVerifierTimeout bounds a call to the payment provider, within the
+// deadline of the purchase request making it.
+const paymentVerifierTimeout = 10 * time.Second
+
+// paymentVerifier returns the verifier of purchase receipts. The fake one,
+// which accepts receipts anyone can forge, is only used in dev mode; without
+// it, a backend that keeps the shop needs a payment provider to start.
+func paymentVerifier(cfg *config.Config, repos *storage.Repositories) (shop.PaymentVerifier, error) {
+	switch {
+	case cfg.Shop.VerifierURL != "":
+		return shop.NewHTTPVerifier(cfg.Shop.VerifierURL, &http.Client{Timeout: paymentVerifierTimeout}), nil
+	case cfg.DevMode:
+		log.Println("Dev mode: accepting fake purchase receipts")
+		return shop.NewFakeVerifier(), nil
+	case repos.Shop == nil:
+		return nil, nil
+	default:
+		return nil, errors.New("shop.verifier_url is required to verify purchases; set dev_mode to accept fake receipts instead")
+	}
+}
+
+// autoMigrate applies the pending migrations on startup. It is off by
+// default, leaving schema changes to the migrate command.
+func autoMigrate(m *migrate.Migrator) error {
+	n, err := m.Up(context.Background())
+	if err != nil {
+		return fmt.Errorf("error migrating the database: %w", err)
 	}
 
-	log.Println("Starting server on port :8080")
-	return server.ListenAndServe()
+	log.Printf("Applied %d migrations", n)
+
+	return nil
+}
+
+// usernamePolicy returns the default username policy extended with the
+// configured word lists and timings.
+func usernamePolicy(cfg *config.Config) (player.UsernamePolicy, error) {
+	policy := player.DefaultUsernamePolicy()
+	policy.RenameCooldown = cfg.Game.RenameCooldown
+	policy.HoldPeriod = cfg.Game.NameHoldPeriod
+
+	if path := cfg.Username.ReservedFile; path != "" {
+		words, err := player.LoadWordList(path)
+		if err != nil {
+			return policy, fmt.Errorf("loading reserved usernames: %w", err)
+		}
+		policy.Reserved = append(policy.Reserved, words...)
+	}
+
+	if path := cfg.Username.ProfanityFile; path != "" {
+		words, err := player.LoadWordList(path)
+		if err != nil {
+			return policy, fmt.Errorf("loading profanity list: %w", err)
+		}
+		policy.Profanity = append(policy.Profanity, words...)
+	}
+
+	return policy, nil
+}
+
+const configUsage = "usage: config print"
+
+// runConfig implements the config subcommand.
+func runConfig(cfg *config.Config, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return errors.New(configUsage)
+	}
+	return cfg.Print(os.Stdout)
+}
+
+// validate exits if the configuration is invalid. Only the commands that open
+// storage call it: config print has to work on an incomplete configuration
+// to help complete it.
+func validate(cfg *config.Config) {
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Error loading the configuration: %s", err)
+	}
 }
 
 func main() {
-	if err := run(); err != nil {
-		log.Fatalf("Error starting the application: %s", err)
+	// The .env file is optional, the same settings can come from the
+	// environment, a config file or flags.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Fatalf("Error loading .env file: %s", err)
+	}
+
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Error loading the configuration: %s", err)
+	}
+
+	if len(args) == 0 {
+		validate(cfg)
+		if err := run(cfg); err != nil {
+			log.Fatalf("Error starting the application: %s", err)
+		}
+		return
+	}
+
+	switch args[0] {
+	case "migrate":
+		validate(cfg)
+		if err := runMigrate(cfg, args[1:]); err != nil {
+			log.Fatalf("Error running migrations: %s", err)
+		}
+	case "config":
+		if err := runConfig(cfg, args[1:]); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("Unknown command '%s', see -help", args[0])
 	}
 }
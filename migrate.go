@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hossokawa/go-nethttp-example/internal/config"
+	"github.com/hossokawa/go-nethttp-example/internal/database"
+	"github.com/hossokawa/go-nethttp-example/internal/migrate"
+	"github.com/hossokawa/go-nethttp-example/migrations"
+)
+
+const migrateUsage = `usage: migrate <command>
+
+commands:
+  up          apply every pending migration
+  down        revert the newest applied migration
+  status      list the migrations and when they were applied
+  to N        apply or revert migrations until N is the newest applied one (0 reverts all)
+  baseline N  record migrations up to N as applied without running them, to adopt
+              a database whose schema is already at N`
+
+func newMigrator(db *database.DB) (*migrate.Migrator, error) {
+	ms, err := migrate.Load(migrations.Postgres)
+	if err != nil {
+		return nil, fmt.Errorf("loading migrations: %w", err)
+	}
+	return migrate.New(db, ms), nil
+}
+
+// runMigrate implements the migrate subcommand against the configured
+// Postgres database.
+func runMigrate(cfg *config.Config, args []string) error {
+	var version int
+	switch {
+	case len(args) == 1 && (args[0] == "up" || args[0] == "down" || args[0] == "status"):
+	case len(args) == 2 && (args[0] == "to" || args[0] == "baseline"):
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid migration version '%s'", args[1])
+		}
+		version = v
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	if cfg.Database.URL == "" {
+		return errors.New("db.url is required")
+	}
+
+	ctx := context.Background()
+
+	db, err := database.Open(ctx, cfg.Database.Config)
+	if err != nil {
+		return fmt.Errorf("error connecting to the database: %w", err)
+	}
+	defer db.Close()
+
+	m, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied %d migrations", n)
+	case "down":
+		reverted, err := m.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if !reverted {
+			log.Println("No migration to revert")
+		} else {
+			log.Println("Reverted 1 migration")
+		}
+	case "to":
+		n, err := m.To(ctx, version)
+		if err != nil {
+			return err
+		}
+		log.Printf("Applied or reverted %d migrations", n)
+	case "baseline":
+		n, err := m.Baseline(ctx, version)
+		if err != nil {
+			return err
+		}
+		log.Printf("Recorded %d migrations as applied", n)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	}
+
+	return nil
+}
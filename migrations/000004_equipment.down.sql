@@ -0,0 +1,3 @@
+ALTER TABLE inventory DROP COLUMN IF EXISTS equipped;
+
+ALTER TABLE item DROP COLUMN IF EXISTS stats;
@@ -0,0 +1,7 @@
+ALTER TABLE item ADD COLUMN IF NOT EXISTS stats JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE inventory ADD COLUMN IF NOT EXISTS equipped BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE item SET stats = '{"strength": 3}' WHERE id = '42c6294c-56de-49d2-be2e-055b2a2151a6';
+
+UPDATE inventory SET equipped = true WHERE item_id = '42c6294c-56de-49d2-be2e-055b2a2151a6';
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS player_effect (
+  id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+  player_id INT NOT NULL REFERENCES player(id),
+  type TEXT NOT NULL,
+  stat TEXT NOT NULL,
+  magnitude INT NOT NULL,
+  stacks INT NOT NULL,
+  max_stacks INT NOT NULL,
+  source TEXT NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX ON player_effect(player_id, type, source);
+
+CREATE INDEX ON player_effect(expires_at);
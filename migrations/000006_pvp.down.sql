@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS pvp_match;
+
+DROP TABLE IF EXISTS pvp_rating;
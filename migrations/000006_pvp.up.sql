@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS pvp_rating (
+  player_id INT PRIMARY KEY REFERENCES player(id),
+  rating INT NOT NULL,
+  wins INT NOT NULL,
+  losses INT NOT NULL,
+  draws INT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS pvp_match (
+  id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+  player_a_id INT NOT NULL REFERENCES player(id),
+  player_b_id INT NOT NULL REFERENCES player(id),
+  rating_a INT NOT NULL,
+  rating_b INT NOT NULL,
+  status TEXT NOT NULL,
+  winner_id INT REFERENCES player(id),
+  rating_change_a INT NOT NULL DEFAULT 0,
+  rating_change_b INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL,
+  completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX ON pvp_match(player_a_id);
+
+CREATE INDEX ON pvp_match(player_b_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS player_location;
+
+DROP TABLE IF EXISTS zone_connection;
+
+DROP TABLE IF EXISTS zone;
@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS zone (
+  id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+  name TEXT NOT NULL,
+  min_level INT NOT NULL,
+  max_level INT NOT NULL,
+  pvp BOOLEAN NOT NULL
+);
+
+CREATE UNIQUE INDEX ON zone(name);
+
+CREATE TABLE IF NOT EXISTS zone_connection (
+  from_zone_id INT NOT NULL REFERENCES zone(id),
+  to_zone_id INT NOT NULL REFERENCES zone(id),
+  PRIMARY KEY (from_zone_id, to_zone_id)
+);
+
+CREATE TABLE IF NOT EXISTS player_location (
+  player_id INT PRIMARY KEY REFERENCES player(id),
+  zone_id INT NOT NULL REFERENCES zone(id),
+  x DOUBLE PRECISION NOT NULL,
+  y DOUBLE PRECISION NOT NULL,
+  z DOUBLE PRECISION NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX ON player_location(zone_id);
+
+INSERT INTO zone (name, min_level, max_level, pvp) VALUES
+('Elwynn Meadows', 1, 10, false),
+('Duskwood Hollow', 10, 25, false),
+('Stranglethorn Vale', 25, 40, true),
+('Burning Steppes', 40, 60, true);
+
+INSERT INTO zone_connection (from_zone_id, to_zone_id) VALUES
+(1, 2), (2, 1),
+(2, 3), (3, 2),
+(3, 4), (4, 3);
+
+INSERT INTO player_location (player_id, zone_id, x, y, z, updated_at) VALUES
+(1, 3, 120.5, 88.0, 0, now()),
+(2, 4, 42.0, 310.25, 12.5, now()),
+(3, 3, 201.75, 16.0, 0, now()),
+(4, 4, 5.0, 5.0, 0, now()),
+(5, 4, 77.5, 140.0, 3.0, now());
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS item_price;
+
+DROP TABLE IF EXISTS player_currency;
+
+DROP TABLE IF EXISTS currency;
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS currency (
+  code TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  cap INT
+);
+
+CREATE TABLE IF NOT EXISTS player_currency (
+  player_id INT NOT NULL REFERENCES player(id),
+  currency_code TEXT NOT NULL REFERENCES currency(code),
+  balance INT NOT NULL CHECK (balance >= 0),
+  PRIMARY KEY (player_id, currency_code)
+);
+
+CREATE TABLE IF NOT EXISTS item_price (
+  item_id UUID NOT NULL REFERENCES item(id),
+  currency_code TEXT NOT NULL REFERENCES currency(code),
+  amount INT NOT NULL CHECK (amount > 0),
+  PRIMARY KEY (item_id, currency_code)
+);
+
+INSERT INTO currency (code, name, cap) VALUES
+('gold', 'Gold', NULL),
+('gems', 'Premium Gems', NULL),
+('honor', 'Honor Tokens', 75000),
+('winterveil', 'Winterveil Tokens', 500);
+
+INSERT INTO player_currency (player_id, currency_code, balance) VALUES
+(1, 'honor', 1200),
+(2, 'gems', 250),
+(4, 'honor', 5400),
+(5, 'winterveil', 35);
+
+INSERT INTO item_price (item_id, currency_code, amount) VALUES
+('42c6294c-56de-49d2-be2e-055b2a2151a6', 'gold', 450),
+('42c6294c-56de-49d2-be2e-055b2a2151a6', 'honor', 300),
+('2e9e9593-c5ec-4554-9e15-131aa0b63127', 'gold', 20);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS purchase_receipt_grant;
+
+DROP TABLE IF EXISTS purchase_receipt;
+
+DROP TABLE IF EXISTS shop_bundle_item;
+
+DROP TABLE IF EXISTS shop_bundle_currency;
+
+DROP TABLE IF EXISTS shop_bundle;
@@ -0,0 +1,56 @@
+CREATE TABLE IF NOT EXISTS shop_bundle (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  price_cents INT NOT NULL,
+  active BOOLEAN NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS shop_bundle_currency (
+  bundle_id TEXT NOT NULL REFERENCES shop_bundle(id),
+  currency_code TEXT NOT NULL REFERENCES currency(code),
+  amount INT NOT NULL CHECK (amount > 0),
+  PRIMARY KEY (bundle_id, currency_code)
+);
+
+CREATE TABLE IF NOT EXISTS shop_bundle_item (
+  bundle_id TEXT NOT NULL REFERENCES shop_bundle(id),
+  item_id UUID NOT NULL REFERENCES item(id),
+  PRIMARY KEY (bundle_id, item_id)
+);
+
+CREATE TABLE IF NOT EXISTS purchase_receipt (
+  transaction_id TEXT PRIMARY KEY,
+  player_id INT NOT NULL REFERENCES player(id),
+  bundle_id TEXT NOT NULL REFERENCES shop_bundle(id),
+  status TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL,
+  granted_at TIMESTAMPTZ,
+  refunded_at TIMESTAMPTZ
+);
+
+CREATE INDEX ON purchase_receipt(player_id);
+
+CREATE TABLE IF NOT EXISTS purchase_receipt_grant (
+  id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+  transaction_id TEXT NOT NULL REFERENCES purchase_receipt(transaction_id),
+  currency_code TEXT REFERENCES currency(code),
+  item_id UUID REFERENCES item(id),
+  amount INT NOT NULL,
+  CHECK ((currency_code IS NULL) <> (item_id IS NULL))
+);
+
+CREATE INDEX ON purchase_receipt_grant(transaction_id);
+
+INSERT INTO shop_bundle (id, name, price_cents, active) VALUES
+('gems_small', 'Pouch of Gems', 499, true),
+('gems_large', 'Chest of Gems', 1999, true),
+('starter_pack', 'Adventurer Starter Pack', 999, true);
+
+INSERT INTO shop_bundle_currency (bundle_id, currency_code, amount) VALUES
+('gems_small', 'gems', 500),
+('gems_large', 'gems', 2200),
+('starter_pack', 'gems', 300),
+('starter_pack', 'gold', 1000);
+
+INSERT INTO shop_bundle_item (bundle_id, item_id) VALUES
+('starter_pack', '42c6294c-56de-49d2-be2e-055b2a2151a6');
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS account_storage_item;
+
+DROP TABLE IF EXISTS account_storage;
+
+DROP TABLE IF EXISTS bank_item;
+
+DROP TABLE IF EXISTS bank;
+
+ALTER TABLE player DROP COLUMN IF EXISTS account_id;
+
+DROP TABLE IF EXISTS account;
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS account (
+  id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX ON account(name);
+
+ALTER TABLE player ADD COLUMN IF NOT EXISTS account_id INT REFERENCES account(id);
+
+CREATE INDEX ON player(account_id);
+
+CREATE TABLE IF NOT EXISTS bank (
+  player_id INT PRIMARY KEY REFERENCES player(id),
+  capacity INT NOT NULL,
+  gold INT NOT NULL CHECK (gold >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS bank_item (
+  player_id INT NOT NULL REFERENCES bank(player_id),
+  item_id UUID NOT NULL REFERENCES item(id),
+  PRIMARY KEY (player_id, item_id)
+);
+
+CREATE TABLE IF NOT EXISTS account_storage (
+  account_id INT PRIMARY KEY REFERENCES account(id),
+  capacity INT NOT NULL,
+  gold INT NOT NULL CHECK (gold >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS account_storage_item (
+  account_id INT NOT NULL REFERENCES account_storage(account_id),
+  item_id UUID NOT NULL REFERENCES item(id),
+  PRIMARY KEY (account_id, item_id)
+);
+
+INSERT INTO account (name, created_at) VALUES
+('sephiro-family', '2024-01-15 14:20:00Z');
+
+UPDATE player SET account_id = 1 WHERE id IN (1, 3);
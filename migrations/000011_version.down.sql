@@ -0,0 +1,3 @@
+ALTER TABLE item DROP COLUMN IF EXISTS version;
+
+ALTER TABLE player DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+ALTER TABLE player ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE item ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
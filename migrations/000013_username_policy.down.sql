@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS player_name_history;
+
+ALTER TABLE player DROP COLUMN IF EXISTS renamed_at;
+
+DROP INDEX IF EXISTS player_lower_username_idx;
+
+DROP INDEX IF EXISTS player_username_key_idx;
+
+ALTER TABLE player DROP COLUMN IF EXISTS username_key;
@@ -0,0 +1,29 @@
+-- username_key is computed by the application (player.UsernameKey): it is the
+-- case-folded name with lookalike characters mapped onto each other. Existing
+-- rows are backfilled with an approximation that covers case and the common
+-- Latin lookalikes; renaming a player recomputes it exactly.
+ALTER TABLE player ADD COLUMN IF NOT EXISTS username_key TEXT;
+
+UPDATE player SET username_key = replace(replace(translate(lower(username), '01i', 'oll'), 'rn', 'm'), 'vv', 'w')
+WHERE username_key IS NULL;
+
+ALTER TABLE player ALTER COLUMN username_key SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS player_username_key_idx ON player(username_key);
+
+CREATE INDEX IF NOT EXISTS player_lower_username_idx ON player(lower(username));
+
+ALTER TABLE player ADD COLUMN IF NOT EXISTS renamed_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS player_name_history (
+  id INT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+  player_id INT NOT NULL REFERENCES player(id) ON DELETE CASCADE,
+  username TEXT NOT NULL,
+  username_key TEXT NOT NULL,
+  changed_at TIMESTAMPTZ NOT NULL,
+  held_until TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX ON player_name_history(player_id, changed_at);
+
+CREATE INDEX ON player_name_history(username_key, held_until);
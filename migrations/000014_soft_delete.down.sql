@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS item_deleted_at_idx;
+
+DROP INDEX IF EXISTS player_deleted_at_idx;
+
+ALTER TABLE item DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE player DROP COLUMN IF EXISTS deleted_at;
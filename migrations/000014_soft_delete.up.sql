@@ -0,0 +1,7 @@
+ALTER TABLE player ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+ALTER TABLE item ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS player_deleted_at_idx ON player(deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS item_deleted_at_idx ON item(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,12 @@
+-- NOT VALID because rows of purged players may no longer satisfy the keys.
+ALTER TABLE pvp_match ADD CONSTRAINT pvp_match_winner_id_fkey
+FOREIGN KEY (winner_id) REFERENCES player(id) NOT VALID;
+
+ALTER TABLE pvp_match ADD CONSTRAINT pvp_match_player_b_id_fkey
+FOREIGN KEY (player_b_id) REFERENCES player(id) NOT VALID;
+
+ALTER TABLE pvp_match ADD CONSTRAINT pvp_match_player_a_id_fkey
+FOREIGN KEY (player_a_id) REFERENCES player(id) NOT VALID;
+
+ALTER TABLE purchase_receipt ADD CONSTRAINT purchase_receipt_player_id_fkey
+FOREIGN KEY (player_id) REFERENCES player(id) NOT VALID;
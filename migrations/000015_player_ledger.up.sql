@@ -0,0 +1,9 @@
+-- Purchase receipts and match results outlive the players they mention, so
+-- permanently deleting a player leaves them pointing at its old id.
+ALTER TABLE purchase_receipt DROP CONSTRAINT IF EXISTS purchase_receipt_player_id_fkey;
+
+ALTER TABLE pvp_match DROP CONSTRAINT IF EXISTS pvp_match_player_a_id_fkey;
+
+ALTER TABLE pvp_match DROP CONSTRAINT IF EXISTS pvp_match_player_b_id_fkey;
+
+ALTER TABLE pvp_match DROP CONSTRAINT IF EXISTS pvp_match_winner_id_fkey;
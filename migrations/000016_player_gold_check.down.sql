@@ -0,0 +1 @@
+ALTER TABLE player DROP CONSTRAINT IF EXISTS player_gold_check;
@@ -0,0 +1,6 @@
+-- Gold is only ever debited when the player has enough of it, which the
+-- constraint now enforces too. Balances that already went negative, before
+-- debits were checked, are reset to zero first.
+UPDATE player SET gold = 0 WHERE gold < 0;
+
+ALTER TABLE player ADD CONSTRAINT player_gold_check CHECK (gold >= 0);
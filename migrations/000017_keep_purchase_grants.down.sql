@@ -0,0 +1,3 @@
+-- NOT VALID because grants of purged items may no longer satisfy the key.
+ALTER TABLE purchase_receipt_grant ADD CONSTRAINT purchase_receipt_grant_item_id_fkey
+FOREIGN KEY (item_id) REFERENCES item(id) NOT VALID;
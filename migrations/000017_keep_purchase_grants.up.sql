@@ -0,0 +1,3 @@
+-- Purchase receipt grants are ledger history and outlive the items they
+-- mention, so permanently deleting an item leaves them pointing at its old id.
+ALTER TABLE purchase_receipt_grant DROP CONSTRAINT IF EXISTS purchase_receipt_grant_item_id_fkey;
@@ -0,0 +1,9 @@
+-- Keys used by several clients cannot share the old primary key, and the
+-- stored responses are only a cache, so they are all dropped.
+DELETE FROM idempotency_key;
+
+ALTER TABLE idempotency_key DROP CONSTRAINT idempotency_key_pkey;
+
+ALTER TABLE idempotency_key ADD PRIMARY KEY (key);
+
+ALTER TABLE idempotency_key DROP COLUMN scope;
@@ -0,0 +1,6 @@
+-- Idempotency keys are chosen by clients, so they are only unique per client.
+ALTER TABLE idempotency_key ADD COLUMN scope TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE idempotency_key DROP CONSTRAINT idempotency_key_pkey;
+
+ALTER TABLE idempotency_key ADD PRIMARY KEY (scope, key);
@@ -0,0 +1,16 @@
+// Package migrations embeds the database migrations in the binary. The
+// Postgres migrations are applied by the migrate command, or on startup when
+// enabled; the SQLite ones are applied when the database is opened.
+package migrations
+
+import "embed"
+
+// Postgres holds the numbered up and down migrations of the Postgres schema.
+//
+//go:embed *.sql
+var Postgres embed.FS
+
+// SQLite holds the numbered up and down migrations of the SQLite schema.
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
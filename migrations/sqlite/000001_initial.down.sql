@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS item_price;
+
+DROP TABLE IF EXISTS currency;
+
+DROP TABLE IF EXISTS inventory;
+
+DROP TABLE IF EXISTS item;
+
+DROP TABLE IF EXISTS player_name_history;
+
+DROP TABLE IF EXISTS player;
@@ -0,0 +1,97 @@
+-- The SQLite schema covers players, items and inventories. It follows the
+-- Postgres schema after all of its migrations, with timestamps stored as
+-- fixed-width UTC text so that they sort correctly, and UUIDs and JSON as text.
+CREATE TABLE IF NOT EXISTS player (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  username TEXT NOT NULL UNIQUE,
+  username_key TEXT NOT NULL UNIQUE,
+  class TEXT NOT NULL,
+  level INTEGER NOT NULL,
+  gold INTEGER NOT NULL,
+  account_id INTEGER,
+  version INTEGER NOT NULL DEFAULT 1,
+  renamed_at TEXT,
+  deleted_at TEXT,
+  created_at TEXT NOT NULL,
+  updated_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS player_lower_username_idx ON player(lower(username));
+
+CREATE INDEX IF NOT EXISTS player_deleted_at_idx ON player(deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS player_name_history (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  player_id INTEGER NOT NULL REFERENCES player(id) ON DELETE CASCADE,
+  username TEXT NOT NULL,
+  username_key TEXT NOT NULL,
+  changed_at TEXT NOT NULL,
+  held_until TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS player_name_history_player_idx ON player_name_history(player_id, changed_at);
+
+CREATE INDEX IF NOT EXISTS player_name_history_key_idx ON player_name_history(username_key, held_until);
+
+CREATE TABLE IF NOT EXISTS item (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  value INTEGER NOT NULL,
+  stats TEXT NOT NULL DEFAULT '{}',
+  version INTEGER NOT NULL DEFAULT 1,
+  deleted_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS item_deleted_at_idx ON item(deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS inventory (
+  player_id INTEGER NOT NULL REFERENCES player(id),
+  item_id TEXT NOT NULL REFERENCES item(id),
+  equipped BOOLEAN NOT NULL DEFAULT false,
+  PRIMARY KEY (player_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS inventory_player_idx ON inventory(player_id);
+
+CREATE TABLE IF NOT EXISTS currency (
+  code TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  cap INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS item_price (
+  item_id TEXT NOT NULL REFERENCES item(id),
+  currency_code TEXT NOT NULL REFERENCES currency(code),
+  amount INTEGER NOT NULL CHECK (amount > 0),
+  PRIMARY KEY (item_id, currency_code)
+);
+
+INSERT INTO player (username, username_key, class, level, gold, created_at, updated_at) VALUES
+('Sephiro', 'sephlro', 'Warrior', 32, 4309, '2024-01-15T14:23:45.000000000Z', '2024-02-10T08:30:15.000000000Z'),
+('SpellQueen', 'spellqueen', 'Mage', 47, 7390, '2023-12-05T10:15:12.000000000Z', '2024-01-22T16:45:22.000000000Z'),
+('StormcallerX', 'stormcallerx', 'Druid', 29, 3982, '2023-11-20T09:20:34.000000000Z', '2024-01-10T12:10:58.000000000Z'),
+('ShadowMaster', 'shadowmaster', 'Rogue', 50, 7521, '2023-10-08T19:50:14.000000000Z', '2023-12-18T11:35:45.000000000Z'),
+('FireFurry', 'flrefurry', 'Sorcerer', 55, 8097, '2023-09-03T17:45:23.000000000Z', '2023-11-14T14:20:30.000000000Z');
+
+INSERT INTO item (id, name, value, stats) VALUES
+('42c6294c-56de-49d2-be2e-055b2a2151a6', 'Rusty Sword', 100, '{"strength": 3}'),
+('2e9e9593-c5ec-4554-9e15-131aa0b63127', 'Bat Wing', 6, '{}'),
+('0faffedc-2047-4616-9357-51d22fe80ff7', 'Skeleton Femur', 2, '{}');
+
+INSERT INTO inventory (player_id, item_id, equipped) VALUES
+(1, '42c6294c-56de-49d2-be2e-055b2a2151a6', true),
+(2, '2e9e9593-c5ec-4554-9e15-131aa0b63127', false),
+(3, '0faffedc-2047-4616-9357-51d22fe80ff7', false),
+(4, '42c6294c-56de-49d2-be2e-055b2a2151a6', true),
+(5, '0faffedc-2047-4616-9357-51d22fe80ff7', false);
+
+INSERT INTO currency (code, name, cap) VALUES
+('gold', 'Gold', NULL),
+('gems', 'Premium Gems', NULL),
+('honor', 'Honor Tokens', 75000),
+('winterveil', 'Winterveil Tokens', 500);
+
+INSERT INTO item_price (item_id, currency_code, amount) VALUES
+('42c6294c-56de-49d2-be2e-055b2a2151a6', 'gold', 450),
+('42c6294c-56de-49d2-be2e-055b2a2151a6', 'honor', 300),
+('2e9e9593-c5ec-4554-9e15-131aa0b63127', 'gold', 20);
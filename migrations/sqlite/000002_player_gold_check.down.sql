@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS player_gold_check_update;
+
+DROP TRIGGER IF EXISTS player_gold_check_insert;
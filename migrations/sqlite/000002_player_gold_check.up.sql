@@ -0,0 +1,15 @@
+-- SQLite cannot add a CHECK constraint to an existing table, so triggers
+-- enforce what player_gold_check does in Postgres.
+UPDATE player SET gold = 0 WHERE gold < 0;
+
+CREATE TRIGGER IF NOT EXISTS player_gold_check_insert BEFORE INSERT ON player
+WHEN NEW.gold < 0
+BEGIN
+  SELECT RAISE(ABORT, 'CHECK constraint failed: player_gold_check');
+END;
+
+CREATE TRIGGER IF NOT EXISTS player_gold_check_update BEFORE UPDATE OF gold ON player
+WHEN NEW.gold < 0
+BEGIN
+  SELECT RAISE(ABORT, 'CHECK constraint failed: player_gold_check');
+END;